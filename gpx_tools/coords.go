@@ -9,7 +9,9 @@ import (
 // in degrees
 // Fields are public, however using
 // setters and creators is strongly
-// encouraged to avoid invalid values
+// encouraged to avoid invalid values.
+// Latitude is in [-90, 90] and longitude
+// in [-180, 180).
 type Coordinates struct {
 	Latitude  float64
 	longitude float64
@@ -27,6 +29,16 @@ func (c *Coordinates) VincentyDistanceFrom(coordinates Coordinates) float64 {
 	return Vincenty(*c, coordinates)
 }
 
+// Return Latitude in degrees.
+func (c *Coordinates) GetLatitude() float64 {
+	return c.Latitude
+}
+
+// Return longitude in degrees.
+func (c *Coordinates) GetLongitude() float64 {
+	return c.longitude
+}
+
 // Return Latitude in radians.
 func (c *Coordinates) GetLatitudeRadians() float64 {
 	return c.Latitude * math.Pi / 180
@@ -37,14 +49,40 @@ func (c *Coordinates) GetLongitudeRadians() float64 {
 	return c.longitude * math.Pi / 180
 }
 
-// Create new Coordinates object
-// with normalized Latitude and longitude.
+// Create new Coordinates object, Latitude outside of
+// [-90, 90] crosses the pole, see NewCoordinatesWrapped.
 func NewCoordinates(latitude, longitude float64) Coordinates {
-	return Coordinates{Normalize(latitude), Normalize(longitude)}
+	return NewCoordinatesWrapped(latitude, longitude)
+}
+
+// Create new Coordinates object, Latitude outside of [-90, 90]
+// continues over the pole to the opposite meridian, e.g. 95, 10
+// becomes 85, -170. Longitude is wrapped to [-180, 180).
+func NewCoordinatesWrapped(latitude, longitude float64) Coordinates {
+	latitude = Normalize(latitude)
+	if latitude > 90 {
+		latitude, longitude = 180-latitude, longitude+180
+	} else if latitude < -90 {
+		latitude, longitude = -180-latitude, longitude+180
+	}
+	return Coordinates{latitude, WrapLongitude(longitude)}
 }
 
-// Set Latitude to normalized value and
-// return error if Latitude is NaN or Inf.
+// Create new Coordinates object and return error if Latitude
+// is outside of [-90, 90] or any value is NaN or Inf.
+// Longitude is wrapped to [-180, 180).
+func NewCoordinatesStrict(latitude, longitude float64) (c Coordinates, err error) {
+	if err := c.SetLatitude(latitude); err != nil {
+		return Coordinates{}, err
+	}
+	if err := c.SetLongitude(longitude); err != nil {
+		return Coordinates{}, err
+	}
+	return c, nil
+}
+
+// Set Latitude and return error if it is
+// outside of [-90, 90], NaN or Inf.
 func (c *Coordinates) SetLatitude(latitude float64) (err error) {
 	if math.IsNaN(latitude) {
 		return fmt.Errorf("Latitude is NaN")
@@ -52,11 +90,14 @@ func (c *Coordinates) SetLatitude(latitude float64) (err error) {
 	if math.IsInf(latitude, 0) {
 		return fmt.Errorf("Latitude is Inf")
 	}
-	c.Latitude = Normalize(latitude)
+	if latitude < -90 || latitude > 90 {
+		return fmt.Errorf("Latitude %v is out of range [-90, 90]", latitude)
+	}
+	c.Latitude = latitude
 	return nil
 }
 
-// Set longitude to normalized value and
+// Set longitude wrapped to [-180, 180) and
 // return error if longitude is NaN or Inf.
 func (c *Coordinates) SetLongitude(longitude float64) (err error) {
 	if math.IsNaN(longitude) {
@@ -65,53 +106,76 @@ func (c *Coordinates) SetLongitude(longitude float64) (err error) {
 	if math.IsInf(longitude, 0) {
 		return fmt.Errorf("Longitude is Inf")
 	}
-	c.longitude = Normalize(longitude)
+	c.longitude = WrapLongitude(longitude)
 	return nil
 }
 
-// Return degrees part of Latitude.
+// Return degrees part of Latitude,
+// truncated toward zero, e.g. -37 for -37.5.
 func (c *Coordinates) GetLatitudeDegrees() int64 {
-	return int64(math.Floor(c.Latitude))
+	return int64(c.Latitude)
 }
 
-// Return degrees part of longitude.
+// Return degrees part of longitude,
+// truncated toward zero, e.g. -122 for -122.4.
 func (c *Coordinates) GetLongitudeDegrees() int64 {
-	return int64(math.Floor(c.longitude))
+	return int64(c.longitude)
 }
 
-// Return minutes part of Latitude with degrees and seconds removed.
+// Return minutes part of Latitude with degrees and seconds removed,
+// it is not negative.
 func (c *Coordinates) GetLatitudeMinutes() int64 {
-	return int64(math.Floor(c.Latitude - float64(c.GetLatitudeDegrees())*60))
+	return int64(math.Abs(c.Latitude-float64(c.GetLatitudeDegrees())) * 60)
 }
 
-// Return minutes part of longitude with degrees and seconds removed.
+// Return minutes part of longitude with degrees and seconds removed,
+// it is not negative.
 func (c *Coordinates) GetLongitudeMinutes() int64 {
-	return int64(math.Floor(c.longitude - float64(c.GetLongitudeDegrees())*60))
+	return int64(math.Abs(c.longitude-float64(c.GetLongitudeDegrees())) * 60)
 }
 
 // Return seconds part of Latitude with degrees and minutes removed and with decimal places.
 func (c *Coordinates) GetLatitudeSeconds() float64 {
-	return ((c.Latitude-float64(c.GetLatitudeDegrees()))*60 - float64(c.GetLatitudeMinutes())) * 60
+	return (math.Abs(c.Latitude-float64(c.GetLatitudeDegrees()))*60 - float64(c.GetLatitudeMinutes())) * 60
 }
 
 // Return seconds part of longitude with degrees and minutes removed and with decimal places.
 func (c *Coordinates) GetLongitudeSeconds() float64 {
-	return ((c.longitude-float64(c.GetLongitudeDegrees()))*60 - float64(c.GetLongitudeMinutes())) * 60
+	return (math.Abs(c.longitude-float64(c.GetLongitudeDegrees()))*60 - float64(c.GetLongitudeMinutes())) * 60
 }
 
-// Return Latitude split into degrees, minutes, seconds and formatted as string with symbols.
+// Return Latitude in degrees, minutes and seconds with hemisphere,
+// e.g. 37°46'29.6"N, see FormatLatitude for other notations.
 func (c *Coordinates) GetLatitudeAsString() string {
-	return fmt.Sprintf("%d°%d'%f\"", c.GetLatitudeDegrees(), c.GetLatitudeMinutes(), c.GetLatitudeSeconds())
+	return FormatLatitude(c.Latitude, CoordinateFormat{Notation: DegreesMinutesSeconds})
 }
 
-// Return longitude split into degrees, minutes, seconds and formatted as string with symbols.
+// Return longitude in degrees, minutes and seconds with hemisphere,
+// e.g. 122°25'09.8"W, see FormatLongitude for other notations.
 func (c *Coordinates) GetLongitudeAsString() string {
-	return fmt.Sprintf("%d°%d'%f\"", c.GetLongitudeDegrees(), c.GetLongitudeMinutes(), c.GetLongitudeSeconds())
+	return FormatLongitude(c.longitude, CoordinateFormat{Notation: DegreesMinutesSeconds})
 }
 
 // Return Latitude and longitude formatted as string.
 func (c *Coordinates) ToString() string {
-	return fmt.Sprintf("%s, %s", c.GetLatitudeAsString(), c.GetLongitudeAsString())
+	return c.Format(CoordinateFormat{Notation: DegreesMinutesSeconds, Separator: ", "})
+}
+
+// Wrap longitude to range [-180, 180).
+func WrapLongitude(degrees float64) float64 {
+	if degrees >= -180 && degrees < 180 {
+		// Values in range are kept exact.
+		return degrees
+	}
+	degrees = math.Mod(degrees+180, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+	if degrees >= 360 {
+		// Adding 360 to tiny negative remainder rounds up.
+		degrees = 0
+	}
+	return degrees - 180
 }
 
 // Normalize Latitude or longitude to range [-180, 180].
@@ -0,0 +1,374 @@
+package gpx_tools
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CoordinateNotation is a notation of latitude and longitude.
+type CoordinateNotation int
+
+const (
+	// DecimalDegrees notation, e.g. 37.774900°N.
+	DecimalDegrees CoordinateNotation = iota
+	// DegreesDecimalMinutes notation, e.g. 37°46.494'N.
+	DegreesDecimalMinutes
+	// DegreesMinutesSeconds notation, e.g. 37°46'29.6"N.
+	DegreesMinutesSeconds
+)
+
+// CoordinateFormat configures formatting of coordinates.
+// Zero value formats decimal degrees with hemisphere letters,
+// e.g. 37.774900°N 122.419400°W.
+type CoordinateFormat struct {
+	// Notation of the value, unknown ones format decimal degrees.
+	Notation CoordinateNotation
+	// Precision is number of decimals of the last part, nil means
+	// 6 for decimal degrees, 3 for minutes and 1 for seconds.
+	Precision *int
+	// Signed writes negative numbers instead of hemisphere letters.
+	Signed bool
+	// HemisphereFirst writes hemisphere letter before the number.
+	HemisphereFirst bool
+	// NoSymbols omits degree, minute and second symbols,
+	// parts are then separated by spaces.
+	NoSymbols bool
+	// PartSeparator is written between degrees, minutes and
+	// seconds and before hemisphere, e.g. " ".
+	PartSeparator string
+	// Separator is written between latitude and longitude,
+	// empty string means a space.
+	Separator string
+}
+
+// Symbols of degrees, minutes and seconds recognized by ParseCoordinates.
+const (
+	degreeSymbols = "°º˚"
+	minuteSymbols = "'′’"
+	secondSymbols = "\"″”"
+)
+
+// Format latitude in degrees.
+func FormatLatitude(latitude float64, format CoordinateFormat) string {
+	return formatCoordinate(latitude, format, "N", "S")
+}
+
+// Format longitude in degrees.
+func FormatLongitude(longitude float64, format CoordinateFormat) string {
+	return formatCoordinate(longitude, format, "E", "W")
+}
+
+// Return Latitude and longitude formatted with separator.
+func (c *Coordinates) Format(format CoordinateFormat) string {
+	separator := format.Separator
+	if separator == "" {
+		separator = " "
+	}
+	return FormatLatitude(c.Latitude, format) + separator + FormatLongitude(c.longitude, format)
+}
+
+func formatCoordinate(degrees float64, format CoordinateFormat, positive, negative string) string {
+	if format.Notation < DecimalDegrees || format.Notation > DegreesMinutesSeconds {
+		format.Notation = DecimalDegrees
+	}
+	precision := []int{6, 3, 1}[format.Notation]
+	if format.Precision != nil {
+		precision = *format.Precision
+	}
+	symbols := []string{"°", "'", "\""}
+	partSeparator := format.PartSeparator
+	if format.NoSymbols {
+		symbols = []string{"", "", ""}
+		if partSeparator == "" {
+			partSeparator = " "
+		}
+	}
+
+	// Value is rounded in units of the last part, so carry
+	// to minutes and degrees is exact, e.g. 59.96" is 1' 0.0".
+	factor := math.Pow10(precision)
+	unitsPerDegree := []float64{1, 60, 3600}[format.Notation]
+	units := math.Round(math.Abs(degrees) * unitsPerDegree * factor)
+	sign, hemisphere := "", positive
+	if degrees < 0 && units > 0 {
+		sign, hemisphere = "-", negative
+	}
+
+	var parts []string
+	switch format.Notation {
+	case DecimalDegrees:
+		parts = []string{strconv.FormatFloat(units/factor, 'f', precision, 64) + symbols[0]}
+	case DegreesDecimalMinutes:
+		perDegree := 60 * factor
+		minutes := math.Mod(units, perDegree) / factor
+		parts = []string{
+			strconv.FormatFloat(math.Floor(units/perDegree), 'f', 0, 64) + symbols[0],
+			padMinutes(strconv.FormatFloat(minutes, 'f', precision, 64)) + symbols[1],
+		}
+	case DegreesMinutesSeconds:
+		perMinute := 60 * factor
+		perDegree := 60 * perMinute
+		seconds := math.Mod(units, perMinute) / factor
+		parts = []string{
+			strconv.FormatFloat(math.Floor(units/perDegree), 'f', 0, 64) + symbols[0],
+			padMinutes(strconv.FormatFloat(math.Floor(math.Mod(units, perDegree)/perMinute), 'f', 0, 64)) + symbols[1],
+			padMinutes(strconv.FormatFloat(seconds, 'f', precision, 64)) + symbols[2],
+		}
+	}
+	value := strings.Join(parts, partSeparator)
+
+	switch {
+	case format.Signed:
+		return sign + value
+	case format.HemisphereFirst:
+		return hemisphere + partSeparator + value
+	}
+	return value + partSeparator + hemisphere
+}
+
+// Pad minutes or seconds to two digits of the integer part.
+func padMinutes(value string) string {
+	if len(value) == 1 || value[1] == '.' {
+		return "0" + value
+	}
+	return value
+}
+
+// Token of coordinate notation.
+type coordToken struct {
+	// Kind is 'n' for number, 'h' for hemisphere, ',' for separator
+	// or 'd', 'm', 's' for symbols of degrees, minutes and seconds.
+	kind  byte
+	text  string
+	value float64
+}
+
+func tokenizeCoordinates(text string) ([]coordToken, error) {
+	var tokens []coordToken
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+		case r >= '0' && r <= '9' || r == '.' || (r == '-' || r == '+') && i+1 < len(runes) && (runes[i+1] >= '0' && runes[i+1] <= '9' || runes[i+1] == '.'):
+			start := i
+			i++
+			for i < len(runes) && (runes[i] >= '0' && runes[i] <= '9' || runes[i] == '.') {
+				i++
+			}
+			number := string(runes[start:i])
+			i--
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid number %q", number)
+			}
+			tokens = append(tokens, coordToken{kind: 'n', text: number, value: value})
+		case strings.ContainsRune("NSEWnsew", r):
+			tokens = append(tokens, coordToken{kind: 'h', text: strings.ToUpper(string(r))})
+		case r == ',' || r == ';':
+			tokens = append(tokens, coordToken{kind: ','})
+		case strings.ContainsRune(degreeSymbols, r):
+			tokens = append(tokens, coordToken{kind: 'd'})
+		case r == '\'' && i+1 < len(runes) && runes[i+1] == '\'':
+			i++
+			tokens = append(tokens, coordToken{kind: 's'})
+		case strings.ContainsRune(minuteSymbols, r):
+			tokens = append(tokens, coordToken{kind: 'm'})
+		case strings.ContainsRune(secondSymbols, r):
+			tokens = append(tokens, coordToken{kind: 's'})
+		default:
+			return nil, fmt.Errorf("Unexpected character %q", r)
+		}
+	}
+	return tokens, nil
+}
+
+// Parse one coordinate of tokens and return it in degrees
+// with hemisphere letter, which is empty if it is missing.
+func parseCoordinateTokens(tokens []coordToken) (degrees float64, hemisphere string, err error) {
+	var parts [3]float64
+	var fractional bool
+	part := 0
+	negative := false
+	for i, token := range tokens {
+		switch token.kind {
+		case 'h':
+			if hemisphere != "" {
+				return 0, "", fmt.Errorf("Multiple hemispheres")
+			}
+			hemisphere = token.text
+		case 'n':
+			if strings.ContainsAny(token.text, "+-") {
+				if part > 0 {
+					return 0, "", fmt.Errorf("Unexpected sign of %q", token.text)
+				}
+				negative = strings.HasPrefix(token.text, "-")
+			}
+			// Symbol following the number selects the part.
+			if i+1 < len(tokens) {
+				switch tokens[i+1].kind {
+				case 'd':
+					if part > 0 {
+						return 0, "", fmt.Errorf("Unexpected degrees %q", token.text)
+					}
+				case 'm':
+					if part > 1 {
+						return 0, "", fmt.Errorf("Unexpected minutes %q", token.text)
+					}
+					part = 1
+				case 's':
+					part = 2
+				}
+			}
+			if part > 2 {
+				return 0, "", fmt.Errorf("Too many numbers")
+			}
+			if fractional {
+				return 0, "", fmt.Errorf("Only the last part can have decimals")
+			}
+			parts[part] = math.Abs(token.value)
+			fractional = math.Trunc(token.value) != token.value
+			part++
+		case ',':
+			return 0, "", fmt.Errorf("Unexpected separator")
+		}
+	}
+	if part == 0 {
+		return 0, "", fmt.Errorf("Missing number")
+	}
+	if parts[1] >= 60 || parts[2] >= 60 {
+		return 0, "", fmt.Errorf("Minutes and seconds must be less than 60")
+	}
+	degrees = parts[0] + parts[1]/60 + parts[2]/3600
+	if negative {
+		if hemisphere == "S" || hemisphere == "W" {
+			return 0, "", fmt.Errorf("Negative value with hemisphere %s", hemisphere)
+		}
+		degrees = -degrees
+	}
+	if hemisphere == "S" || hemisphere == "W" {
+		degrees = -degrees
+	}
+	return degrees, hemisphere, nil
+}
+
+// ParseLatitude parses latitude in decimal degrees, DDM or DMS notation,
+// with N or S hemisphere or sign, and returns error if it is out of range.
+func ParseLatitude(text string) (float64, error) {
+	return parseCoordinate(text, "N", "S", 90)
+}
+
+// ParseLongitude parses longitude in decimal degrees, DDM or DMS notation,
+// with E or W hemisphere or sign, wrapped to [-180, 180).
+func ParseLongitude(text string) (float64, error) {
+	longitude, err := parseCoordinate(text, "E", "W", 180)
+	if err != nil {
+		return 0, err
+	}
+	return WrapLongitude(longitude), nil
+}
+
+func parseCoordinate(text, positive, negative string, limit float64) (float64, error) {
+	tokens, err := tokenizeCoordinates(text)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid coordinate %q: %v", text, err)
+	}
+	degrees, hemisphere, err := parseCoordinateTokens(tokens)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid coordinate %q: %v", text, err)
+	}
+	if hemisphere != "" && hemisphere != positive && hemisphere != negative {
+		return 0, fmt.Errorf("Invalid coordinate %q: unexpected hemisphere %s", text, hemisphere)
+	}
+	if math.Abs(degrees) > limit {
+		return 0, fmt.Errorf("Coordinate %q is out of range [-%v, %v]", text, limit, limit)
+	}
+	return degrees, nil
+}
+
+// ParseCoordinates parses latitude and longitude in decimal degrees,
+// DDM or DMS notation, e.g. 37°46'29.6"N 122°25'9.8"W,
+// N 37° 46.493' W 122° 25.163' or 37.7749, -122.4194.
+//
+// Coordinates are separated by comma or semicolon, by hemisphere
+// letters or by degree symbols, numbers without them are split
+// evenly. Latitude is first unless hemispheres say otherwise.
+func ParseCoordinates(text string) (Coordinates, error) {
+	tokens, err := tokenizeCoordinates(text)
+	if err != nil {
+		return Coordinates{}, fmt.Errorf("Invalid coordinates %q: %v", text, err)
+	}
+	groups, err := splitCoordinateTokens(tokens)
+	if err != nil {
+		return Coordinates{}, fmt.Errorf("Invalid coordinates %q: %v", text, err)
+	}
+
+	var values [2]float64
+	var hemispheres [2]string
+	for i, group := range groups {
+		if values[i], hemispheres[i], err = parseCoordinateTokens(group); err != nil {
+			return Coordinates{}, fmt.Errorf("Invalid coordinates %q: %v", text, err)
+		}
+	}
+	isLongitude := func(hemisphere string) bool { return hemisphere == "E" || hemisphere == "W" }
+	isLatitude := func(hemisphere string) bool { return hemisphere == "N" || hemisphere == "S" }
+	if isLongitude(hemispheres[0]) || isLatitude(hemispheres[1]) {
+		values[0], values[1] = values[1], values[0]
+		hemispheres[0], hemispheres[1] = hemispheres[1], hemispheres[0]
+	}
+	if isLongitude(hemispheres[0]) || isLatitude(hemispheres[1]) {
+		return Coordinates{}, fmt.Errorf("Invalid coordinates %q: hemispheres %s and %s", text, hemispheres[0], hemispheres[1])
+	}
+	if math.Abs(values[1]) > 180 {
+		return Coordinates{}, fmt.Errorf("Invalid coordinates %q: longitude is out of range [-180, 180]", text)
+	}
+	coordinates, err := NewCoordinatesStrict(values[0], values[1])
+	if err != nil {
+		return Coordinates{}, fmt.Errorf("Invalid coordinates %q: %v", text, err)
+	}
+	return coordinates, nil
+}
+
+// Split tokens of latitude and longitude.
+func splitCoordinateTokens(tokens []coordToken) ([][]coordToken, error) {
+	split := func(indexes ...int) ([][]coordToken, error) {
+		if len(indexes) != 1 {
+			return nil, fmt.Errorf("Expected two coordinates")
+		}
+		return [][]coordToken{tokens[:indexes[0]], tokens[indexes[0]:]}, nil
+	}
+
+	var commas, hemispheres, degrees, numbers []int
+	for i, token := range tokens {
+		switch token.kind {
+		case ',':
+			commas = append(commas, i)
+		case 'h':
+			hemispheres = append(hemispheres, i)
+		case 'd':
+			degrees = append(degrees, i-1)
+		case 'n':
+			numbers = append(numbers, i)
+		}
+	}
+	switch {
+	case len(commas) > 0:
+		if len(commas) != 1 {
+			return nil, fmt.Errorf("Expected two coordinates")
+		}
+		return [][]coordToken{tokens[:commas[0]], tokens[commas[0]+1:]}, nil
+	case len(hemispheres) == 2 && hemispheres[0] == 0:
+		// Hemispheres precede numbers.
+		return split(hemispheres[1])
+	case len(hemispheres) == 2:
+		return split(hemispheres[0] + 1)
+	case len(degrees) == 2:
+		return split(degrees[1])
+	case len(numbers) > 0 && len(numbers)%2 == 0 && len(hemispheres) == 0:
+		return split(numbers[len(numbers)/2])
+	}
+	return nil, fmt.Errorf("Expected two coordinates")
+}
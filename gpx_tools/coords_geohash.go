@@ -0,0 +1,139 @@
+package gpx_tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GeohashDirection is a direction of neighbouring geohash cell.
+type GeohashDirection int
+
+const (
+	North GeohashDirection = iota
+	NorthEast
+	East
+	SouthEast
+	South
+	SouthWest
+	West
+	NorthWest
+)
+
+// Alphabet of geohash, base 32 without a, i, l and o.
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash returns geohash of coordinates with given number of
+// characters, each adds 5 bits alternating longitude and Latitude.
+// Precision less than 1 returns empty string.
+func (c *Coordinates) Geohash(precision int) string {
+	if precision < 1 {
+		return ""
+	}
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+	hash := make([]byte, precision)
+	even := true
+	for i := range hash {
+		var index int
+		for bit := 0; bit < 5; bit++ {
+			index <<= 1
+			if even {
+				middle := (minLon + maxLon) / 2
+				if c.longitude >= middle {
+					index |= 1
+					minLon = middle
+				} else {
+					maxLon = middle
+				}
+			} else {
+				middle := (minLat + maxLat) / 2
+				if c.Latitude >= middle {
+					index |= 1
+					minLat = middle
+				} else {
+					maxLat = middle
+				}
+			}
+			even = !even
+		}
+		hash[i] = geohashAlphabet[index]
+	}
+	return string(hash)
+}
+
+// GeohashBounds returns the cell of geohash or error
+// if it contains invalid characters.
+func GeohashBounds(hash string) (*BoundsType, error) {
+	if hash == "" {
+		return nil, fmt.Errorf("Geohash is empty")
+	}
+	bounds := &BoundsType{MinlatAttr: -90, MaxlatAttr: 90, MinlonAttr: -180, MaxlonAttr: 180}
+	even := true
+	for _, r := range strings.ToLower(hash) {
+		index := strings.IndexRune(geohashAlphabet, r)
+		if index < 0 {
+			return nil, fmt.Errorf("Invalid character %q of geohash %q", r, hash)
+		}
+		for bit := 4; bit >= 0; bit-- {
+			set := index>>bit&1 == 1
+			if even {
+				middle := (bounds.MinlonAttr + bounds.MaxlonAttr) / 2
+				if set {
+					bounds.MinlonAttr = middle
+				} else {
+					bounds.MaxlonAttr = middle
+				}
+			} else {
+				middle := (bounds.MinlatAttr + bounds.MaxlatAttr) / 2
+				if set {
+					bounds.MinlatAttr = middle
+				} else {
+					bounds.MaxlatAttr = middle
+				}
+			}
+			even = !even
+		}
+	}
+	return bounds, nil
+}
+
+// DecodeGeohash returns the centre of geohash cell.
+func DecodeGeohash(hash string) (Coordinates, error) {
+	bounds, err := GeohashBounds(hash)
+	if err != nil {
+		return Coordinates{}, err
+	}
+	return NewCoordinates((bounds.MinlatAttr+bounds.MaxlatAttr)/2, (bounds.MinlonAttr+bounds.MaxlonAttr)/2), nil
+}
+
+// GeohashNeighbor returns geohash of the same precision next to
+// the given one in direction. Cells wrap over the antimeridian,
+// error is returned for cells beyond the poles.
+func GeohashNeighbor(hash string, direction GeohashDirection) (string, error) {
+	bounds, err := GeohashBounds(hash)
+	if err != nil {
+		return "", err
+	}
+	height, width := bounds.MaxlatAttr-bounds.MinlatAttr, bounds.MaxlonAttr-bounds.MinlonAttr
+	offsets := [][2]float64{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}[direction]
+	latitude := (bounds.MinlatAttr+bounds.MaxlatAttr)/2 + offsets[0]*height
+	longitude := (bounds.MinlonAttr+bounds.MaxlonAttr)/2 + offsets[1]*width
+	if latitude > 90 || latitude < -90 {
+		return "", fmt.Errorf("Geohash %q has no neighbour beyond the pole", hash)
+	}
+	c := NewCoordinates(latitude, longitude)
+	return c.Geohash(len(hash)), nil
+}
+
+// GeohashNeighbors returns geohashes of all eight neighbours indexed
+// by GeohashDirection, neighbours beyond the poles are empty.
+func GeohashNeighbors(hash string) (neighbors [8]string, err error) {
+	if _, err := GeohashBounds(hash); err != nil {
+		return neighbors, err
+	}
+	for direction := range neighbors {
+		// The only error left is the pole, which leaves the neighbour empty.
+		neighbors[direction], _ = GeohashNeighbor(hash, GeohashDirection(direction))
+	}
+	return neighbors, nil
+}
@@ -0,0 +1,176 @@
+package gpx_tools
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// WebMercatorRadius is the radius of sphere of EPSG:3857 in meters.
+	WebMercatorRadius = 6378137.0
+	// WebMercatorMaxLatitude is the latitude of edges of square map.
+	WebMercatorMaxLatitude = 85.05112877980659
+	// TileSize is the size of map tiles in pixels.
+	TileSize = 256
+)
+
+// Tile is XYZ map tile, X grows east and Y south
+// from the north-west corner of the map.
+type Tile struct {
+	X, Y, Zoom int
+}
+
+// ToWebMercator projects coordinates to EPSG:3857 in meters,
+// Latitude is clamped to WebMercatorMaxLatitude.
+func (c *Coordinates) ToWebMercator() (x, y float64) {
+	latitude := math.Max(-WebMercatorMaxLatitude, math.Min(WebMercatorMaxLatitude, c.Latitude))
+	x = WebMercatorRadius * c.GetLongitudeRadians()
+	y = WebMercatorRadius * math.Log(math.Tan(math.Pi/4+latitude*math.Pi/360))
+	return x, y
+}
+
+// WebMercatorToCoordinates converts EPSG:3857 meters to coordinates.
+func WebMercatorToCoordinates(x, y float64) Coordinates {
+	latitude := (2*math.Atan(math.Exp(y/WebMercatorRadius)) - math.Pi/2) * 180 / math.Pi
+	return NewCoordinates(latitude, x/WebMercatorRadius*180/math.Pi)
+}
+
+// Return position in tiles at zoom level with fractional part
+// of position in the tile.
+func (c *Coordinates) tilePosition(zoom int) (x, y float64) {
+	mercatorX, mercatorY := c.ToWebMercator()
+	tiles := math.Exp2(float64(zoom))
+	x = (mercatorX/(2*math.Pi*WebMercatorRadius) + 0.5) * tiles
+	y = (0.5 - mercatorY/(2*math.Pi*WebMercatorRadius)) * tiles
+	maxTile := math.Nextafter(tiles, 0)
+	return math.Max(0, math.Min(maxTile, x)), math.Max(0, math.Min(maxTile, y))
+}
+
+// ToTile returns the tile containing coordinates at zoom level
+// and pixel offset of coordinates in the tile.
+func (c *Coordinates) ToTile(zoom int) (tile Tile, pixelX, pixelY float64) {
+	x, y := c.tilePosition(zoom)
+	tile = Tile{X: int(x), Y: int(y), Zoom: zoom}
+	return tile, (x - math.Floor(x)) * TileSize, (y - math.Floor(y)) * TileSize
+}
+
+// Bounds returns the area of the tile.
+func (tile Tile) Bounds() *BoundsType {
+	tiles := math.Exp2(float64(tile.Zoom))
+	latitude := func(y float64) float64 {
+		c := WebMercatorToCoordinates(0, (0.5-y/tiles)*2*math.Pi*WebMercatorRadius)
+		return c.Latitude
+	}
+	// Longitude is linear in tiles, which keeps edges of the map exact.
+	return &BoundsType{
+		MinlatAttr: latitude(float64(tile.Y + 1)), MinlonAttr: float64(tile.X)/tiles*360 - 180,
+		MaxlatAttr: latitude(float64(tile.Y)), MaxlonAttr: float64(tile.X+1)/tiles*360 - 180,
+	}
+}
+
+// Tiles returns tiles covering the bounds at zoom level sorted by
+// rows. Bounds with MinlonAttr greater than MaxlonAttr cross
+// the antimeridian.
+func (bounds *BoundsType) Tiles(zoom int) []Tile {
+	northWest := NewCoordinates(bounds.MaxlatAttr, bounds.MinlonAttr)
+	southEast := NewCoordinates(bounds.MinlatAttr, bounds.MaxlonAttr)
+	minX, minY := northWest.tilePosition(zoom)
+	maxX, maxY := southEast.tilePosition(zoom)
+	if bounds.MaxlonAttr == 180 {
+		maxX = math.Exp2(float64(zoom)) - 1
+	}
+	columns := int(maxX) - int(minX) + 1
+	count := int(math.Exp2(float64(zoom)))
+	if columns <= 0 {
+		columns += count
+	}
+	var tiles []Tile
+	for y := int(minY); y <= int(maxY); y++ {
+		for i := 0; i < columns && i < count; i++ {
+			tiles = append(tiles, Tile{X: (int(minX) + i) % count, Y: y, Zoom: zoom})
+		}
+	}
+	return tiles
+}
+
+// Tiles returns tiles crossed by lines of the track segments at zoom
+// level sorted by rows. Lines are straight in Web Mercator projection
+// and take the shorter way, crossing the antimeridian if needed.
+func (trk *TrkType) Tiles(zoom int) []Tile {
+	covered := map[Tile]bool{}
+	visit := func(tileX, tileY int) {
+		covered[Tile{tileX, tileY, zoom}] = true
+	}
+	size := math.Exp2(float64(zoom))
+	maxTile := math.Nextafter(size, 0)
+	for _, trkseg := range trk.Trkseg {
+		var x0, y0 float64
+		for i, wpt := range trkseg.Trkpt {
+			c := wpt.ToCoordinates()
+			x, y := c.tilePosition(zoom)
+			switch {
+			case i == 0:
+				visit(int(x), int(y))
+			case x-x0 > size/2:
+				// Westward over the antimeridian, split at its latitude.
+				crossY := y0 + (y-y0)*x0/(x0+size-x)
+				traverseTiles(x0, y0, 0, crossY, visit)
+				traverseTiles(maxTile, crossY, x, y, visit)
+			case x0-x > size/2:
+				// Eastward over the antimeridian.
+				crossY := y0 + (y-y0)*(size-x0)/(size-x0+x)
+				traverseTiles(x0, y0, maxTile, crossY, visit)
+				traverseTiles(0, crossY, x, y, visit)
+			default:
+				traverseTiles(x0, y0, x, y, visit)
+			}
+			x0, y0 = x, y
+		}
+	}
+
+	tiles := make([]Tile, 0, len(covered))
+	for tile := range covered {
+		tiles = append(tiles, tile)
+	}
+	sort.Slice(tiles, func(i, j int) bool {
+		if tiles[i].Y != tiles[j].Y {
+			return tiles[i].Y < tiles[j].Y
+		}
+		return tiles[i].X < tiles[j].X
+	})
+	return tiles
+}
+
+// Call visit for every grid cell crossed by the line, see Amanatides
+// and Woo: A Fast Voxel Traversal Algorithm for Ray Tracing.
+func traverseTiles(x0, y0, x1, y1 float64, visit func(x, y int)) {
+	x, y := int(x0), int(y0)
+	endX, endY := int(x1), int(y1)
+	visit(x, y)
+
+	step := func(from, to float64) (direction int, delta, next float64) {
+		switch {
+		case to > from:
+			return 1, 1 / (to - from), (math.Floor(from) + 1 - from) / (to - from)
+		case to < from:
+			return -1, 1 / (from - to), (from - math.Floor(from)) / (from - to)
+		}
+		return 0, math.Inf(1), math.Inf(1)
+	}
+	stepX, deltaX, nextX := step(x0, x1)
+	stepY, deltaY, nextY := step(y0, y1)
+	for x != endX || y != endY {
+		if nextX < nextY {
+			x += stepX
+			nextX += deltaX
+		} else {
+			y += stepY
+			nextY += deltaY
+		}
+		if math.Min(nextX, nextY) > 1 && (x != endX || y != endY) {
+			// Rounding left the end cell unreached.
+			x, y = endX, endY
+		}
+		visit(x, y)
+	}
+}
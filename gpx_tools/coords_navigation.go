@@ -0,0 +1,297 @@
+package gpx_tools
+
+import (
+	"fmt"
+	"math"
+)
+
+// EarthRadius is the mean radius of the earth in meters used by
+// spherical formulas, same as by Haversine.
+const EarthRadius = 6371000.0
+
+// Return bearing in degrees in range [0, 360).
+func bearingDegrees(radians float64) DegreesType {
+	degrees := math.Mod(radians*180/math.Pi+360, 360)
+	if degrees == 360 {
+		degrees = 0
+	}
+	return DegreesType(degrees)
+}
+
+// Return coordinates from latitude and longitude in radians.
+func coordinatesFromRadians(phi, lambda float64) Coordinates {
+	return NewCoordinates(phi*180/math.Pi, lambda*180/math.Pi)
+}
+
+// Return angular distance between coordinates on sphere.
+func angularDistance(c1, c2 Coordinates) float64 {
+	phi1, phi2 := c1.GetLatitudeRadians(), c2.GetLatitudeRadians()
+	deltaPhi := phi2 - phi1
+	deltaLambda := c2.GetLongitudeRadians() - c1.GetLongitudeRadians()
+	a := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+	return 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// InitialBearingTo returns bearing of great circle
+// at the start of path to coordinates.
+func (c *Coordinates) InitialBearingTo(coordinates Coordinates) DegreesType {
+	phi1, phi2 := c.GetLatitudeRadians(), coordinates.GetLatitudeRadians()
+	deltaLambda := coordinates.GetLongitudeRadians() - c.GetLongitudeRadians()
+	y := math.Sin(deltaLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(deltaLambda)
+	return bearingDegrees(math.Atan2(y, x))
+}
+
+// FinalBearingTo returns bearing of great circle
+// at the end of path to coordinates.
+func (c *Coordinates) FinalBearingTo(coordinates Coordinates) DegreesType {
+	return bearingDegrees(float64(coordinates.InitialBearingTo(*c))*math.Pi/180 + math.Pi)
+}
+
+// DestinationPoint returns coordinates reached after travelling
+// distance in meters along great circle starting at bearing.
+func (c *Coordinates) DestinationPoint(distance float64, bearing DegreesType) Coordinates {
+	phi1, lambda1 := c.GetLatitudeRadians(), c.GetLongitudeRadians()
+	delta := distance / EarthRadius
+	theta := float64(bearing) * math.Pi / 180
+	phi2 := math.Asin(math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta))
+	lambda2 := lambda1 + math.Atan2(
+		math.Sin(theta)*math.Sin(delta)*math.Cos(phi1),
+		math.Cos(delta)-math.Sin(phi1)*math.Sin(phi2))
+	return coordinatesFromRadians(phi2, lambda2)
+}
+
+// MidpointTo returns the point half way along great circle to coordinates.
+func (c *Coordinates) MidpointTo(coordinates Coordinates) Coordinates {
+	return c.IntermediatePointTo(coordinates, 0.5)
+}
+
+// IntermediatePointTo returns the point at fraction of great circle
+// path to coordinates, 0 is the start and 1 the end.
+func (c *Coordinates) IntermediatePointTo(coordinates Coordinates, fraction float64) Coordinates {
+	delta := angularDistance(*c, coordinates)
+	if delta == 0 {
+		return *c
+	}
+	phi1, lambda1 := c.GetLatitudeRadians(), c.GetLongitudeRadians()
+	phi2, lambda2 := coordinates.GetLatitudeRadians(), coordinates.GetLongitudeRadians()
+	a := math.Sin((1-fraction)*delta) / math.Sin(delta)
+	b := math.Sin(fraction*delta) / math.Sin(delta)
+	x := a*math.Cos(phi1)*math.Cos(lambda1) + b*math.Cos(phi2)*math.Cos(lambda2)
+	y := a*math.Cos(phi1)*math.Sin(lambda1) + b*math.Cos(phi2)*math.Sin(lambda2)
+	z := a*math.Sin(phi1) + b*math.Sin(phi2)
+	return coordinatesFromRadians(math.Atan2(z, math.Hypot(x, y)), math.Atan2(y, x))
+}
+
+// Intersection returns the point where great circle starting at bearing
+// crosses great circle starting at coordinates at otherBearing. Error
+// is returned when the paths coincide or they meet only behind one
+// of the starting points.
+func (c *Coordinates) Intersection(bearing DegreesType, coordinates Coordinates, otherBearing DegreesType) (Coordinates, error) {
+	phi1, lambda1 := c.GetLatitudeRadians(), c.GetLongitudeRadians()
+	phi2, lambda2 := coordinates.GetLatitudeRadians(), coordinates.GetLongitudeRadians()
+	theta13 := float64(bearing) * math.Pi / 180
+	theta23 := float64(otherBearing) * math.Pi / 180
+
+	delta12 := angularDistance(*c, coordinates)
+	if delta12 == 0 {
+		return *c, nil
+	}
+	// Bearings between the starting points.
+	clamp := func(x float64) float64 { return math.Max(-1, math.Min(1, x)) }
+	thetaA := math.Acos(clamp((math.Sin(phi2) - math.Sin(phi1)*math.Cos(delta12)) / (math.Sin(delta12) * math.Cos(phi1))))
+	thetaB := math.Acos(clamp((math.Sin(phi1) - math.Sin(phi2)*math.Cos(delta12)) / (math.Sin(delta12) * math.Cos(phi2))))
+	theta12, theta21 := thetaA, 2*math.Pi-thetaB
+	if math.Sin(lambda2-lambda1) <= 0 {
+		theta12, theta21 = 2*math.Pi-thetaA, thetaB
+	}
+
+	// Angles of triangle at the starting points.
+	alpha1 := theta13 - theta12
+	alpha2 := theta21 - theta23
+	if math.Abs(math.Sin(alpha1)) < 1e-15 && math.Abs(math.Sin(alpha2)) < 1e-15 {
+		return Coordinates{}, fmt.Errorf("Paths coincide, there are infinite intersections")
+	}
+	if math.Sin(alpha1)*math.Sin(alpha2) < 0 {
+		return Coordinates{}, fmt.Errorf("Paths do not intersect ahead of both starting points")
+	}
+
+	cosAlpha3 := -math.Cos(alpha1)*math.Cos(alpha2) + math.Sin(alpha1)*math.Sin(alpha2)*math.Cos(delta12)
+	delta13 := math.Atan2(math.Sin(delta12)*math.Sin(alpha1)*math.Sin(alpha2), math.Cos(alpha2)+math.Cos(alpha1)*cosAlpha3)
+	phi3 := math.Asin(clamp(math.Sin(phi1)*math.Cos(delta13) + math.Cos(phi1)*math.Sin(delta13)*math.Cos(theta13)))
+	lambda3 := lambda1 + math.Atan2(
+		math.Sin(theta13)*math.Sin(delta13)*math.Cos(phi1),
+		math.Cos(delta13)-math.Sin(phi1)*math.Sin(phi3))
+	return coordinatesFromRadians(phi3, lambda3), nil
+}
+
+// VincentyInverse returns distance in meters between coordinates on
+// WGS84 ellipsoid with initial and final bearing of the geodesic.
+// Error is returned when the iteration does not converge, which
+// happens for nearly antipodal points.
+func VincentyInverse(c1, c2 Coordinates) (distance float64, initial, final DegreesType, err error) {
+	f := wgs84Flattening
+	a := wgs84SemiMajorAxis
+	b := a * (1 - f)
+	phi1, phi2 := c1.GetLatitudeRadians(), c2.GetLatitudeRadians()
+	l := c2.GetLongitudeRadians() - c1.GetLongitudeRadians()
+	sinU1, cosU1 := math.Sincos(math.Atan((1 - f) * math.Tan(phi1)))
+	sinU2, cosU2 := math.Sincos(math.Atan((1 - f) * math.Tan(phi2)))
+
+	var sinSigma, cosSigma, sigma, cosSquaredAlpha, cos2SigmaM, sinLambda, cosLambda float64
+	lambda := l
+	for i := 0; ; i++ {
+		if i == 1000 {
+			return 0, 0, 0, fmt.Errorf("Vincenty formula failed to converge")
+		}
+		sinLambda, cosLambda = math.Sincos(lambda)
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			// Coincident points.
+			return 0, 0, 0, nil
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSquaredAlpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cosSquaredAlpha != 0 {
+			// Not on the equatorial line.
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSquaredAlpha
+		}
+		c := f / 16 * cosSquaredAlpha * (4 + f*(4-3*cosSquaredAlpha))
+		previous := lambda
+		lambda = l + (1-c)*f*sinAlpha*(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-previous) < 1e-12 {
+			break
+		}
+	}
+
+	uSquared := cosSquaredAlpha * (a*a - b*b) / (b * b)
+	deltaSigma := vincentyDeltaSigma(uSquared, sinSigma, cosSigma, cos2SigmaM)
+	distance = b * vincentyA(uSquared) * (sigma - deltaSigma)
+	initial = bearingDegrees(math.Atan2(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda))
+	final = bearingDegrees(math.Atan2(cosU1*sinLambda, -sinU1*cosU2+cosU1*sinU2*cosLambda))
+	return distance, initial, final, nil
+}
+
+// VincentyDirect returns coordinates reached after travelling distance
+// in meters along geodesic of WGS84 ellipsoid starting at bearing
+// together with the final bearing.
+func VincentyDirect(c Coordinates, distance float64, bearing DegreesType) (Coordinates, DegreesType) {
+	f := wgs84Flattening
+	a := wgs84SemiMajorAxis
+	b := a * (1 - f)
+	sinAlpha1, cosAlpha1 := math.Sincos(float64(bearing) * math.Pi / 180)
+	tanU1 := (1 - f) * math.Tan(c.GetLatitudeRadians())
+	cosU1 := 1 / math.Sqrt(1+tanU1*tanU1)
+	sinU1 := tanU1 * cosU1
+	sigma1 := math.Atan2(tanU1, cosAlpha1)
+	sinAlpha := cosU1 * sinAlpha1
+	cosSquaredAlpha := 1 - sinAlpha*sinAlpha
+	uSquared := cosSquaredAlpha * (a*a - b*b) / (b * b)
+	bigA := vincentyA(uSquared)
+
+	sigma := distance / (b * bigA)
+	var sinSigma, cosSigma, cos2SigmaM float64
+	for i := 0; i < 100; i++ {
+		cos2SigmaM = math.Cos(2*sigma1 + sigma)
+		sinSigma, cosSigma = math.Sincos(sigma)
+		previous := sigma
+		sigma = distance/(b*bigA) + vincentyDeltaSigma(uSquared, sinSigma, cosSigma, cos2SigmaM)
+		if math.Abs(sigma-previous) < 1e-12 {
+			break
+		}
+	}
+	cos2SigmaM = math.Cos(2*sigma1 + sigma)
+	sinSigma, cosSigma = math.Sincos(sigma)
+
+	x := sinU1*sinSigma - cosU1*cosSigma*cosAlpha1
+	phi2 := math.Atan2(sinU1*cosSigma+cosU1*sinSigma*cosAlpha1, (1-f)*math.Hypot(sinAlpha, x))
+	lambda := math.Atan2(sinSigma*sinAlpha1, cosU1*cosSigma-sinU1*sinSigma*cosAlpha1)
+	cc := f / 16 * cosSquaredAlpha * (4 + f*(4-3*cosSquaredAlpha))
+	l := lambda - (1-cc)*f*sinAlpha*(sigma+cc*sinSigma*(cos2SigmaM+cc*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+	return coordinatesFromRadians(phi2, c.GetLongitudeRadians()+l), bearingDegrees(math.Atan2(sinAlpha, -x))
+}
+
+// Return coefficient A of Vincenty's formulae.
+func vincentyA(uSquared float64) float64 {
+	return 1 + uSquared/16384*(4096+uSquared*(-768+uSquared*(320-175*uSquared)))
+}
+
+// Return difference of angular distance on sphere and ellipsoid.
+func vincentyDeltaSigma(uSquared, sinSigma, cosSigma, cos2SigmaM float64) float64 {
+	b := uSquared / 1024 * (256 + uSquared*(-128+uSquared*(74-47*uSquared)))
+	return b * sinSigma * (cos2SigmaM + b/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		b/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+}
+
+// VincentyInitialBearingTo returns bearing of geodesic
+// at the start of path to coordinates, see VincentyInverse.
+func (c *Coordinates) VincentyInitialBearingTo(coordinates Coordinates) (DegreesType, error) {
+	_, initial, _, err := VincentyInverse(*c, coordinates)
+	return initial, err
+}
+
+// VincentyFinalBearingTo returns bearing of geodesic
+// at the end of path to coordinates, see VincentyInverse.
+func (c *Coordinates) VincentyFinalBearingTo(coordinates Coordinates) (DegreesType, error) {
+	_, _, final, err := VincentyInverse(*c, coordinates)
+	return final, err
+}
+
+// VincentyDestinationPoint returns coordinates reached after travelling
+// distance in meters along geodesic starting at bearing.
+func (c *Coordinates) VincentyDestinationPoint(distance float64, bearing DegreesType) Coordinates {
+	destination, _ := VincentyDirect(*c, distance, bearing)
+	return destination
+}
+
+// VincentyMidpointTo returns the point half way along geodesic to coordinates.
+func (c *Coordinates) VincentyMidpointTo(coordinates Coordinates) (Coordinates, error) {
+	return c.VincentyIntermediatePointTo(coordinates, 0.5)
+}
+
+// VincentyIntermediatePointTo returns the point at fraction of geodesic
+// path to coordinates, 0 is the start and 1 the end.
+func (c *Coordinates) VincentyIntermediatePointTo(coordinates Coordinates, fraction float64) (Coordinates, error) {
+	distance, initial, _, err := VincentyInverse(*c, coordinates)
+	if err != nil {
+		return Coordinates{}, err
+	}
+	return c.VincentyDestinationPoint(distance*fraction, initial), nil
+}
+
+// VincentyIntersection returns the point where geodesic starting at
+// bearing crosses geodesic starting at coordinates at otherBearing.
+// Spherical intersection is refined by Newton's method on distances
+// along both geodesics, errors are the same as of Intersection.
+func (c *Coordinates) VincentyIntersection(bearing DegreesType, coordinates Coordinates, otherBearing DegreesType) (Coordinates, error) {
+	intersection, err := c.Intersection(bearing, coordinates, otherBearing)
+	if err != nil {
+		return Coordinates{}, err
+	}
+	distance1 := angularDistance(*c, intersection) * EarthRadius
+	distance2 := angularDistance(coordinates, intersection) * EarthRadius
+	for i := 0; i < 50; i++ {
+		p1, final1 := VincentyDirect(*c, distance1, bearing)
+		p2, final2 := VincentyDirect(coordinates, distance2, otherBearing)
+		// Offset of p1 from p2 to north and east in meters.
+		north := (p1.Latitude - p2.Latitude) * math.Pi / 180 * EarthRadius
+		east := WrapLongitude(p1.longitude-p2.longitude) * math.Pi / 180 * EarthRadius * math.Cos(p1.GetLatitudeRadians())
+		if math.Hypot(north, east) < 1e-6 {
+			return p1, nil
+		}
+		// Moving along geodesics changes the offset in their final bearings.
+		sin1, cos1 := math.Sincos(float64(final1) * math.Pi / 180)
+		sin2, cos2 := math.Sincos(float64(final2) * math.Pi / 180)
+		determinant := -cos1*sin2 + cos2*sin1
+		if math.Abs(determinant) < 1e-12 {
+			return Coordinates{}, fmt.Errorf("Geodesics are parallel at the intersection")
+		}
+		distance1 -= (-north*sin2 + cos2*east) / determinant
+		distance2 -= (cos1*east - sin1*north) / determinant
+	}
+	return Coordinates{}, fmt.Errorf("Intersection of geodesics failed to converge")
+}
@@ -0,0 +1,306 @@
+package gpx_tools
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// UTM is a position in Universal Transverse Mercator grid on WGS84.
+// Band is the latitude band letter C–X, bands N and higher are on the
+// northern hemisphere. Easting includes false easting of 500 km and
+// northing on the southern hemisphere false northing of 10 000 km.
+type UTM struct {
+	Zone     int
+	Band     byte
+	Easting  float64
+	Northing float64
+}
+
+const (
+	utmScale         = 0.9996
+	utmFalseEasting  = 500000.0
+	utmFalseNorthing = 10000000.0
+	// Latitude bands of 8°, X has 12°.
+	utmBands = "CDEFGHJKLMNPQRSTUVWX"
+)
+
+// Coefficients of Krüger series to sixth order of third flattening,
+// see Karney: Transverse Mercator with an accuracy of a few nanometers.
+var utmA, utmAlpha, utmBeta = krugerCoefficients()
+
+func krugerCoefficients() (a float64, alpha, beta [6]float64) {
+	n := wgs84Flattening / (2 - wgs84Flattening)
+	n2, n3, n4, n5, n6 := n*n, n*n*n, n*n*n*n, n*n*n*n*n, n*n*n*n*n*n
+	a = wgs84SemiMajorAxis / (1 + n) * (1 + n2/4 + n4/64 + n6/256)
+	alpha = [6]float64{
+		n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180 - 127*n5/288 + 7891*n6/37800,
+		13*n2/48 - 3*n3/5 + 557*n4/1440 + 281*n5/630 - 1983433*n6/1935360,
+		61*n3/240 - 103*n4/140 + 15061*n5/26880 + 167603*n6/181440,
+		49561*n4/161280 - 179*n5/168 + 6601661*n6/7257600,
+		34729*n5/80640 - 3418889*n6/1995840,
+		212378941 * n6 / 319334400,
+	}
+	beta = [6]float64{
+		n/2 - 2*n2/3 + 37*n3/96 - n4/360 - 81*n5/512 + 96199*n6/604800,
+		n2/48 + n3/15 - 437*n4/1440 + 46*n5/105 - 1118711*n6/3870720,
+		17*n3/480 - 37*n4/840 - 209*n5/4480 + 5569*n6/90720,
+		4397*n4/161280 - 11*n5/504 - 830251*n6/7257600,
+		4583*n5/161280 - 108847*n6/3991680,
+		20648693 * n6 / 638668800,
+	}
+	return a, alpha, beta
+}
+
+// Parameters of WGS84 ellipsoid.
+const (
+	wgs84SemiMajorAxis = 6378137.0
+	wgs84Flattening    = 1 / 298.257223563
+)
+
+var wgs84Eccentricity = math.Sqrt(wgs84Flattening * (2 - wgs84Flattening))
+
+// Return UTM zone and band of coordinates including exceptions
+// of Norway and Svalbard, or error outside of latitudes -80° to 84°.
+func utmZone(latitude, longitude float64) (zone int, band byte, err error) {
+	if latitude < -80 || latitude > 84 {
+		return 0, 0, fmt.Errorf("Latitude %v is outside of UTM range -80° to 84°", latitude)
+	}
+	band = utmBands[int(math.Min(math.Floor(latitude/8+10), float64(len(utmBands)-1)))]
+	zone = int(math.Floor((WrapLongitude(longitude)+180)/6)) + 1
+	switch {
+	case band == 'V' && zone == 31 && longitude >= 3:
+		zone = 32
+	case band == 'X' && zone == 32:
+		zone = 31
+		if longitude >= 9 {
+			zone = 33
+		}
+	case band == 'X' && zone == 34:
+		zone = 33
+		if longitude >= 21 {
+			zone = 35
+		}
+	case band == 'X' && zone == 36:
+		zone = 35
+		if longitude >= 33 {
+			zone = 37
+		}
+	}
+	return zone, band, nil
+}
+
+// Return longitude of the central meridian of zone in radians.
+func utmCentralMeridian(zone int) float64 {
+	return float64((zone-1)*6-180+3) * math.Pi / 180
+}
+
+// ToUTM converts coordinates to UTM in their zone,
+// error is returned outside of latitudes -80° to 84°.
+func (c *Coordinates) ToUTM() (UTM, error) {
+	zone, band, err := utmZone(c.Latitude, c.longitude)
+	if err != nil {
+		return UTM{}, err
+	}
+	easting, northing := transverseMercator(c.GetLatitudeRadians(), c.GetLongitudeRadians()-utmCentralMeridian(zone))
+	if northing < 0 {
+		northing += utmFalseNorthing
+	}
+	return UTM{Zone: zone, Band: band, Easting: easting + utmFalseEasting, Northing: northing}, nil
+}
+
+// Project latitude and longitude from central meridian in radians
+// to easting and northing without false origin.
+func transverseMercator(phi, lambda float64) (x, y float64) {
+	e := wgs84Eccentricity
+	tau := math.Tan(phi)
+	sigma := math.Sinh(e * math.Atanh(e*tau/math.Sqrt(1+tau*tau)))
+	tauPrime := tau*math.Sqrt(1+sigma*sigma) - sigma*math.Sqrt(1+tau*tau)
+
+	xiPrime := math.Atan2(tauPrime, math.Cos(lambda))
+	etaPrime := math.Asinh(math.Sin(lambda) / math.Sqrt(tauPrime*tauPrime+math.Cos(lambda)*math.Cos(lambda)))
+	xi, eta := xiPrime, etaPrime
+	for j, alpha := range utmAlpha {
+		k := 2 * float64(j+1)
+		xi += alpha * math.Sin(k*xiPrime) * math.Cosh(k*etaPrime)
+		eta += alpha * math.Cos(k*xiPrime) * math.Sinh(k*etaPrime)
+	}
+	return utmScale * utmA * eta, utmScale * utmA * xi
+}
+
+// ToCoordinates converts UTM position to coordinates, hemisphere is
+// given by the band. Error is returned for invalid zone or band.
+func (utm UTM) ToCoordinates() (Coordinates, error) {
+	if utm.Zone < 1 || utm.Zone > 60 {
+		return Coordinates{}, fmt.Errorf("Invalid UTM zone %d", utm.Zone)
+	}
+	if strings.IndexByte(utmBands, utm.Band) < 0 {
+		return Coordinates{}, fmt.Errorf("Invalid UTM band %q", utm.Band)
+	}
+	x, y := utm.Easting-utmFalseEasting, utm.Northing
+	if !utm.IsNorthern() {
+		y -= utmFalseNorthing
+	}
+
+	eta, xi := x/(utmScale*utmA), y/(utmScale*utmA)
+	xiPrime, etaPrime := xi, eta
+	for j, beta := range utmBeta {
+		k := 2 * float64(j+1)
+		xiPrime -= beta * math.Sin(k*xi) * math.Cosh(k*eta)
+		etaPrime -= beta * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	sinhEtaPrime, sinXiPrime, cosXiPrime := math.Sinh(etaPrime), math.Sin(xiPrime), math.Cos(xiPrime)
+	tauPrime := sinXiPrime / math.Sqrt(sinhEtaPrime*sinhEtaPrime+cosXiPrime*cosXiPrime)
+
+	// Newton-Raphson iteration of tau from tau prime.
+	e := wgs84Eccentricity
+	tau := tauPrime
+	for i := 0; i < 20; i++ {
+		sigma := math.Sinh(e * math.Atanh(e*tau/math.Sqrt(1+tau*tau)))
+		tauI := tau*math.Sqrt(1+sigma*sigma) - sigma*math.Sqrt(1+tau*tau)
+		delta := (tauPrime - tauI) / math.Sqrt(1+tauI*tauI) *
+			(1 + (1-e*e)*tau*tau) / ((1 - e*e) * math.Sqrt(1+tau*tau))
+		tau += delta
+		if math.Abs(delta) < 1e-12 {
+			break
+		}
+	}
+
+	latitude := math.Atan(tau) * 180 / math.Pi
+	longitude := (math.Atan2(sinhEtaPrime, cosXiPrime) + utmCentralMeridian(utm.Zone)) * 180 / math.Pi
+	return NewCoordinatesStrict(latitude, longitude)
+}
+
+// IsNorthern returns true for positions on the northern hemisphere.
+func (utm UTM) IsNorthern() bool {
+	return utm.Band >= 'N'
+}
+
+// String returns zone, band, easting and northing
+// rounded to meters, e.g. 33U 455678 5438123.
+func (utm UTM) String() string {
+	return fmt.Sprintf("%d%c %.0f %.0f", utm.Zone, utm.Band, math.Floor(utm.Easting+0.5), math.Floor(utm.Northing+0.5))
+}
+
+// ParseUTM parses zone with band, easting and northing
+// in meters separated by spaces, e.g. 33U 455678 5438123.
+func ParseUTM(text string) (UTM, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 4 {
+		// Zone and band separated, e.g. 33 U 455678 5438123.
+		fields = []string{fields[0] + fields[1], fields[2], fields[3]}
+	}
+	if len(fields) != 3 || len(fields[0]) < 2 {
+		return UTM{}, fmt.Errorf("Invalid UTM %q", text)
+	}
+	zone, band, err := parseGridZone(fields[0])
+	if err != nil {
+		return UTM{}, fmt.Errorf("Invalid UTM %q: %v", text, err)
+	}
+	easting, errEasting := strconv.ParseFloat(fields[1], 64)
+	northing, errNorthing := strconv.ParseFloat(fields[2], 64)
+	if errEasting != nil || errNorthing != nil || easting < 0 || easting >= 1000000 || northing < 0 || northing > utmFalseNorthing {
+		return UTM{}, fmt.Errorf("Invalid UTM %q: invalid easting or northing", text)
+	}
+	return UTM{Zone: zone, Band: band, Easting: easting, Northing: northing}, nil
+}
+
+// Parse zone number followed by band letter, e.g. 33U.
+func parseGridZone(text string) (zone int, band byte, err error) {
+	band = byte(unicode.ToUpper(rune(text[len(text)-1])))
+	zone, err = strconv.Atoi(text[:len(text)-1])
+	if err != nil || zone < 1 || zone > 60 {
+		return 0, 0, fmt.Errorf("invalid zone %q", text[:len(text)-1])
+	}
+	if strings.IndexByte(utmBands, band) < 0 {
+		return 0, 0, fmt.Errorf("invalid band %q", band)
+	}
+	return zone, band, nil
+}
+
+// Letters of 100 km squares of MGRS, columns repeat every
+// three zones and rows are shifted in even zones.
+var (
+	mgrsColumnLetters = []string{"ABCDEFGH", "JKLMNPQR", "STUVWXYZ"}
+	mgrsRowLetters    = []string{"ABCDEFGHJKLMNPQRSTUV", "FGHJKLMNPQRSTUVABCDE"}
+)
+
+// ToMGRS returns MGRS reference of coordinates with given number
+// of digits of easting and northing, 5 for 1 m, 4 for 10 m down to
+// 0 for 100 km square, e.g. 33U VP 55678 38123. Position is truncated
+// to the square containing it, as required by MGRS.
+func (c *Coordinates) ToMGRS(precision int) (string, error) {
+	if precision < 0 || precision > 5 {
+		return "", fmt.Errorf("MGRS precision %d is out of range 0 to 5", precision)
+	}
+	utm, err := c.ToUTM()
+	if err != nil {
+		return "", err
+	}
+	column := int(math.Floor(utm.Easting / 100000))
+	row := int(math.Floor(utm.Northing/100000)) % 20
+	square := string([]byte{
+		mgrsColumnLetters[(utm.Zone-1)%3][column-1],
+		mgrsRowLetters[(utm.Zone-1)%2][row],
+	})
+
+	reference := fmt.Sprintf("%d%c %s", utm.Zone, utm.Band, square)
+	if precision > 0 {
+		// Rounding to micrometers avoids truncating of values like 99.9999999.
+		divisor := math.Pow10(5 - precision)
+		easting := math.Floor(math.Round(math.Mod(utm.Easting, 100000)*1e6) / 1e6 / divisor)
+		northing := math.Floor(math.Round(math.Mod(utm.Northing, 100000)*1e6) / 1e6 / divisor)
+		reference += fmt.Sprintf(" %0*.0f %0*.0f", precision, easting, precision, northing)
+	}
+	return reference, nil
+}
+
+// ParseMGRS parses MGRS reference with or without spaces, e.g.
+// 33U VP 55678 38123 or 33UVP5567838123, and returns UTM position
+// of the south-west corner of the referenced square.
+func ParseMGRS(text string) (UTM, error) {
+	compact := strings.ToUpper(strings.Join(strings.Fields(text), ""))
+	zoneEnd := strings.IndexFunc(compact, func(r rune) bool { return r < '0' || r > '9' })
+	if zoneEnd < 1 || len(compact) < zoneEnd+3 {
+		return UTM{}, fmt.Errorf("Invalid MGRS %q", text)
+	}
+	zone, band, err := parseGridZone(compact[:zoneEnd+1])
+	if err != nil {
+		return UTM{}, fmt.Errorf("Invalid MGRS %q: %v", text, err)
+	}
+	column := strings.IndexByte(mgrsColumnLetters[(zone-1)%3], compact[zoneEnd+1])
+	row := strings.IndexByte(mgrsRowLetters[(zone-1)%2], compact[zoneEnd+2])
+	if column < 0 || row < 0 {
+		return UTM{}, fmt.Errorf("Invalid MGRS %q: invalid 100 km square %s", text, compact[zoneEnd+1:zoneEnd+3])
+	}
+
+	digits := compact[zoneEnd+3:]
+	if len(digits)%2 != 0 || len(digits) > 10 || strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return UTM{}, fmt.Errorf("Invalid MGRS %q: invalid easting and northing %q", text, digits)
+	}
+	precision := len(digits) / 2
+	var easting, northing float64
+	if precision > 0 {
+		multiplier := math.Pow10(5 - precision)
+		e, _ := strconv.Atoi(digits[:precision])
+		n, _ := strconv.Atoi(digits[precision:])
+		easting, northing = float64(e)*multiplier, float64(n)*multiplier
+	}
+	easting += float64(column+1) * 100000
+	northing += float64(row) * 100000
+
+	// Rows repeat every 2000 km, the right cycle is the first one
+	// north of the bottom of the band.
+	bandLatitude := float64(strings.IndexByte(utmBands, band)-10) * 8 * math.Pi / 180
+	_, bandNorthing := transverseMercator(bandLatitude, 0)
+	if bandNorthing < 0 {
+		bandNorthing += utmFalseNorthing
+	}
+	bandNorthing = math.Floor(bandNorthing/100000) * 100000
+	for northing < bandNorthing {
+		northing += 2000000
+	}
+	return UTM{Zone: zone, Band: band, Easting: easting, Northing: northing}, nil
+}
@@ -0,0 +1,553 @@
+package gpx_tools
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CsvColumn is a column of CSV export and import.
+type CsvColumn string
+
+const (
+	// CsvKind is "wpt", "rtept" or "trkpt".
+	CsvKind CsvColumn = "kind"
+	// CsvTrack is index of route or track the point belongs to.
+	CsvTrack CsvColumn = "track"
+	// CsvSegment is index of track segment.
+	CsvSegment CsvColumn = "segment"
+	// CsvIndex is index of point in its list.
+	CsvIndex         CsvColumn = "index"
+	CsvLat           CsvColumn = "lat"
+	CsvLon           CsvColumn = "lon"
+	CsvEle           CsvColumn = "ele"
+	CsvTime          CsvColumn = "time"
+	CsvName          CsvColumn = "name"
+	CsvCmt           CsvColumn = "cmt"
+	CsvDesc          CsvColumn = "desc"
+	CsvSrc           CsvColumn = "src"
+	CsvSym           CsvColumn = "sym"
+	CsvType          CsvColumn = "type"
+	CsvFix           CsvColumn = "fix"
+	CsvSat           CsvColumn = "sat"
+	CsvHdop          CsvColumn = "hdop"
+	CsvVdop          CsvColumn = "vdop"
+	CsvPdop          CsvColumn = "pdop"
+	CsvMagvar        CsvColumn = "magvar"
+	CsvGeoidheight   CsvColumn = "geoidheight"
+	CsvAgeofdgpsdata CsvColumn = "ageofdgpsdata"
+	CsvDgpsid        CsvColumn = "dgpsid"
+	// CsvDistance is distance from the start of route or track in meters,
+	// it is only exported.
+	CsvDistance CsvColumn = "distance"
+	// CsvSpeed is speed from the previous point in m/s, it is only exported.
+	CsvSpeed CsvColumn = "speed"
+)
+
+// DefaultCsvColumns are exported when no columns are configured.
+var DefaultCsvColumns = []CsvColumn{CsvKind, CsvTrack, CsvSegment, CsvLat, CsvLon, CsvEle, CsvTime, CsvName, CsvDistance, CsvSpeed}
+
+// Special time formats of CSV, other formats are layouts of time.Format.
+const (
+	// CsvUnixTime is time in seconds since 1970-01-01 UTC.
+	CsvUnixTime = "unix"
+	// CsvUnixMilliTime is time in milliseconds since 1970-01-01 UTC.
+	CsvUnixMilliTime = "unixms"
+)
+
+// Units of elevation, values are meters per unit.
+const (
+	UnitMeters = 1.0
+	UnitFeet   = 0.3048
+)
+
+// CsvWriteOptions configure WriteCsv.
+// Zero value writes DefaultCsvColumns separated by commas.
+type CsvWriteOptions struct {
+	Columns []CsvColumn
+	// Delimiter of fields, zero means comma.
+	Delimiter rune
+	// TimeFormat is a layout of time.Format, CsvUnixTime or CsvUnixMilliTime,
+	// empty string writes time as in GPX.
+	TimeFormat string
+	// ElevationUnit in meters per unit, zero means meters.
+	ElevationUnit float64
+	// Algorithm used to calculate distance and speed, nil means Haversine.
+	Algorithm DistanceAlgorithm
+}
+
+// CsvMapping configures ParseCsv.
+// Zero value reads comma separated waypoints with columns named like CsvColumn.
+type CsvMapping struct {
+	// Columns maps fields to header names of the file. Fields missing
+	// here are read from columns named like the field (case insensitive),
+	// "latitude", "lng", "longitude", "elevation" and "altitude" are
+	// recognized too.
+	Columns map[CsvColumn]string
+	// Delimiter of fields, zero means comma.
+	Delimiter rune
+	// TimeFormat is a layout of time.Parse, CsvUnixTime or CsvUnixMilliTime,
+	// empty string means xsd:dateTime, see ParseGpxTimeStr.
+	TimeFormat string
+	// TimeLocation of times without zone, nil means DefaultTimeLocation.
+	TimeLocation *time.Location
+	// ElevationUnit in meters per unit, zero means meters.
+	ElevationUnit float64
+	// Kind of points, "wpt", "rtept" or "trkpt", used when the file
+	// has no kind column. Empty string means "wpt".
+	Kind string
+}
+
+var csvAliases = map[string]CsvColumn{
+	"latitude":  CsvLat,
+	"lng":       CsvLon,
+	"longitude": CsvLon,
+	"elevation": CsvEle,
+	"altitude":  CsvEle,
+}
+
+// Getter and setter of a WptType field stored in a column.
+type csvField struct {
+	get func(wpt *WptType) string
+	set func(wpt *WptType, value string) error
+}
+
+var csvFields = map[CsvColumn]csvField{
+	CsvName:          csvStringField(func(wpt *WptType) *string { return &wpt.Name }),
+	CsvCmt:           csvStringField(func(wpt *WptType) *string { return &wpt.Cmt }),
+	CsvDesc:          csvStringField(func(wpt *WptType) *string { return &wpt.Desc }),
+	CsvSrc:           csvStringField(func(wpt *WptType) *string { return &wpt.Src }),
+	CsvSym:           csvStringField(func(wpt *WptType) *string { return &wpt.Sym }),
+	CsvType:          csvStringField(func(wpt *WptType) *string { return &wpt.Type }),
+	CsvFix:           csvStringField(func(wpt *WptType) *string { return &wpt.Fix }),
+	CsvSat:           csvIntField(func(wpt *WptType) **int { return &wpt.Sat }),
+	CsvHdop:          csvFloatField(func(wpt *WptType) **float64 { return &wpt.Hdop }),
+	CsvVdop:          csvFloatField(func(wpt *WptType) **float64 { return &wpt.Vdop }),
+	CsvPdop:          csvFloatField(func(wpt *WptType) **float64 { return &wpt.Pdop }),
+	CsvMagvar:        csvFloatField(func(wpt *WptType) **float64 { return &wpt.Magvar }),
+	CsvGeoidheight:   csvFloatField(func(wpt *WptType) **float64 { return &wpt.Geoidheight }),
+	CsvAgeofdgpsdata: csvFloatField(func(wpt *WptType) **float64 { return &wpt.Ageofdgpsdata }),
+	CsvDgpsid:        csvIntField(func(wpt *WptType) **int { return &wpt.Dgpsid }),
+}
+
+func csvStringField(field func(wpt *WptType) *string) csvField {
+	return csvField{
+		get: func(wpt *WptType) string { return *field(wpt) },
+		set: func(wpt *WptType, value string) error {
+			*field(wpt) = value
+			return nil
+		},
+	}
+}
+
+func csvFloatField(field func(wpt *WptType) **float64) csvField {
+	return csvField{
+		get: func(wpt *WptType) string {
+			if *field(wpt) == nil {
+				return ""
+			}
+			return formatFloat(**field(wpt))
+		},
+		set: func(wpt *WptType, value string) error {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("Invalid number %q", value)
+			}
+			*field(wpt) = &number
+			return nil
+		},
+	}
+}
+
+func csvIntField(field func(wpt *WptType) **int) csvField {
+	return csvField{
+		get: func(wpt *WptType) string {
+			if *field(wpt) == nil {
+				return ""
+			}
+			return strconv.Itoa(**field(wpt))
+		},
+		set: func(wpt *WptType, value string) error {
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("Invalid integer %q", value)
+			}
+			*field(wpt) = &number
+			return nil
+		},
+	}
+}
+
+// WriteCsvFile writes all points of the Gpx struct to a CSV file with
+// DefaultCsvColumns or returns an error if the file could not be written.
+func WriteCsvFile(gpx Gpx, path string) (err error) {
+	csvFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := csvFile.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	return WriteCsv(csvFile, gpx, CsvWriteOptions{})
+}
+
+// WriteCsv writes a header and a row for every waypoint, route point
+// and track point of the Gpx struct.
+//
+// Track and segment columns are indexes of route or track and its
+// segment, they are empty for waypoints. Distance is cumulative in
+// every route and track, speed is calculated from the previous point
+// of the same segment, it is empty for the first point and for points
+// without time.
+func WriteCsv(w io.Writer, gpx Gpx, opts CsvWriteOptions) error {
+	if opts.Columns == nil {
+		opts.Columns = DefaultCsvColumns
+	}
+	if opts.Algorithm == nil {
+		opts.Algorithm = Haversine
+	}
+	if opts.ElevationUnit == 0 {
+		opts.ElevationUnit = UnitMeters
+	}
+
+	writer := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		writer.Comma = opts.Delimiter
+	}
+	header := make([]string, len(opts.Columns))
+	for i, column := range opts.Columns {
+		header[i] = string(column)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	row := &csvRow{opts: opts, segment: -1}
+	writeRows := func(kind string, track, segment int, points []*WptType) error {
+		row.kind, row.track, row.segment = kind, track, segment
+		for i, wpt := range points {
+			if i > 0 {
+				distance, err := distanceBetween(points[i-1], wpt, opts.Algorithm)
+				if err != nil {
+					return err
+				}
+				row.distance += distance
+			}
+			row.index, row.wpt, row.previous = i, wpt, nil
+			if i > 0 {
+				row.previous = points[i-1]
+			}
+			if err := writer.Write(row.values()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := writeRows("wpt", -1, -1, gpx.Wpt); err != nil {
+		return err
+	}
+	for i, rte := range gpx.Rte {
+		row.distance = 0
+		if err := writeRows("rtept", i, -1, rte.Rtept); err != nil {
+			return err
+		}
+	}
+	for i, trk := range gpx.Trk {
+		row.distance = 0
+		for j, trkseg := range trk.Trkseg {
+			if err := writeRows("trkpt", i, j, trkseg.Trkpt); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+type csvRow struct {
+	opts     CsvWriteOptions
+	kind     string
+	track    int
+	segment  int
+	index    int
+	wpt      *WptType
+	previous *WptType
+	distance float64
+}
+
+func (row *csvRow) values() []string {
+	values := make([]string, len(row.opts.Columns))
+	for i, column := range row.opts.Columns {
+		values[i] = row.value(column)
+	}
+	return values
+}
+
+func (row *csvRow) value(column CsvColumn) string {
+	wpt := row.wpt
+	switch column {
+	case CsvKind:
+		return row.kind
+	case CsvTrack:
+		return csvIndex(row.track)
+	case CsvSegment:
+		return csvIndex(row.segment)
+	case CsvIndex:
+		return strconv.Itoa(row.index)
+	case CsvLat:
+		return formatFloat(wpt.LatAttr)
+	case CsvLon:
+		return formatFloat(wpt.LonAttr)
+	case CsvEle:
+		if !wpt.HasElevation() {
+			return ""
+		}
+		return formatFloat(*wpt.Ele / row.opts.ElevationUnit)
+	case CsvTime:
+		if wpt.Time == nil {
+			return ""
+		}
+		return formatCsvTime(wpt.Time, row.opts.TimeFormat)
+	case CsvDistance:
+		if row.kind == "wpt" {
+			return ""
+		}
+		return formatFloat(row.distance)
+	case CsvSpeed:
+		if row.kind == "wpt" || row.previous == nil {
+			return ""
+		}
+		speed, err := VelocityBetweenPoints(row.previous, wpt, row.opts.Algorithm)
+		if err != nil {
+			return ""
+		}
+		return formatFloat(speed)
+	}
+	if field, ok := csvFields[column]; ok {
+		return field.get(wpt)
+	}
+	return ""
+}
+
+func csvIndex(index int) string {
+	if index < 0 {
+		return ""
+	}
+	return strconv.Itoa(index)
+}
+
+func formatCsvTime(t *GpxTime, format string) string {
+	switch format {
+	case "":
+		return t.String()
+	case CsvUnixTime:
+		return strconv.FormatInt(t.Time.Unix(), 10)
+	case CsvUnixMilliTime:
+		return strconv.FormatInt(t.Time.UnixMilli(), 10)
+	}
+	return t.Time.Format(format)
+}
+
+func parseCsvTime(value, format string, location *time.Location) (*GpxTime, error) {
+	if location == nil {
+		location = DefaultTimeLocation
+	}
+	switch format {
+	case "":
+		t, err := ParseGpxTimeStrIn(value, location)
+		if err != nil {
+			return nil, err
+		}
+		return NewGpxTime(t), nil
+	case CsvUnixTime, CsvUnixMilliTime:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid time %q", value)
+		}
+		if format == CsvUnixTime {
+			number *= 1000
+		}
+		return NewGpxTime(time.UnixMilli(int64(number)).UTC()), nil
+	}
+	t, err := time.ParseInLocation(format, value, location)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid time %q", value)
+	}
+	return NewGpxTime(t), nil
+}
+
+// ParseCsvFile parses a CSV file with points, see ParseCsv.
+func ParseCsvFile(path string, mapping CsvMapping) (gpx Gpx, err error) {
+	csvFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer csvFile.Close()
+
+	return ParseCsv(csvFile, mapping)
+}
+
+// ParseCsv parses CSV with a header and a point in every row.
+//
+// Columns are found by the mapping, lat and lon columns are required.
+// Points become waypoints, route points or track points according to
+// the kind column or mapping.Kind. A new route or track is started when
+// value in the track column changes and a new segment when value in the
+// segment column changes. Columns not mapped to fields are ignored.
+func ParseCsv(r io.Reader, mapping CsvMapping) (gpx Gpx, err error) {
+	reader := csv.NewReader(r)
+	if mapping.Delimiter != 0 {
+		reader.Comma = mapping.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if mapping.ElevationUnit == 0 {
+		mapping.ElevationUnit = UnitMeters
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV has no header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	indexes := csvColumnIndexes(header, mapping.Columns)
+	if _, ok := indexes[CsvLat]; !ok {
+		return nil, fmt.Errorf("CSV has no latitude column")
+	}
+	if _, ok := indexes[CsvLon]; !ok {
+		return nil, fmt.Errorf("CSV has no longitude column")
+	}
+
+	builder := csvBuilder{gpx: NewGpx(DefaultCreator)}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := builder.row(record, indexes, mapping); err != nil {
+			return nil, fmt.Errorf("Row %d: %v", line, err)
+		}
+	}
+	return builder.gpx, nil
+}
+
+// Return indexes of columns of fields, mapped names are preferred
+// to names of fields and aliases.
+func csvColumnIndexes(header []string, mapped map[CsvColumn]string) map[CsvColumn]int {
+	indexes := map[CsvColumn]int{}
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		column := CsvColumn(strings.ToLower(name))
+		if alias, ok := csvAliases[string(column)]; ok {
+			column = alias
+		}
+		if _, ok := indexes[column]; !ok {
+			indexes[column] = i
+		}
+	}
+	for column, name := range mapped {
+		delete(indexes, column)
+		for i, headerName := range header {
+			if strings.EqualFold(strings.TrimSpace(headerName), name) {
+				indexes[column] = i
+				break
+			}
+		}
+	}
+	return indexes
+}
+
+type csvBuilder struct {
+	gpx     *GpxType
+	kind    string
+	track   string
+	segment string
+	rte     *RteType
+	trk     *TrkType
+	trkseg  *TrksegType
+}
+
+func (b *csvBuilder) row(record []string, indexes map[CsvColumn]int, mapping CsvMapping) error {
+	get := func(column CsvColumn) string {
+		if i, ok := indexes[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	lat, err := strconv.ParseFloat(get(CsvLat), 64)
+	if err != nil {
+		return fmt.Errorf("Invalid latitude %q", get(CsvLat))
+	}
+	lon, err := strconv.ParseFloat(get(CsvLon), 64)
+	if err != nil {
+		return fmt.Errorf("Invalid longitude %q", get(CsvLon))
+	}
+	wpt := &WptType{LatAttr: lat, LonAttr: lon}
+	if value := get(CsvEle); value != "" {
+		ele, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid elevation %q", value)
+		}
+		wpt.SetElevation(ele * mapping.ElevationUnit)
+	}
+	if value := get(CsvTime); value != "" {
+		if wpt.Time, err = parseCsvTime(value, mapping.TimeFormat, mapping.TimeLocation); err != nil {
+			return err
+		}
+	}
+	for column, field := range csvFields {
+		if value := get(column); value != "" {
+			if err := field.set(wpt, value); err != nil {
+				return fmt.Errorf("Column %s: %v", column, err)
+			}
+		}
+	}
+
+	kind := get(CsvKind)
+	if kind == "" {
+		kind = mapping.Kind
+	}
+	track, segment := get(CsvTrack), get(CsvSegment)
+	newTrack := kind != b.kind || track != b.track
+	newSegment := newTrack || segment != b.segment
+	b.kind, b.track, b.segment = kind, track, segment
+
+	switch kind {
+	case "", "wpt":
+		b.gpx.Wpt = append(b.gpx.Wpt, wpt)
+	case "rtept":
+		if newTrack || b.rte == nil {
+			b.rte = &RteType{}
+			b.gpx.Rte = append(b.gpx.Rte, b.rte)
+		}
+		b.rte.Rtept = append(b.rte.Rtept, wpt)
+	case "trkpt":
+		if newTrack || b.trk == nil {
+			b.trk = &TrkType{}
+			b.gpx.Trk = append(b.gpx.Trk, b.trk)
+		}
+		if newSegment || b.trkseg == nil {
+			b.trkseg = &TrksegType{}
+			b.trk.Trkseg = append(b.trk.Trkseg, b.trkseg)
+		}
+		b.trkseg.Trkpt = append(b.trkseg.Trkpt, wpt)
+	default:
+		return fmt.Errorf("Unknown kind of point %q", kind)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package gpx_tools
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DatumNamespace is the namespace of extension holding the name
+// of datum waypoints were converted from.
+const DatumNamespace = "urn:gpx-tools:datum"
+
+// Prefix declared for DatumNamespace by FromDatum.
+const datumPrefix = "datum"
+
+// Ellipsoid is a reference ellipsoid of a datum.
+type Ellipsoid struct {
+	Name string
+	// Semi-major axis in meters.
+	SemiMajorAxis float64
+	Flattening    float64
+}
+
+// Helmert is a 7-parameter transformation of ECEF coordinates in
+// position vector convention, same as +towgs84 of PROJ.
+type Helmert struct {
+	// Translation in meters.
+	Tx, Ty, Tz float64
+	// Rotation in arc seconds.
+	Rx, Ry, Rz float64
+	// Scale in parts per million.
+	Scale float64
+}
+
+// Datum is a geodetic datum defined by its ellipsoid and
+// transformation to WGS84.
+type Datum struct {
+	Name      string
+	Ellipsoid Ellipsoid
+	ToWGS84   Helmert
+}
+
+var (
+	WGS84Ellipsoid    = Ellipsoid{"WGS84", wgs84SemiMajorAxis, wgs84Flattening}
+	Airy1830          = Ellipsoid{"Airy 1830", 6377563.396, 1 / 299.3249646}
+	International1924 = Ellipsoid{"International 1924", 6378388, 1 / 297.0}
+	Clarke1866        = Ellipsoid{"Clarke 1866", 6378206.4, 1 / 294.9786982}
+	Bessel1841        = Ellipsoid{"Bessel 1841", 6377397.155, 1 / 299.1528128}
+)
+
+var (
+	WGS84 = &Datum{"WGS84", WGS84Ellipsoid, Helmert{}}
+	// OSGB36 of Ordnance Survey of Great Britain.
+	OSGB36 = &Datum{"OSGB36", Airy1830, Helmert{446.448, -125.157, 542.06, 0.15, 0.247, 0.842, -20.489}}
+	// ED50 of western Europe.
+	ED50 = &Datum{"ED50", International1924, Helmert{-87, -98, -121, 0, 0, 0, 0}}
+	// NAD27 of contiguous United States.
+	NAD27 = &Datum{"NAD27", Clarke1866, Helmert{-8, 160, 176, 0, 0, 0, 0}}
+	// SJTSK of Czechia and Slovakia.
+	SJTSK = &Datum{"S-JTSK", Bessel1841, Helmert{570.8, 85.7, 462.8, 4.998, 1.587, 5.261, 3.56}}
+)
+
+// Datums are the named datums by their upper case names.
+var Datums = map[string]*Datum{}
+
+func init() {
+	for _, datum := range []*Datum{WGS84, OSGB36, ED50, NAD27, SJTSK} {
+		Datums[datum.Name] = datum
+	}
+}
+
+// GetDatum returns datum of Datums by name ignoring case.
+func GetDatum(name string) (*Datum, error) {
+	if datum, ok := Datums[strings.ToUpper(strings.TrimSpace(name))]; ok {
+		return datum, nil
+	}
+	names := make([]string, 0, len(Datums))
+	for name := range Datums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("Unknown datum %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// Return square of first eccentricity.
+func (e Ellipsoid) eccentricitySquared() float64 {
+	return e.Flattening * (2 - e.Flattening)
+}
+
+// ToECEF converts geodetic coordinates with height above
+// the ellipsoid to earth-centred, earth-fixed meters.
+func (e Ellipsoid) ToECEF(c Coordinates3D) (x, y, z float64) {
+	phi := c.Coordinates.GetLatitudeRadians()
+	lambda := c.Coordinates.GetLongitudeRadians()
+	e2 := e.eccentricitySquared()
+	// Radius of curvature in the prime vertical.
+	n := e.SemiMajorAxis / math.Sqrt(1-e2*math.Sin(phi)*math.Sin(phi))
+	x = (n + c.Altitude) * math.Cos(phi) * math.Cos(lambda)
+	y = (n + c.Altitude) * math.Cos(phi) * math.Sin(lambda)
+	z = (n*(1-e2) + c.Altitude) * math.Sin(phi)
+	return x, y, z
+}
+
+// FromECEF converts earth-centred, earth-fixed meters to geodetic
+// coordinates with height above the ellipsoid using Bowring's method.
+func (e Ellipsoid) FromECEF(x, y, z float64) Coordinates3D {
+	e2 := e.eccentricitySquared()
+	b := e.SemiMajorAxis * (1 - e.Flattening)
+	// Second eccentricity squared.
+	ep2 := e2 / (1 - e2)
+	p := math.Hypot(x, y)
+	theta := math.Atan2(z*e.SemiMajorAxis, p*b)
+	sin, cos := math.Sincos(theta)
+	phi := math.Atan2(z+ep2*b*sin*sin*sin, p-e2*e.SemiMajorAxis*cos*cos*cos)
+	lambda := math.Atan2(y, x)
+
+	n := e.SemiMajorAxis / math.Sqrt(1-e2*math.Sin(phi)*math.Sin(phi))
+	var height float64
+	if math.Abs(math.Cos(phi)) > 1e-9 {
+		height = p/math.Cos(phi) - n
+	} else {
+		height = math.Abs(z) - b
+	}
+	return NewCoordinates3D(phi*180/math.Pi, lambda*180/math.Pi, height)
+}
+
+// Return matrix of the transformation, rotations are assumed small.
+func (h Helmert) matrix() [3][3]float64 {
+	const arcSecond = math.Pi / (180 * 3600)
+	s := 1 + h.Scale*1e-6
+	rx, ry, rz := h.Rx*arcSecond, h.Ry*arcSecond, h.Rz*arcSecond
+	return [3][3]float64{
+		{s, -s * rz, s * ry},
+		{s * rz, s, -s * rx},
+		{-s * ry, s * rx, s},
+	}
+}
+
+// Apply transforms ECEF coordinates.
+func (h Helmert) Apply(x, y, z float64) (float64, float64, float64) {
+	m := h.matrix()
+	return h.Tx + m[0][0]*x + m[0][1]*y + m[0][2]*z,
+		h.Ty + m[1][0]*x + m[1][1]*y + m[1][2]*z,
+		h.Tz + m[2][0]*x + m[2][1]*y + m[2][2]*z
+}
+
+// ApplyInverse transforms ECEF coordinates back, so that it
+// reverts Apply exactly.
+func (h Helmert) ApplyInverse(x, y, z float64) (float64, float64, float64) {
+	m := h.matrix()
+	x, y, z = x-h.Tx, y-h.Ty, z-h.Tz
+	// Cramer's rule.
+	det := func(a, b, c [3]float64) float64 {
+		return a[0]*(b[1]*c[2]-b[2]*c[1]) - a[1]*(b[0]*c[2]-b[2]*c[0]) + a[2]*(b[0]*c[1]-b[1]*c[0])
+	}
+	column := func(i int) [3]float64 {
+		return [3]float64{m[0][i], m[1][i], m[2][i]}
+	}
+	v := [3]float64{x, y, z}
+	d := det(column(0), column(1), column(2))
+	return det(v, column(1), column(2)) / d,
+		det(column(0), v, column(2)) / d,
+		det(column(0), column(1), v) / d
+}
+
+// ToWGS84 converts coordinates from the datum to WGS84,
+// altitude is height above the ellipsoid.
+func (c Coordinates3D) ToWGS84(from *Datum) Coordinates3D {
+	return c.Transform(from, WGS84)
+}
+
+// FromWGS84 converts WGS84 coordinates to the datum,
+// altitude is height above the ellipsoid.
+func (c Coordinates3D) FromWGS84(to *Datum) Coordinates3D {
+	return c.Transform(WGS84, to)
+}
+
+// Transform converts coordinates between datums through WGS84,
+// altitude is height above the ellipsoid.
+func (c Coordinates3D) Transform(from, to *Datum) Coordinates3D {
+	if from == to {
+		return c
+	}
+	x, y, z := from.Ellipsoid.ToECEF(c)
+	x, y, z = from.ToWGS84.Apply(x, y, z)
+	x, y, z = to.ToWGS84.ApplyInverse(x, y, z)
+	return to.Ellipsoid.FromECEF(x, y, z)
+}
+
+// FromDatum converts waypoints, route and track points from the
+// datum to WGS84 and tags them with the name of the datum, see
+// GetSourceDatum. Points already tagged are skipped, so converting
+// twice is harmless. Elevation is kept, as it is usually height
+// above the geoid rather than the ellipsoid.
+// Returns error and leaves the document unchanged if latitude
+// or longitude of any point is invalid.
+func (gpx *GpxType) FromDatum(datum *Datum) error {
+	if datum == WGS84 {
+		return nil
+	}
+	points := gpx.Wpt
+	for _, rte := range gpx.Rte {
+		points = append(points[:len(points):len(points)], rte.Rtept...)
+	}
+	for _, trk := range gpx.Trk {
+		for _, trkseg := range trk.Trkseg {
+			points = append(points[:len(points):len(points)], trkseg.Trkpt...)
+		}
+	}
+
+	// All points are checked before any of them is changed.
+	var pending []*WptType
+	var coordinates []Coordinates
+	for _, wpt := range points {
+		source, err := wpt.GetSourceDatum()
+		if err != nil {
+			return err
+		}
+		if source != "" {
+			continue
+		}
+		c, err := wpt.ToCoordinatesChecked()
+		if err != nil {
+			return err
+		}
+		pending = append(pending, wpt)
+		coordinates = append(coordinates, c)
+	}
+	for i, wpt := range pending {
+		if err := wpt.fromDatum(gpx, datum, coordinates[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wpt *WptType) fromDatum(gpx *GpxType, datum *Datum, coordinates Coordinates) error {
+	// Height has negligible effect on latitude and longitude.
+	c := Coordinates3D{Coordinates: coordinates}.ToWGS84(datum)
+	wpt.LatAttr, wpt.LonAttr = c.Coordinates.GetLatitude(), c.Coordinates.GetLongitude()
+	if wpt.Extensions == nil {
+		wpt.Extensions = gpx.NewExtensions()
+	}
+	gpx.declareNamespace(datumPrefix, DatumNamespace)
+	return wpt.Extensions.SetElement(NewExtensionElement(DatumNamespace, "source", datum.Name))
+}
+
+// GetSourceDatum returns name of datum the waypoint was converted
+// from by FromDatum, empty when it was not converted.
+func (wpt *WptType) GetSourceDatum() (name string, err error) {
+	if wpt.Extensions == nil {
+		return "", nil
+	}
+	element, err := wpt.Extensions.GetElement(DatumNamespace, "source")
+	if err != nil || element == nil {
+		return "", err
+	}
+	return element.Text, nil
+}
+
+// ParseGpxFileFromDatum parses GPX file with coordinates in the datum
+// and converts them to WGS84, see FromDatum.
+func ParseGpxFileFromDatum(path string, datum *Datum) (gpx Gpx, err error) {
+	gpx, err = ParseGpxFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := (*GpxType)(gpx).FromDatum(datum); err != nil {
+		return nil, err
+	}
+	return gpx, nil
+}
@@ -0,0 +1,426 @@
+package gpx_tools
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Global numbers of FIT messages used by ParseFitBytes.
+const (
+	fitFileIdMessage  = 0
+	fitSessionMessage = 18
+	fitLapMessage     = 19
+	fitRecordMessage  = 20
+)
+
+// Number of timestamp field, which is the same in all messages.
+const fitTimestampField = 253
+
+// FIT timestamps are seconds since this moment.
+var fitEpoch = time.Date(1989, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// Sizes of FIT base types indexed by base type number.
+var fitBaseTypeSizes = [...]int{1, 1, 1, 2, 2, 4, 4, 1, 4, 8, 1, 2, 4, 1, 8, 8, 8}
+
+// Raw values meaning "no value" of FIT base types indexed by base type number.
+var fitInvalidValues = [...]uint64{
+	0xFF, 0x7F, 0xFF, 0x7FFF, 0xFFFF, 0x7FFFFFFF, 0xFFFFFFFF, 0,
+	0xFFFFFFFF, 0xFFFFFFFFFFFFFFFF, 0, 0, 0, 0xFF,
+	0x7FFFFFFFFFFFFFFF, 0xFFFFFFFFFFFFFFFF, 0,
+}
+
+// Names of sports of session message.
+var fitSports = map[uint64]string{
+	0: "generic", 1: "running", 2: "cycling", 3: "transition", 4: "fitness_equipment",
+	5: "swimming", 10: "training", 11: "walking", 12: "cross_country_skiing",
+	13: "alpine_skiing", 14: "snowboarding", 15: "rowing", 16: "mountaineering",
+	17: "hiking", 18: "multisport", 19: "paddling",
+}
+
+// ParseFitFile decodes a .fit file and converts it to Gpx,
+// see ParseFitBytes.
+func ParseFitFile(path string) (gpx Gpx, err error) {
+	fitFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fitFile.Close()
+
+	return ParseFit(fitFile)
+}
+
+// ParseFit reads the whole FIT file from the reader
+// and decodes it like ParseFitBytes.
+func ParseFit(r io.Reader) (gpx Gpx, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFitBytes(data)
+}
+
+// ParseFitBytes decodes FIT activity file and converts it to Gpx.
+//
+// Record messages with position become track points with elevation,
+// heart rate, cadence, temperature, speed, power and distance are kept
+// in extensions, see SensorData. A new segment is started after every
+// lap message and a new track after every session message, sport of
+// the session is kept in type of the track. Time of file creation
+// becomes time of metadata.
+//
+// CRC of header and data is checked, chained FIT files are decoded
+// one after another into the same document.
+func ParseFitBytes(data []byte) (gpx Gpx, err error) {
+	c := &fitConverter{gpx: NewGpx(DefaultCreator)}
+	for len(data) > 0 {
+		decoder := &fitDecoder{handler: c.message}
+		n, err := decoder.decode(data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[n:]
+	}
+	return c.gpx, nil
+}
+
+type fitField struct {
+	number   byte
+	size     int
+	baseType byte
+}
+
+type fitDefinition struct {
+	global    uint16
+	bigEndian bool
+	fields    []fitField
+	// Size of developer fields, which are skipped.
+	developerSize int
+}
+
+type fitValue struct {
+	baseType  byte
+	data      []byte
+	bigEndian bool
+}
+
+type fitMessage struct {
+	global       uint16
+	values       map[byte]fitValue
+	timestamp    uint32
+	hasTimestamp bool
+}
+
+type fitDecoder struct {
+	handler       func(message *fitMessage) error
+	definitions   [16]*fitDefinition
+	lastTimestamp uint32
+}
+
+// Decodes one FIT file from the start of data and
+// returns number of bytes it occupies.
+func (d *fitDecoder) decode(data []byte) (int, error) {
+	if len(data) < 12 {
+		return 0, fmt.Errorf("FIT file is too short")
+	}
+	headerSize := int(data[0])
+	if headerSize < 12 || len(data) < headerSize || string(data[8:12]) != ".FIT" {
+		return 0, fmt.Errorf("Invalid FIT file header")
+	}
+	end := headerSize + int(binary.LittleEndian.Uint32(data[4:8]))
+	if len(data) < end+2 {
+		return 0, fmt.Errorf("FIT file is truncated")
+	}
+	if headerSize >= 14 {
+		if crc := binary.LittleEndian.Uint16(data[12:14]); crc != 0 && crc != fitCrc(data[:12]) {
+			return 0, fmt.Errorf("FIT header CRC mismatch")
+		}
+	}
+	if binary.LittleEndian.Uint16(data[end:end+2]) != fitCrc(data[:end]) {
+		return 0, fmt.Errorf("FIT file CRC mismatch")
+	}
+
+	position := headerSize
+	for position < end {
+		n, err := d.record(data[position:end])
+		if err != nil {
+			return 0, fmt.Errorf("FIT record at byte %d: %v", position, err)
+		}
+		position += n
+	}
+	return end + 2, nil
+}
+
+// Decodes one record and returns its size.
+func (d *fitDecoder) record(data []byte) (int, error) {
+	header := data[0]
+	if header&0x80 != 0 {
+		// Compressed timestamp header holds offset to the last timestamp.
+		offset := uint32(header & 0x1F)
+		timestamp := d.lastTimestamp&^0x1F + offset
+		if offset < d.lastTimestamp&0x1F {
+			timestamp += 0x20
+		}
+		d.lastTimestamp = timestamp
+		n, err := d.dataMessage(data[1:], (header>>5)&0x03, &timestamp)
+		return n + 1, err
+	}
+
+	local := header & 0x0F
+	if header&0x40 != 0 {
+		n, err := d.definitionMessage(data[1:], local, header&0x20 != 0)
+		return n + 1, err
+	}
+	n, err := d.dataMessage(data[1:], local, nil)
+	return n + 1, err
+}
+
+func (d *fitDecoder) definitionMessage(data []byte, local byte, developer bool) (int, error) {
+	if len(data) < 5 {
+		return 0, fmt.Errorf("Definition message is truncated")
+	}
+	definition := &fitDefinition{bigEndian: data[1] == 1}
+	if definition.bigEndian {
+		definition.global = binary.BigEndian.Uint16(data[2:4])
+	} else {
+		definition.global = binary.LittleEndian.Uint16(data[2:4])
+	}
+
+	count := int(data[4])
+	position := 5
+	if len(data) < position+count*3 {
+		return 0, fmt.Errorf("Definition message is truncated")
+	}
+	for i := 0; i < count; i++ {
+		definition.fields = append(definition.fields, fitField{
+			number:   data[position],
+			size:     int(data[position+1]),
+			baseType: data[position+2],
+		})
+		position += 3
+	}
+
+	if developer {
+		if len(data) < position+1 {
+			return 0, fmt.Errorf("Definition message is truncated")
+		}
+		count := int(data[position])
+		position++
+		if len(data) < position+count*3 {
+			return 0, fmt.Errorf("Definition message is truncated")
+		}
+		for i := 0; i < count; i++ {
+			definition.developerSize += int(data[position+1])
+			position += 3
+		}
+	}
+
+	d.definitions[local] = definition
+	return position, nil
+}
+
+func (d *fitDecoder) dataMessage(data []byte, local byte, timestamp *uint32) (int, error) {
+	definition := d.definitions[local]
+	if definition == nil {
+		return 0, fmt.Errorf("Local message type %d is not defined", local)
+	}
+
+	message := &fitMessage{global: definition.global, values: map[byte]fitValue{}}
+	position := 0
+	for _, field := range definition.fields {
+		if len(data) < position+field.size {
+			return 0, fmt.Errorf("Data message is truncated")
+		}
+		message.values[field.number] = fitValue{
+			baseType:  field.baseType,
+			data:      data[position : position+field.size],
+			bigEndian: definition.bigEndian,
+		}
+		position += field.size
+	}
+	if len(data) < position+definition.developerSize {
+		return 0, fmt.Errorf("Data message is truncated")
+	}
+	position += definition.developerSize
+
+	if timestamp != nil {
+		message.timestamp, message.hasTimestamp = *timestamp, true
+	} else if value, ok := message.number(fitTimestampField); ok {
+		message.timestamp, message.hasTimestamp = uint32(value), true
+		d.lastTimestamp = message.timestamp
+	}
+
+	if err := d.handler(message); err != nil {
+		return 0, err
+	}
+	return position, nil
+}
+
+// Return first element of the value as number and whether
+// it is valid, invalid values are the "no value" markers of FIT.
+func (v fitValue) number() (float64, bool) {
+	baseType := int(v.baseType & 0x1F)
+	if baseType >= len(fitBaseTypeSizes) || baseType == 7 {
+		return 0, false
+	}
+	size := fitBaseTypeSizes[baseType]
+	if len(v.data) < size {
+		return 0, false
+	}
+
+	var raw uint64
+	for i := 0; i < size; i++ {
+		if v.bigEndian {
+			raw = raw<<8 | uint64(v.data[i])
+		} else {
+			raw |= uint64(v.data[i]) << (8 * i)
+		}
+	}
+	if raw == fitInvalidValues[baseType] {
+		return 0, false
+	}
+
+	switch baseType {
+	case 1:
+		return float64(int8(raw)), true
+	case 3:
+		return float64(int16(raw)), true
+	case 5:
+		return float64(int32(raw)), true
+	case 14:
+		return float64(int64(raw)), true
+	case 8:
+		return float64(math.Float32frombits(uint32(raw))), true
+	case 9:
+		return math.Float64frombits(raw), true
+	}
+	return float64(raw), true
+}
+
+// Return value of the field and whether it is present and valid.
+func (m *fitMessage) number(field byte) (float64, bool) {
+	value, ok := m.values[field]
+	if !ok {
+		return 0, false
+	}
+	return value.number()
+}
+
+// Return value of the field converted by scale and offset of the profile.
+func (m *fitMessage) scaled(field byte, scale, offset float64) (*float64, bool) {
+	value, ok := m.number(field)
+	if !ok {
+		return nil, false
+	}
+	return Optional(value/scale - offset), true
+}
+
+// Return value of the field converted from semicircles to degrees.
+func (m *fitMessage) degrees(field byte) (float64, bool) {
+	value, ok := m.number(field)
+	return value * 180 / math.Pow(2, 31), ok
+}
+
+type fitConverter struct {
+	gpx    *GpxType
+	trk    *TrkType
+	trkseg *TrksegType
+}
+
+func (c *fitConverter) message(message *fitMessage) error {
+	switch message.global {
+	case fitFileIdMessage:
+		if created, ok := message.number(4); ok {
+			c.gpx.Metadata = &MetadataType{Time: NewGpxTime(fitEpoch.Add(time.Duration(created) * time.Second))}
+		}
+	case fitRecordMessage:
+		return c.record(message)
+	case fitLapMessage:
+		c.trkseg = nil
+	case fitSessionMessage:
+		if c.trk != nil {
+			if sport, ok := message.number(5); ok {
+				c.trk.Type = fitSports[uint64(sport)]
+				if c.trk.Type == "" {
+					c.trk.Type = "sport_" + strconv.Itoa(int(sport))
+				}
+			}
+		}
+		c.trk, c.trkseg = nil, nil
+	}
+	return nil
+}
+
+func (c *fitConverter) record(message *fitMessage) error {
+	lat, latOk := message.degrees(0)
+	lon, lonOk := message.degrees(1)
+	if !latOk || !lonOk {
+		return nil
+	}
+
+	wpt := &WptType{LatAttr: lat, LonAttr: lon}
+	if message.hasTimestamp {
+		wpt.Time = NewGpxTime(fitEpoch.Add(time.Duration(message.timestamp) * time.Second))
+	}
+	if ele, ok := message.scaled(78, 5, 500); ok {
+		wpt.Ele = ele
+	} else if ele, ok := message.scaled(2, 5, 500); ok {
+		wpt.Ele = ele
+	}
+
+	var data SensorData
+	if heartRate, ok := message.number(3); ok {
+		data.HeartRate = Optional(int(heartRate))
+	}
+	if cadence, ok := message.number(4); ok {
+		data.Cadence = Optional(int(cadence))
+	}
+	if temperature, ok := message.number(13); ok {
+		data.Temperature = Optional(temperature)
+	}
+	if speed, ok := message.scaled(73, 1000, 0); ok {
+		data.Speed = speed
+	} else if speed, ok := message.scaled(6, 1000, 0); ok {
+		data.Speed = speed
+	}
+	if power, ok := message.number(7); ok {
+		data.Power = Optional(int(power))
+	}
+	data.Distance, _ = message.scaled(5, 100, 0)
+	if err := c.gpx.SetSensorData(wpt, data); err != nil {
+		return err
+	}
+
+	if c.trk == nil {
+		c.trk = &TrkType{}
+		c.gpx.Trk = append(c.gpx.Trk, c.trk)
+	}
+	if c.trkseg == nil {
+		c.trkseg = &TrksegType{}
+		c.trk.Trkseg = append(c.trk.Trkseg, c.trkseg)
+	}
+	c.trkseg.Trkpt = append(c.trkseg.Trkpt, wpt)
+	return nil
+}
+
+// CRC-16 used by FIT files.
+func fitCrc(data []byte) uint16 {
+	table := [16]uint16{
+		0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+		0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+	}
+	var crc uint16
+	for _, b := range data {
+		tmp := table[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ table[b&0xF]
+		tmp = table[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ table[(b>>4)&0xF]
+	}
+	return crc
+}
@@ -13,6 +13,22 @@ type CoordConvertible interface {
 	getTimestamp() (time.Time, error)
 }
 
+// CheckedCoordConvertible is implemented by points which report
+// invalid latitude or longitude, e.g. WptType and PtType.
+// Functions of this package detect it and use ToCoordinatesChecked
+// instead of ToCoordinates, which wraps invalid latitude over the pole.
+type CheckedCoordConvertible interface {
+	ToCoordinatesChecked() (Coordinates, error)
+}
+
+// Return coordinates of the point, checked if it supports it.
+func checkedCoordinates(point CoordConvertible) (Coordinates, error) {
+	if checked, ok := point.(CheckedCoordConvertible); ok {
+		return checked.ToCoordinatesChecked()
+	}
+	return point.ToCoordinates(), nil
+}
+
 // Perform Vincenty's calculation on two coordinates
 // and return the distance between them in meters.
 // See: http://en.wikipedia.org/wiki/Vincenty%27s_formulae
@@ -0,0 +1,379 @@
+package gpx_tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Names of feature properties written by ToGeoJson.
+// The _gpxType property tells which GPX element a feature
+// comes from, as in other GPX to GeoJSON converters.
+const (
+	geoJsonName                 = "name"
+	geoJsonDesc                 = "desc"
+	geoJsonType                 = "type"
+	geoJsonTime                 = "time"
+	geoJsonGpxType              = "_gpxType"
+	geoJsonCoordinateProperties = "coordinateProperties"
+	geoJsonTimes                = "times"
+)
+
+// ParseGeoJsonFile parses a GeoJSON file and converts it to Gpx,
+// see ParseGeoJsonBytes.
+func ParseGeoJsonFile(path string) (gpx Gpx, err error) {
+	jsonFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer jsonFile.Close()
+
+	return ParseGeoJson(jsonFile)
+}
+
+// ParseGeoJson reads the whole GeoJSON document from the reader
+// and parses it like ParseGeoJsonBytes.
+func ParseGeoJson(r io.Reader) (gpx Gpx, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGeoJsonBytes(data)
+}
+
+// ParseGeoJsonBytes parses GeoJSON FeatureCollection, Feature
+// or bare geometry and converts it to Gpx, see GeoJsonFeatureCollection.ToGpx.
+func ParseGeoJsonBytes(data []byte) (gpx Gpx, err error) {
+	var object struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+
+	collection := &GeoJsonFeatureCollection{Type: "FeatureCollection"}
+	switch object.Type {
+	case "FeatureCollection":
+		err = json.Unmarshal(data, collection)
+	case "Feature":
+		feature := &GeoJsonFeature{}
+		err = json.Unmarshal(data, feature)
+		collection.Features = append(collection.Features, feature)
+	case "":
+		return nil, fmt.Errorf("GeoJSON object has no type")
+	default:
+		geometry := &GeoJsonGeometry{}
+		err = json.Unmarshal(data, geometry)
+		collection.Features = append(collection.Features, &GeoJsonFeature{Type: "Feature", Geometry: geometry})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return collection.ToGpx()
+}
+
+// ToGpx converts GeoJSON features to GPX 1.1.
+//
+// Points become waypoints, LineStrings become routes if their
+// _gpxType property is "rte" and tracks otherwise, every line of
+// MultiLineString becomes a track segment. Name, desc, type and time
+// properties and times in coordinateProperties are kept.
+// Polygons are skipped.
+func (collection *GeoJsonFeatureCollection) ToGpx() (gpx Gpx, err error) {
+	result := NewGpx(DefaultCreator)
+	for i, feature := range collection.Features {
+		if feature == nil || feature.Geometry == nil {
+			continue
+		}
+		if err := geoJsonFeatureToGpx(result, feature, feature.Geometry); err != nil {
+			return nil, fmt.Errorf("Feature %d: %v", i, err)
+		}
+	}
+	return result, nil
+}
+
+func geoJsonFeatureToGpx(gpx *GpxType, feature *GeoJsonFeature, geometry *GeoJsonGeometry) error {
+	name := geoJsonString(feature.Properties[geoJsonName])
+	desc := geoJsonString(feature.Properties[geoJsonDesc])
+	kind := geoJsonString(feature.Properties[geoJsonType])
+	times := geoJsonPropertyTimes(feature.Properties)
+
+	switch geometry.Type {
+	case "Point":
+		var position []float64
+		if err := json.Unmarshal(geometry.Coordinates, &position); err != nil {
+			return err
+		}
+		wpt, err := geoJsonWpt(position, feature.Properties[geoJsonTime])
+		if err != nil {
+			return err
+		}
+		wpt.Name, wpt.Desc, wpt.Type = name, desc, kind
+		gpx.Wpt = append(gpx.Wpt, wpt)
+	case "MultiPoint":
+		var positions [][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &positions); err != nil {
+			return err
+		}
+		points, err := geoJsonWpts(positions, geoJsonTimesOf(times, -1))
+		if err != nil {
+			return err
+		}
+		for _, wpt := range points {
+			wpt.Name, wpt.Desc, wpt.Type = name, desc, kind
+		}
+		gpx.Wpt = append(gpx.Wpt, points...)
+	case "LineString":
+		var positions [][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &positions); err != nil {
+			return err
+		}
+		points, err := geoJsonWpts(positions, geoJsonTimesOf(times, -1))
+		if err != nil {
+			return err
+		}
+		if geoJsonString(feature.Properties[geoJsonGpxType]) == "rte" {
+			gpx.Rte = append(gpx.Rte, &RteType{Name: name, Desc: desc, Type: kind, Rtept: points})
+		} else {
+			trk := &TrkType{Name: name, Desc: desc, Type: kind}
+			trk.Trkseg = append(trk.Trkseg, &TrksegType{Trkpt: points})
+			gpx.Trk = append(gpx.Trk, trk)
+		}
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &lines); err != nil {
+			return err
+		}
+		trk := &TrkType{Name: name, Desc: desc, Type: kind}
+		for i, positions := range lines {
+			points, err := geoJsonWpts(positions, geoJsonTimesOf(times, i))
+			if err != nil {
+				return err
+			}
+			trk.Trkseg = append(trk.Trkseg, &TrksegType{Trkpt: points})
+		}
+		gpx.Trk = append(gpx.Trk, trk)
+	case "GeometryCollection":
+		for _, nested := range geometry.Geometries {
+			if nested == nil {
+				continue
+			}
+			plain := &GeoJsonFeature{Properties: map[string]any{geoJsonName: name, geoJsonDesc: desc, geoJsonType: kind}}
+			if err := geoJsonFeatureToGpx(gpx, plain, nested); err != nil {
+				return err
+			}
+		}
+	case "Polygon", "MultiPolygon":
+	default:
+		return fmt.Errorf("Unknown geometry type %q", geometry.Type)
+	}
+	return nil
+}
+
+func geoJsonWpts(positions [][]float64, times []any) ([]*WptType, error) {
+	points := make([]*WptType, 0, len(positions))
+	for i, position := range positions {
+		var timestamp any
+		if i < len(times) {
+			timestamp = times[i]
+		}
+		wpt, err := geoJsonWpt(position, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, wpt)
+	}
+	return points, nil
+}
+
+func geoJsonWpt(position []float64, timestamp any) (*WptType, error) {
+	if len(position) < 2 {
+		return nil, fmt.Errorf("Position %v has less than 2 values", position)
+	}
+	coordinates, err := NewCoordinatesStrict(position[1], position[0])
+	if err != nil {
+		return nil, fmt.Errorf("Position %v: %v", position, err)
+	}
+	wpt := &WptType{LatAttr: coordinates.Latitude, LonAttr: coordinates.GetLongitude()}
+	if len(position) > 2 {
+		wpt.SetElevation(position[2])
+	}
+	if timeStr := geoJsonString(timestamp); timeStr != "" {
+		wpt.Time = NewGpxTimeStr(timeStr)
+	}
+	return wpt, nil
+}
+
+// Return times array of coordinateProperties.
+func geoJsonPropertyTimes(properties map[string]any) []any {
+	coordinateProperties, _ := properties[geoJsonCoordinateProperties].(map[string]any)
+	times, _ := coordinateProperties[geoJsonTimes].([]any)
+	return times
+}
+
+// Return times of line with given index of MultiLineString,
+// or times itself for index -1.
+func geoJsonTimesOf(times []any, index int) []any {
+	if index < 0 {
+		return times
+	}
+	if index >= len(times) {
+		return nil
+	}
+	line, _ := times[index].([]any)
+	return line
+}
+
+func geoJsonString(value any) string {
+	s, _ := value.(string)
+	return s
+}
+
+// ToGeoJson converts Gpx to GeoJSON FeatureCollection.
+//
+// Waypoints are converted to Points, routes to LineStrings and tracks
+// to MultiLineStrings with a line for every segment. Name, desc, type
+// and time are stored in properties, times of route and track points
+// in coordinateProperties.times (an array per segment for tracks).
+// Elevation is the third value of positions. Returns error if
+// coordinates can not be written as JSON, e.g. when they are NaN.
+func ToGeoJson(gpx Gpx) (*GeoJsonFeatureCollection, error) {
+	collection := &GeoJsonFeatureCollection{Type: "FeatureCollection", Features: []*GeoJsonFeature{}}
+
+	for _, wpt := range gpx.Wpt {
+		properties := geoJsonProperties("wpt", wpt.Name, wpt.Desc, wpt.Type)
+		if wpt.Time != nil {
+			properties[geoJsonTime] = wpt.Time.String()
+		}
+		coordinates, err := json.Marshal(geoJsonPositions([]*WptType{wpt})[0])
+		if err != nil {
+			return nil, err
+		}
+		collection.Features = append(collection.Features, &GeoJsonFeature{
+			Type:       "Feature",
+			Geometry:   &GeoJsonGeometry{Type: "Point", Coordinates: coordinates},
+			Properties: properties,
+		})
+	}
+
+	for _, rte := range gpx.Rte {
+		properties := geoJsonProperties("rte", rte.Name, rte.Desc, rte.Type)
+		if times, ok := geoJsonPointTimes(rte.Rtept); ok {
+			properties[geoJsonCoordinateProperties] = map[string]any{geoJsonTimes: times}
+		}
+		coordinates, err := json.Marshal(geoJsonPositions(rte.Rtept))
+		if err != nil {
+			return nil, err
+		}
+		collection.Features = append(collection.Features, &GeoJsonFeature{
+			Type:       "Feature",
+			Geometry:   &GeoJsonGeometry{Type: "LineString", Coordinates: coordinates},
+			Properties: properties,
+		})
+	}
+
+	for _, trk := range gpx.Trk {
+		properties := geoJsonProperties("trk", trk.Name, trk.Desc, trk.Type)
+		lines := [][][]float64{}
+		times := [][]any{}
+		timed := false
+		for _, trkseg := range trk.Trkseg {
+			lines = append(lines, geoJsonPositions(trkseg.Trkpt))
+			segmentTimes, ok := geoJsonPointTimes(trkseg.Trkpt)
+			times = append(times, segmentTimes)
+			timed = timed || ok
+		}
+		if timed {
+			properties[geoJsonCoordinateProperties] = map[string]any{geoJsonTimes: times}
+		}
+		coordinates, err := json.Marshal(lines)
+		if err != nil {
+			return nil, err
+		}
+		collection.Features = append(collection.Features, &GeoJsonFeature{
+			Type:       "Feature",
+			Geometry:   &GeoJsonGeometry{Type: "MultiLineString", Coordinates: coordinates},
+			Properties: properties,
+		})
+	}
+
+	return collection, nil
+}
+
+func geoJsonProperties(gpxType, name, desc, kind string) map[string]any {
+	properties := map[string]any{geoJsonGpxType: gpxType}
+	if name != "" {
+		properties[geoJsonName] = name
+	}
+	if desc != "" {
+		properties[geoJsonDesc] = desc
+	}
+	if kind != "" {
+		properties[geoJsonType] = kind
+	}
+	return properties
+}
+
+// Return positions of points, elevation is written
+// for all points if any of them has elevation.
+func geoJsonPositions(points []*WptType) [][]float64 {
+	altitude := false
+	for _, wpt := range points {
+		altitude = altitude || wpt.HasElevation()
+	}
+	positions := make([][]float64, 0, len(points))
+	for _, wpt := range points {
+		// Coordinates are written as they are, Validate reports invalid ones.
+		position := []float64{wpt.LonAttr, wpt.LatAttr}
+		if altitude {
+			position = append(position, optionalValue(wpt.Ele))
+		}
+		positions = append(positions, position)
+	}
+	return positions
+}
+
+// Return times of points, null for points without time,
+// and whether any of the points has time.
+func geoJsonPointTimes(points []*WptType) ([]any, bool) {
+	times := make([]any, 0, len(points))
+	timed := false
+	for _, wpt := range points {
+		if wpt.Time == nil {
+			times = append(times, nil)
+			continue
+		}
+		times = append(times, wpt.Time.String())
+		timed = true
+	}
+	return times, timed
+}
+
+// WriteGeoJsonFile converts the Gpx struct to GeoJSON and writes it
+// to a file or returns an error if the file could not be written.
+func WriteGeoJsonFile(gpx Gpx, path string) (err error) {
+	jsonFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := jsonFile.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	return WriteGeoJson(jsonFile, gpx, "")
+}
+
+// WriteGeoJson converts the Gpx struct to GeoJSON FeatureCollection,
+// see ToGeoJson, and writes it to w indented by indent.
+func WriteGeoJson(w io.Writer, gpx Gpx, indent string) error {
+	collection, err := ToGeoJson(gpx)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", indent)
+	return encoder.Encode(collection)
+}
@@ -0,0 +1,28 @@
+package gpx_tools
+
+import (
+	"encoding/json"
+)
+
+// GeoJsonFeatureCollection is the root object of GeoJSON document
+// written by ToGeoJson.
+type GeoJsonFeatureCollection struct {
+	Type     string            `json:"type"`
+	Features []*GeoJsonFeature `json:"features"`
+}
+
+// GeoJsonFeature is a geometry with properties.
+type GeoJsonFeature struct {
+	Type       string           `json:"type"`
+	Geometry   *GeoJsonGeometry `json:"geometry"`
+	Properties map[string]any   `json:"properties"`
+}
+
+// GeoJsonGeometry is a geometry of any type. Coordinates are kept
+// as raw JSON as their nesting depends on the type,
+// GeometryCollection has Geometries instead of Coordinates.
+type GeoJsonGeometry struct {
+	Type        string             `json:"type"`
+	Coordinates json.RawMessage    `json:"coordinates,omitempty"`
+	Geometries  []*GeoJsonGeometry `json:"geometries,omitempty"`
+}
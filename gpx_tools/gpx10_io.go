@@ -0,0 +1,384 @@
+package gpx_tools
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Prefix used for elements of GPX 1.0, which have no
+// counterpart in GPX 1.1 and are kept in extensions.
+const gpx10Prefix = "gpx10"
+
+// DetectGpxVersion reads the root element of the document and
+// returns the GPX version it is written in ("1.0" or "1.1").
+// The namespace of the root element is preferred, version
+// attribute is used when the namespace is missing.
+func DetectGpxVersion(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", fmt.Errorf("No gpx element found")
+		}
+		if err != nil {
+			return "", err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "gpx" {
+			return "", fmt.Errorf("Root element is <%s>, not <gpx>", start.Name.Local)
+		}
+		switch start.Name.Space {
+		case Gpx10Namespace:
+			return "1.0", nil
+		case Gpx11Namespace:
+			return "1.1", nil
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Local == "version" && attr.Name.Space == "" {
+				return attr.Value, nil
+			}
+		}
+		return "1.1", nil
+	}
+}
+
+// ParseGpx10Bytes parses a GPX 1.0 document into its own model.
+// Use ParseGpxBytes to get the document upgraded to GPX 1.1.
+func ParseGpx10Bytes(bytes []byte) (gpx *Gpx10Type, err error) {
+	err = xml.Unmarshal(bytes, &gpx)
+	return gpx, err
+}
+
+// ToGpx converts GPX 1.0 document to GPX 1.1.
+//
+// Conversion is lossless, elements of GPX 1.0 without counterpart
+// in GPX 1.1 (course and speed of points, url without link,
+// email which cannot be split into id and domain) are stored in
+// extensions in the GPX 1.0 namespace and elements of other schemas
+// are moved to extensions.
+func (gpx10 *Gpx10Type) ToGpx() Gpx {
+	gpx := &GpxType{
+		XMLName:        xml.Name{Local: "gpx"},
+		VersionAttr:    "1.1",
+		CreatorAttr:    gpx10.CreatorAttr,
+		Namespaces:     map[string]string{gpx10Prefix: Gpx10Namespace},
+		SchemaLocation: gpx10.SchemaLocation,
+	}
+	c := gpx10Converter{gpx: gpx}
+
+	if gpx10.Name != "" || gpx10.Desc != "" || gpx10.Author != "" || gpx10.Email != "" ||
+		gpx10.Url != "" || gpx10.Urlname != "" || gpx10.Time != nil || gpx10.Keywords != "" || gpx10.Bounds != nil {
+		metadata := &MetadataType{
+			Name:     gpx10.Name,
+			Desc:     gpx10.Desc,
+			Time:     gpx10.Time,
+			Keywords: gpx10.Keywords,
+			Bounds:   gpx10.Bounds,
+		}
+		var extra []*ExtensionElement
+		if gpx10.Author != "" || gpx10.Email != "" {
+			metadata.Author = &PersonType{Name: gpx10.Author}
+			if id, domain, found := strings.Cut(gpx10.Email, "@"); found && !strings.Contains(domain, "@") {
+				metadata.Author.Email = &EmailType{IdAttr: id, DomainAttr: domain}
+			} else if gpx10.Email != "" {
+				extra = append(extra, NewExtensionElement(Gpx10Namespace, "email", gpx10.Email))
+			}
+		}
+		var urlExtra *ExtensionElement
+		metadata.Link, urlExtra = linkFromGpx10(gpx10.Url, gpx10.Urlname)
+		if urlExtra != nil {
+			extra = append(extra, urlExtra)
+		}
+		metadata.Extensions = c.extensions(extra)
+		gpx.Metadata = metadata
+	}
+	gpx.Extensions = c.extensions(gpx10.Any)
+
+	for _, wpt := range gpx10.Wpt {
+		gpx.Wpt = append(gpx.Wpt, c.wpt(wpt))
+	}
+	for _, rte10 := range gpx10.Rte {
+		rte := &RteType{
+			Name:   rte10.Name,
+			Cmt:    rte10.Cmt,
+			Desc:   rte10.Desc,
+			Src:    rte10.Src,
+			Number: rte10.Number,
+		}
+		var urlExtra *ExtensionElement
+		rte.Link, urlExtra = linkFromGpx10(rte10.Url, rte10.Urlname)
+		rte.Extensions = c.extensions(appendElement(rte10.Any, urlExtra))
+		for _, rtept := range rte10.Rtept {
+			rte.Rtept = append(rte.Rtept, c.wpt(rtept))
+		}
+		gpx.Rte = append(gpx.Rte, rte)
+	}
+	for _, trk10 := range gpx10.Trk {
+		trk := &TrkType{
+			Name:   trk10.Name,
+			Cmt:    trk10.Cmt,
+			Desc:   trk10.Desc,
+			Src:    trk10.Src,
+			Number: trk10.Number,
+		}
+		var urlExtra *ExtensionElement
+		trk.Link, urlExtra = linkFromGpx10(trk10.Url, trk10.Urlname)
+		trk.Extensions = c.extensions(appendElement(trk10.Any, urlExtra))
+		for _, trkseg10 := range trk10.Trkseg {
+			trkseg := &TrksegType{}
+			for _, trkpt := range trkseg10.Trkpt {
+				trkseg.Trkpt = append(trkseg.Trkpt, c.wpt(trkpt))
+			}
+			trk.Trkseg = append(trk.Trkseg, trkseg)
+		}
+		gpx.Trk = append(gpx.Trk, trk)
+	}
+
+	if !c.usesGpx10 {
+		delete(gpx.Namespaces, gpx10Prefix)
+	}
+	return gpx
+}
+
+type gpx10Converter struct {
+	gpx       *GpxType
+	usesGpx10 bool
+}
+
+// Return extensions with the elements or nil if there are none.
+func (c *gpx10Converter) extensions(elements []*ExtensionElement) *ExtensionsType {
+	if len(elements) == 0 {
+		return nil
+	}
+	ext := c.gpx.NewExtensions()
+	for _, element := range elements {
+		if element.XMLName.Space == Gpx10Namespace {
+			c.usesGpx10 = true
+		}
+		ext.AddElement(element)
+	}
+	return ext
+}
+
+func (c *gpx10Converter) wpt(wpt10 *Wpt10Type) *WptType {
+	wpt := &WptType{
+		LatAttr:       wpt10.LatAttr,
+		LonAttr:       wpt10.LonAttr,
+		Ele:           wpt10.Ele,
+		Time:          wpt10.Time,
+		Magvar:        wpt10.Magvar,
+		Geoidheight:   wpt10.Geoidheight,
+		Name:          wpt10.Name,
+		Cmt:           wpt10.Cmt,
+		Desc:          wpt10.Desc,
+		Src:           wpt10.Src,
+		Sym:           wpt10.Sym,
+		Type:          wpt10.Type,
+		Fix:           wpt10.Fix,
+		Sat:           wpt10.Sat,
+		Hdop:          wpt10.Hdop,
+		Vdop:          wpt10.Vdop,
+		Pdop:          wpt10.Pdop,
+		Ageofdgpsdata: wpt10.Ageofdgpsdata,
+		Dgpsid:        wpt10.Dgpsid,
+	}
+	var extra []*ExtensionElement
+	if wpt10.Course != nil {
+		extra = append(extra, NewExtensionElement(Gpx10Namespace, "course", formatFloat(*wpt10.Course)))
+	}
+	if wpt10.Speed != nil {
+		extra = append(extra, NewExtensionElement(Gpx10Namespace, "speed", formatFloat(*wpt10.Speed)))
+	}
+	var urlExtra *ExtensionElement
+	wpt.Link, urlExtra = linkFromGpx10(wpt10.Url, wpt10.Urlname)
+	extra = appendElement(extra, urlExtra)
+	wpt.Extensions = c.extensions(append(extra, wpt10.Any...))
+	return wpt
+}
+
+// GPX 1.1 link requires href, urlname without url
+// is returned as extension element.
+func linkFromGpx10(url, urlname string) ([]*LinkType, *ExtensionElement) {
+	if url == "" {
+		if urlname != "" {
+			return nil, NewExtensionElement(Gpx10Namespace, "urlname", urlname)
+		}
+		return nil, nil
+	}
+	return []*LinkType{{HrefAttr: url, Text: urlname}}, nil
+}
+
+func appendElement(elements []*ExtensionElement, element *ExtensionElement) []*ExtensionElement {
+	if element == nil {
+		return elements
+	}
+	return append(elements, element)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// ToGpx10 converts GPX 1.1 document to GPX 1.0 for legacy consumers.
+//
+// Elements stored in extensions by ToGpx are restored and other
+// extension elements are written as elements of other schemas.
+// Copyright, all links but the first one and link types are lost,
+// as GPX 1.0 cannot represent them.
+func ToGpx10(gpx Gpx) *Gpx10Type {
+	gpx10 := &Gpx10Type{
+		XMLName:     xml.Name{Local: "gpx"},
+		VersionAttr: "1.0",
+		CreatorAttr: gpx.CreatorAttr,
+		Any:         gpx10Extensions(gpx.Extensions, nil),
+	}
+
+	if metadata := gpx.Metadata; metadata != nil {
+		gpx10.Name = metadata.Name
+		gpx10.Desc = metadata.Desc
+		gpx10.Time = metadata.Time
+		gpx10.Keywords = metadata.Keywords
+		gpx10.Bounds = metadata.Bounds
+		links := metadata.Link
+		if author := metadata.Author; author != nil {
+			gpx10.Author = author.Name
+			if author.Email != nil {
+				gpx10.Email = author.Email.GetEmailStr()
+			}
+			if author.Link != nil {
+				links = append(links, author.Link)
+			}
+		}
+		gpx10.Url, gpx10.Urlname = linkToGpx10(links)
+		gpx10.Any = append(gpx10Extensions(metadata.Extensions, func(name, value string) {
+			switch name {
+			case "email":
+				gpx10.Email = value
+			case "urlname":
+				gpx10.Urlname = value
+			}
+		}), gpx10.Any...)
+	}
+
+	for _, wpt := range gpx.Wpt {
+		gpx10.Wpt = append(gpx10.Wpt, wptToGpx10(wpt))
+	}
+	for _, rte := range gpx.Rte {
+		rte10 := &Rte10Type{
+			Name:   rte.Name,
+			Cmt:    rte.Cmt,
+			Desc:   rte.Desc,
+			Src:    rte.Src,
+			Number: rte.Number,
+		}
+		rte10.Url, rte10.Urlname = linkToGpx10(rte.Link)
+		rte10.Any = gpx10Extensions(rte.Extensions, func(name, value string) {
+			if name == "urlname" {
+				rte10.Urlname = value
+			}
+		})
+		for _, rtept := range rte.Rtept {
+			rte10.Rtept = append(rte10.Rtept, wptToGpx10(rtept))
+		}
+		gpx10.Rte = append(gpx10.Rte, rte10)
+	}
+	for _, trk := range gpx.Trk {
+		trk10 := &Trk10Type{
+			Name:   trk.Name,
+			Cmt:    trk.Cmt,
+			Desc:   trk.Desc,
+			Src:    trk.Src,
+			Number: trk.Number,
+		}
+		trk10.Url, trk10.Urlname = linkToGpx10(trk.Link)
+		trk10.Any = gpx10Extensions(trk.Extensions, func(name, value string) {
+			if name == "urlname" {
+				trk10.Urlname = value
+			}
+		})
+		for _, trkseg := range trk.Trkseg {
+			trkseg10 := &Trkseg10Type{}
+			for _, trkpt := range trkseg.Trkpt {
+				trkseg10.Trkpt = append(trkseg10.Trkpt, wptToGpx10(trkpt))
+			}
+			trk10.Trkseg = append(trk10.Trkseg, trkseg10)
+		}
+		gpx10.Trk = append(gpx10.Trk, trk10)
+	}
+	return gpx10
+}
+
+func wptToGpx10(wpt *WptType) *Wpt10Type {
+	wpt10 := &Wpt10Type{
+		LatAttr:       wpt.LatAttr,
+		LonAttr:       wpt.LonAttr,
+		Ele:           wpt.Ele,
+		Time:          wpt.Time,
+		Magvar:        wpt.Magvar,
+		Geoidheight:   wpt.Geoidheight,
+		Name:          wpt.Name,
+		Cmt:           wpt.Cmt,
+		Desc:          wpt.Desc,
+		Src:           wpt.Src,
+		Sym:           wpt.Sym,
+		Type:          wpt.Type,
+		Fix:           wpt.Fix,
+		Sat:           wpt.Sat,
+		Hdop:          wpt.Hdop,
+		Vdop:          wpt.Vdop,
+		Pdop:          wpt.Pdop,
+		Ageofdgpsdata: wpt.Ageofdgpsdata,
+		Dgpsid:        wpt.Dgpsid,
+	}
+	wpt10.Url, wpt10.Urlname = linkToGpx10(wpt.Link)
+	wpt10.Any = gpx10Extensions(wpt.Extensions, func(name, value string) {
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		switch {
+		case name == "urlname":
+			wpt10.Urlname = value
+		case name == "course" && err == nil:
+			wpt10.Course = &number
+		case name == "speed" && err == nil:
+			wpt10.Speed = &number
+		}
+	})
+	return wpt10
+}
+
+func linkToGpx10(links []*LinkType) (url, urlname string) {
+	if len(links) == 0 {
+		return "", ""
+	}
+	return links[0].HrefAttr, links[0].Text
+}
+
+// Passes elements in the GPX 1.0 namespace to restore
+// and returns all other elements.
+// Extensions which cannot be parsed are dropped.
+func gpx10Extensions(ext *ExtensionsType, restore func(name, value string)) []*ExtensionElement {
+	if ext == nil {
+		return nil
+	}
+	elements, err := ext.Elements()
+	if err != nil {
+		return nil
+	}
+	var other []*ExtensionElement
+	for _, element := range elements {
+		if element.XMLName.Space == Gpx10Namespace {
+			if restore != nil {
+				restore(element.XMLName.Local, element.Text)
+			}
+			continue
+		}
+		other = append(other, element)
+	}
+	return other
+}
@@ -0,0 +1,126 @@
+package gpx_tools
+
+import (
+	"encoding/xml"
+)
+
+// Gpx10Namespace is the XML namespace of GPX version 1.0.
+const Gpx10Namespace = "http://www.topografix.com/GPX/1/0"
+
+// Gpx10Type is the root element of GPX 1.0 document.
+// GPX 1.0 has no metadata element, document information is stored
+// directly in the root and elements of other schemas may be
+// placed at the end of most elements.
+type Gpx10Type struct {
+	XMLName     xml.Name            `xml:"gpx"`
+	VersionAttr string              `xml:"version,attr"`
+	CreatorAttr string              `xml:"creator,attr"`
+	Name        string              `xml:"name,omitempty"`
+	Desc        string              `xml:"desc,omitempty"`
+	Author      string              `xml:"author,omitempty"`
+	Email       string              `xml:"email,omitempty"`
+	Url         string              `xml:"url,omitempty"`
+	Urlname     string              `xml:"urlname,omitempty"`
+	Time        *GpxTime            `xml:"time,omitempty"`
+	Keywords    string              `xml:"keywords,omitempty"`
+	Bounds      *BoundsType         `xml:"bounds"`
+	Wpt         []*Wpt10Type        `xml:"wpt"`
+	Rte         []*Rte10Type        `xml:"rte"`
+	Trk         []*Trk10Type        `xml:"trk"`
+	Any         []*ExtensionElement `xml:",any"`
+
+	// SchemaLocation is the xsi:schemaLocation attribute of the gpx element.
+	SchemaLocation string `xml:"-"`
+}
+
+// UnmarshalXML decodes the gpx element and remembers its schema location.
+func (gpx *Gpx10Type) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type gpx10Alias Gpx10Type
+	if err := d.DecodeElement((*gpx10Alias)(gpx), &start); err != nil {
+		return err
+	}
+	gpx.SchemaLocation = schemaLocation(start.Attr)
+	return nil
+}
+
+// Wpt10Type is a GPX 1.0 waypoint, route point or track point.
+// Course and Speed are defined for track points only.
+type Wpt10Type struct {
+	XMLName       xml.Name            `xml:"-"`
+	LatAttr       float64             `xml:"lat,attr"`
+	LonAttr       float64             `xml:"lon,attr"`
+	Ele           *float64            `xml:"ele,omitempty"`
+	Time          *GpxTime            `xml:"time,omitempty"`
+	Course        *float64            `xml:"course,omitempty"`
+	Speed         *float64            `xml:"speed,omitempty"`
+	Magvar        *float64            `xml:"magvar,omitempty"`
+	Geoidheight   *float64            `xml:"geoidheight,omitempty"`
+	Name          string              `xml:"name,omitempty"`
+	Cmt           string              `xml:"cmt,omitempty"`
+	Desc          string              `xml:"desc,omitempty"`
+	Src           string              `xml:"src,omitempty"`
+	Url           string              `xml:"url,omitempty"`
+	Urlname       string              `xml:"urlname,omitempty"`
+	Sym           string              `xml:"sym,omitempty"`
+	Type          string              `xml:"type,omitempty"`
+	Fix           string              `xml:"fix,omitempty"`
+	Sat           *int                `xml:"sat,omitempty"`
+	Hdop          *float64            `xml:"hdop,omitempty"`
+	Vdop          *float64            `xml:"vdop,omitempty"`
+	Pdop          *float64            `xml:"pdop,omitempty"`
+	Ageofdgpsdata *float64            `xml:"ageofdgpsdata,omitempty"`
+	Dgpsid        *int                `xml:"dgpsid,omitempty"`
+	Any           []*ExtensionElement `xml:",any"`
+}
+
+// Rte10Type is a GPX 1.0 route.
+type Rte10Type struct {
+	XMLName xml.Name            `xml:"rte"`
+	Name    string              `xml:"name,omitempty"`
+	Cmt     string              `xml:"cmt,omitempty"`
+	Desc    string              `xml:"desc,omitempty"`
+	Src     string              `xml:"src,omitempty"`
+	Url     string              `xml:"url,omitempty"`
+	Urlname string              `xml:"urlname,omitempty"`
+	Number  *int                `xml:"number,omitempty"`
+	Any     []*ExtensionElement `xml:",any"`
+	Rtept   []*Wpt10Type        `xml:"rtept"`
+}
+
+// Trk10Type is a GPX 1.0 track.
+type Trk10Type struct {
+	XMLName xml.Name            `xml:"trk"`
+	Name    string              `xml:"name,omitempty"`
+	Cmt     string              `xml:"cmt,omitempty"`
+	Desc    string              `xml:"desc,omitempty"`
+	Src     string              `xml:"src,omitempty"`
+	Url     string              `xml:"url,omitempty"`
+	Urlname string              `xml:"urlname,omitempty"`
+	Number  *int                `xml:"number,omitempty"`
+	Any     []*ExtensionElement `xml:",any"`
+	Trkseg  []*Trkseg10Type     `xml:"trkseg"`
+}
+
+// Trkseg10Type is a GPX 1.0 track segment.
+type Trkseg10Type struct {
+	XMLName xml.Name     `xml:"trkseg"`
+	Trkpt   []*Wpt10Type `xml:"trkpt"`
+}
+
+// MarshalXML encodes the gpx element in the GPX 1.0 namespace
+// with schema location. Version is always written as 1.0 and
+// missing creator is replaced by DefaultCreator.
+func (gpx *Gpx10Type) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type gpx10Alias Gpx10Type
+	alias := gpx10Alias(*gpx)
+	alias.VersionAttr = "1.0"
+	if alias.CreatorAttr == "" {
+		alias.CreatorAttr = DefaultCreator
+	}
+
+	start = xml.StartElement{
+		Name: xml.Name{Local: "gpx"},
+		Attr: rootNamespaceAttrs(Gpx10Namespace, Gpx10SchemaLocation, ""),
+	}
+	return e.EncodeElement(&alias, start)
+}
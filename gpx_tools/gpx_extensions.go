@@ -0,0 +1,520 @@
+package gpx_tools
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ExtensionElement is a single element inside of extensions.
+// XMLName.Space holds the namespace URI of the element, not its prefix.
+//
+// Mixed content is not supported, all text of the element is
+// joined into Text.
+type ExtensionElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr          `xml:",any,attr"`
+	Text     string              `xml:",chardata"`
+	Children []*ExtensionElement `xml:",any"`
+}
+
+// Create new ExtensionElement with given namespace URI, name and text.
+func NewExtensionElement(space, local, text string) *ExtensionElement {
+	return &ExtensionElement{XMLName: xml.Name{Space: space, Local: local}, Text: text}
+}
+
+// Is reports whether the element has given namespace URI and name.
+// Empty space matches any namespace.
+func (e *ExtensionElement) Is(space, local string) bool {
+	return e.XMLName.Local == local && (space == "" || e.XMLName.Space == space)
+}
+
+// Return first child element with given namespace URI and name or nil.
+// Empty space matches any namespace.
+func (e *ExtensionElement) GetChild(space, local string) *ExtensionElement {
+	for _, child := range e.Children {
+		if child.Is(space, local) {
+			return child
+		}
+	}
+	return nil
+}
+
+// Append child element and return it.
+func (e *ExtensionElement) AddChild(child *ExtensionElement) *ExtensionElement {
+	e.Children = append(e.Children, child)
+	return child
+}
+
+// Return value of attribute with given namespace URI and name
+// and whether the attribute is present.
+func (e *ExtensionElement) GetAttr(space, local string) (string, bool) {
+	for _, attr := range e.Attrs {
+		if attr.Name.Local == local && (space == "" || attr.Name.Space == space) {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// MarshalXML encodes the element on its own, the namespace of the
+// element is declared as default namespace. Namespace declarations
+// kept in Attrs from parsing are dropped, they are not needed.
+func (e *ExtensionElement) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: e.XMLName}
+	for _, attr := range e.Attrs {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		start.Attr = append(start.Attr, attr)
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if e.Text != "" {
+		if err := enc.EncodeToken(xml.CharData(e.Text)); err != nil {
+			return err
+		}
+	}
+	for _, child := range e.Children {
+		if err := enc.EncodeElement(child, xml.StartElement{Name: child.XMLName}); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// Return namespace prefixes, which the extensions may use, mapped to URIs.
+// For parsed documents these are the prefixes declared on the gpx element
+// and on elements enclosing the extensions.
+func (ext *ExtensionsType) Namespaces() map[string]string {
+	if len(ext.scope) == 0 {
+		return ext.namespaces
+	}
+	namespaces := make(map[string]string, len(ext.namespaces)+len(ext.scope))
+	for prefix, uri := range ext.namespaces {
+		namespaces[prefix] = uri
+	}
+	for prefix, uri := range ext.scope {
+		namespaces[prefix] = uri
+	}
+	return namespaces
+}
+
+// UnmarshalXML keeps the content as raw InnerXML and records URIs
+// of the namespace prefixes it uses, which may be declared on any
+// element enclosing the extensions.
+func (ext *ExtensionsType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var content struct {
+		InnerXML string              `xml:",innerxml"`
+		Elements []*ExtensionElement `xml:",any"`
+	}
+	if err := d.DecodeElement(&content, &start); err != nil {
+		return err
+	}
+	ext.XMLName = start.Name
+	ext.InnerXML = content.InnerXML
+	ext.scope = enclosingPrefixes(content.InnerXML, content.Elements)
+	return nil
+}
+
+// MarshalXML writes InnerXML as it is and declares prefixes it uses,
+// which are not declared on the gpx element with the same URI.
+func (ext *ExtensionsType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "extensions"}}
+	used := usedPrefixes(ext.InnerXML)
+	for _, prefix := range sortedKeys(ext.scope) {
+		uri := ext.scope[prefix]
+		if used[prefix] && ext.namespaces[prefix] != uri {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: uri})
+		}
+	}
+	content := struct {
+		InnerXML string `xml:",innerxml"`
+	}{ext.InnerXML}
+	return e.EncodeElement(content, start)
+}
+
+// Elements parses InnerXML and returns all top level elements.
+func (ext *ExtensionsType) Elements() ([]*ExtensionElement, error) {
+	var elements []*ExtensionElement
+	err := ext.scan(func(element *ExtensionElement, from, to int) error {
+		elements = append(elements, element)
+		return nil
+	})
+	return elements, err
+}
+
+// Return all top level elements with given namespace URI and name.
+// Empty space matches any namespace.
+func (ext *ExtensionsType) GetElements(space, local string) ([]*ExtensionElement, error) {
+	var elements []*ExtensionElement
+	err := ext.scan(func(element *ExtensionElement, from, to int) error {
+		if element.Is(space, local) {
+			elements = append(elements, element)
+		}
+		return nil
+	})
+	return elements, err
+}
+
+// Return first top level element with given namespace URI and name
+// or nil if there is no such element.
+// Empty space matches any namespace.
+func (ext *ExtensionsType) GetElement(space, local string) (*ExtensionElement, error) {
+	elements, err := ext.GetElements(space, local)
+	if err != nil || len(elements) == 0 {
+		return nil, err
+	}
+	return elements[0], nil
+}
+
+// AddElement serializes the element and appends it to InnerXML.
+// Known namespace prefixes are used for the element and its children,
+// unknown namespaces are declared on the element itself.
+func (ext *ExtensionsType) AddElement(element *ExtensionElement) error {
+	if element.XMLName.Local == "" {
+		return fmt.Errorf("Extension element has no name")
+	}
+	var b strings.Builder
+	ext.writeElement(&b, element, "")
+	ext.InnerXML += b.String()
+	return nil
+}
+
+// RemoveElements removes all top level elements with given namespace URI
+// and name and returns how many elements were removed.
+// Empty space matches any namespace.
+// The rest of InnerXML is kept untouched.
+func (ext *ExtensionsType) RemoveElements(space, local string) (removed int, err error) {
+	var b strings.Builder
+	last := 0
+	err = ext.scan(func(element *ExtensionElement, from, to int) error {
+		if element.Is(space, local) {
+			b.WriteString(ext.InnerXML[last:from])
+			last = to
+			removed++
+		}
+		return nil
+	})
+	if err != nil || removed == 0 {
+		return 0, err
+	}
+	b.WriteString(ext.InnerXML[last:])
+	ext.InnerXML = b.String()
+	return removed, nil
+}
+
+// SetElement replaces all top level elements with the same
+// namespace URI and name with the element.
+func (ext *ExtensionsType) SetElement(element *ExtensionElement) error {
+	if _, err := ext.RemoveElements(element.XMLName.Space, element.XMLName.Local); err != nil {
+		return err
+	}
+	return ext.AddElement(element)
+}
+
+// IsEmpty reports whether the extensions contain no elements.
+func (ext *ExtensionsType) IsEmpty() bool {
+	return strings.TrimSpace(ext.InnerXML) == ""
+}
+
+// Decodes InnerXML wrapped in an element declaring the known
+// namespaces and calls fn for every top level element with its
+// byte range in InnerXML.
+func (ext *ExtensionsType) scan(fn func(element *ExtensionElement, from, to int) error) error {
+	var b strings.Builder
+	b.WriteString("<extensions")
+	namespaces := ext.Namespaces()
+	for _, prefix := range sortedKeys(namespaces) {
+		b.WriteString(" xmlns:" + prefix + `="`)
+		xml.EscapeText(&b, []byte(namespaces[prefix]))
+		b.WriteString(`"`)
+	}
+	b.WriteString(">")
+	offset := b.Len()
+	b.WriteString(ext.InnerXML)
+	b.WriteString("</extensions>")
+
+	decoder := xml.NewDecoder(strings.NewReader(b.String()))
+	depth := 0
+	for {
+		from := int(decoder.InputOffset()) - offset
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				depth++
+				continue
+			}
+			element := &ExtensionElement{}
+			if err := decoder.DecodeElement(element, &t); err != nil {
+				return err
+			}
+			if err := fn(element, from, int(decoder.InputOffset())-offset); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+func (ext *ExtensionsType) prefixFor(space string) string {
+	namespaces := ext.Namespaces()
+	for _, prefix := range sortedKeys(namespaces) {
+		if namespaces[prefix] == space {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// Writes element as XML, defaultSpace is the default namespace
+// in scope of the element.
+func (ext *ExtensionsType) writeElement(b *strings.Builder, element *ExtensionElement, defaultSpace string) {
+	name := element.XMLName.Local
+	var declarations []string
+	space := element.XMLName.Space
+	if space == "" && defaultSpace != "" {
+		declarations = append(declarations, `xmlns=""`)
+		defaultSpace = ""
+	} else if space != "" && space != defaultSpace {
+		if prefix := ext.prefixFor(space); prefix != "" {
+			name = prefix + ":" + name
+		} else {
+			declarations = append(declarations, `xmlns="`+escapeAttr(space)+`"`)
+			defaultSpace = space
+		}
+	}
+
+	b.WriteString("<" + name)
+	for _, declaration := range declarations {
+		b.WriteString(" " + declaration)
+	}
+	generated := 0
+	for _, attr := range element.Attrs {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		attrName := attr.Name.Local
+		switch {
+		case attr.Name.Space == "":
+		case attr.Name.Space == "http://www.w3.org/XML/1998/namespace":
+			attrName = "xml:" + attrName
+		case ext.prefixFor(attr.Name.Space) != "":
+			attrName = ext.prefixFor(attr.Name.Space) + ":" + attrName
+		default:
+			prefix := fmt.Sprintf("ns%d", generated)
+			generated++
+			b.WriteString(" xmlns:" + prefix + `="` + escapeAttr(attr.Name.Space) + `"`)
+			attrName = prefix + ":" + attrName
+		}
+		b.WriteString(" " + attrName + `="` + escapeAttr(attr.Value) + `"`)
+	}
+	b.WriteString(">")
+	xml.EscapeText(b, []byte(element.Text))
+	for _, child := range element.Children {
+		ext.writeElement(b, child, defaultSpace)
+	}
+	b.WriteString("</" + name + ">")
+}
+
+func escapeAttr(value string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Pair prefixed names of raw XML with names of the decoded elements,
+// which the decoder resolved with declarations of all enclosing
+// elements, and return prefixes not declared inside of the raw XML
+// mapped to their URIs.
+func enclosingPrefixes(innerXML string, elements []*ExtensionElement) map[string]string {
+	var resolved []xml.Name
+	var walk func(elements []*ExtensionElement)
+	walk = func(elements []*ExtensionElement) {
+		for _, element := range elements {
+			resolved = append(resolved, element.XMLName)
+			for _, attr := range element.Attrs {
+				resolved = append(resolved, attr.Name)
+			}
+			walk(element.Children)
+		}
+	}
+	walk(elements)
+
+	prefixes := map[string]string{}
+	var declared []map[string]string
+	decoder := xml.NewDecoder(strings.NewReader(innerXML))
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return prefixes
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			declared = append(declared, namespaceDeclarations(t.Attr))
+			names := []xml.Name{t.Name}
+			for _, attr := range t.Attr {
+				names = append(names, attr.Name)
+			}
+			for _, name := range names {
+				if len(resolved) == 0 {
+					return prefixes
+				}
+				space := resolved[0].Space
+				resolved = resolved[1:]
+				// Unbound prefixes are left unresolved by the decoder.
+				if name.Space == "" || name.Space == "xmlns" || name.Space == "xml" || space == name.Space {
+					continue
+				}
+				if _, ok := prefixes[name.Space]; !ok && !isDeclared(declared, name.Space) {
+					prefixes[name.Space] = space
+				}
+			}
+		case xml.EndElement:
+			if len(declared) > 0 {
+				declared = declared[:len(declared)-1]
+			}
+		}
+	}
+}
+
+func isDeclared(scopes []map[string]string, prefix string) bool {
+	for _, scope := range scopes {
+		if _, ok := scope[prefix]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Return prefixes of elements and attributes of raw XML.
+func usedPrefixes(innerXML string) map[string]bool {
+	used := map[string]bool{}
+	decoder := xml.NewDecoder(strings.NewReader(innerXML))
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return used
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			used[start.Name.Space] = true
+			for _, attr := range start.Attr {
+				used[attr.Name.Space] = true
+			}
+		}
+	}
+}
+
+// Collect namespace prefixes declared by xmlns:prefix attributes.
+func namespaceDeclarations(attrs []xml.Attr) map[string]string {
+	namespaces := map[string]string{}
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" {
+			namespaces[attr.Name.Local] = attr.Value
+		}
+	}
+	return namespaces
+}
+
+func (ext *ExtensionsType) bindNamespaces(namespaces map[string]string) {
+	if ext != nil {
+		ext.namespaces = namespaces
+	}
+}
+
+func (metadata *MetadataType) bindNamespaces(namespaces map[string]string) {
+	if metadata != nil {
+		metadata.Extensions.bindNamespaces(namespaces)
+	}
+}
+
+func (wpt *WptType) bindNamespaces(namespaces map[string]string) {
+	if wpt != nil {
+		wpt.Extensions.bindNamespaces(namespaces)
+	}
+}
+
+func (rte *RteType) bindNamespaces(namespaces map[string]string) {
+	if rte == nil {
+		return
+	}
+	rte.Extensions.bindNamespaces(namespaces)
+	for _, rtept := range rte.Rtept {
+		rtept.bindNamespaces(namespaces)
+	}
+}
+
+func (trk *TrkType) bindNamespaces(namespaces map[string]string) {
+	if trk == nil {
+		return
+	}
+	trk.Extensions.bindNamespaces(namespaces)
+	for _, trkseg := range trk.Trkseg {
+		trkseg.bindNamespaces(namespaces)
+	}
+}
+
+func (trkseg *TrksegType) bindNamespaces(namespaces map[string]string) {
+	if trkseg == nil {
+		return
+	}
+	trkseg.Extensions.bindNamespaces(namespaces)
+	for _, trkpt := range trkseg.Trkpt {
+		trkpt.bindNamespaces(namespaces)
+	}
+}
+
+// Make all extensions in the document resolve prefixes
+// declared on the gpx element.
+func (gpx *GpxType) bindNamespaces() {
+	gpx.Extensions.bindNamespaces(gpx.Namespaces)
+	gpx.Metadata.bindNamespaces(gpx.Namespaces)
+	for _, wpt := range gpx.Wpt {
+		wpt.bindNamespaces(gpx.Namespaces)
+	}
+	for _, rte := range gpx.Rte {
+		rte.bindNamespaces(gpx.Namespaces)
+	}
+	for _, trk := range gpx.Trk {
+		trk.bindNamespaces(gpx.Namespaces)
+	}
+}
+
+// NewExtensions returns empty extensions which use the namespace
+// prefixes declared on the gpx element.
+func (gpx *GpxType) NewExtensions() *ExtensionsType {
+	if gpx.Namespaces == nil {
+		gpx.Namespaces = map[string]string{}
+	}
+	return &ExtensionsType{namespaces: gpx.Namespaces}
+}
+
+// Declare namespace prefix on the gpx element, so it can be used
+// by extensions of the document.
+func (gpx *GpxType) SetNamespace(prefix, uri string) {
+	if gpx.Namespaces == nil {
+		gpx.Namespaces = map[string]string{}
+		gpx.bindNamespaces()
+	}
+	gpx.Namespaces[prefix] = uri
+}
@@ -1,14 +1,56 @@
 package gpx_tools
 
 import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/xml"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
+// Compression used by WriteGpx.
+type Compression int
+
+const (
+	NoCompression Compression = iota
+	GzipCompression
+	ZipCompression
+)
+
+// WriteOptions configure WriteGpx.
+// Zero value writes uncompressed GPX 1.1 without indentation.
+type WriteOptions struct {
+	// Indent is used for every level of nested elements,
+	// empty string writes the whole document on one line.
+	Indent      string
+	Compression Compression
+	// ZipEntryName is the name of the file inside of zip archive,
+	// "track.gpx" is used if it is empty.
+	ZipEntryName string
+	// Version is "1.1" or "1.0", empty string means "1.1".
+	Version string
+	// Creator replaces creator of the document if it is not empty.
+	Creator string
+}
+
+// ParseOptions configure parsing of GPX documents.
+// Zero value parses like ParseGpx.
+type ParseOptions struct {
+	// TimeLocation of timestamps without zone,
+	// nil means DefaultTimeLocation.
+	TimeLocation *time.Location
+}
+
 // This function parses an XML file in the
-// GPX version 1.1 format and returns a Gpx struct
+// GPX version 1.1 or 1.0 format and returns a Gpx struct
 // or an error if the file could not be parsed.
+// Files compressed by gzip or zip are decompressed, see ParseGpx.
 //
 // Returned Gpx is the root element in the XML file and a pointer to GpxType
 func ParseGpxFile(path string) (gpx Gpx, err error) {
@@ -18,38 +60,260 @@ func ParseGpxFile(path string) (gpx Gpx, err error) {
 	}
 	defer xmlFile.Close()
 
-	bytes, err := io.ReadAll(xmlFile)
+	return ParseGpx(xmlFile)
+}
+
+// ParseGpxFileWithOptions is like ParseGpxFile, configured by opts.
+func ParseGpxFileWithOptions(path string, opts ParseOptions) (gpx Gpx, err error) {
+	xmlFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer xmlFile.Close()
+
+	return ParseGpxWithOptions(xmlFile, opts)
+}
+
+// ParseGpx reads the whole GPX document from the reader
+// and parses it like ParseGpxBytes.
+//
+// Gzip and zip compressed input is detected by its first bytes
+// and decompressed, from zip archive the first .gpx file is read.
+func ParseGpx(r io.Reader) (gpx Gpx, err error) {
+	return ParseGpxWithOptions(r, ParseOptions{})
+}
+
+// ParseGpxWithOptions is like ParseGpx, configured by opts.
+func ParseGpxWithOptions(r io.Reader, opts ParseOptions) (gpx Gpx, err error) {
+	r, err = decompressReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
 
-	return ParseGpxBytes(bytes)
+	return ParseGpxBytesWithOptions(bytes, opts)
 }
 
-// Basically alias for Unmarshal
+// Basically alias for Unmarshal.
+// GPX 1.0 documents are detected and upgraded to GPX 1.1,
+// see Gpx10Type.ToGpx.
 func ParseGpxBytes(bytes []byte) (gpx Gpx, err error) {
-	err = xml.Unmarshal(bytes, &gpx)
-	return gpx, err
+	return ParseGpxBytesWithOptions(bytes, ParseOptions{})
+}
+
+// ParseGpxBytesWithOptions is like ParseGpxBytes, configured by opts.
+func ParseGpxBytesWithOptions(bytes []byte, opts ParseOptions) (gpx Gpx, err error) {
+	version, err := DetectGpxVersion(bytes)
+	if err != nil {
+		return nil, err
+	}
+	if version == "1.0" {
+		gpx10, err := ParseGpx10Bytes(bytes)
+		if err != nil {
+			return nil, err
+		}
+		gpx = gpx10.ToGpx()
+	} else if err = xml.Unmarshal(bytes, &gpx); err != nil {
+		return gpx, err
+	}
+
+	if opts.TimeLocation != nil {
+		(*GpxType)(gpx).relocateTimes(opts.TimeLocation)
+	}
+	return gpx, nil
+}
+
+// Read zone-less timestamps of metadata and points in location.
+func (gpx *GpxType) relocateTimes(location *time.Location) {
+	if gpx.Metadata != nil {
+		gpx.Metadata.Time.relocate(location)
+	}
+	for _, wpt := range gpx.Wpt {
+		wpt.Time.relocate(location)
+	}
+	for _, rte := range gpx.Rte {
+		for _, wpt := range rte.Rtept {
+			wpt.Time.relocate(location)
+		}
+	}
+	for _, trk := range gpx.Trk {
+		for _, trkseg := range trk.Trkseg {
+			for _, wpt := range trkseg.Trkpt {
+				wpt.Time.relocate(location)
+			}
+		}
+	}
+}
+
+// Returns reader of decompressed data if r starts
+// with gzip or zip signature, otherwise reader of r.
+// Zip archives are read into memory as they need random access.
+func decompressReader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	signature, err := buffered.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(signature, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(buffered)
+	case bytes.HasPrefix(signature, []byte("PK\x03\x04")):
+		data, err := io.ReadAll(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return openZipEntry(data, ".gpx")
+	}
+	return buffered, nil
+}
+
+// Returns content of the first file in zip archive with given
+// extension or of the first file if there is no such file.
+func openZipEntry(data []byte, extension string) (io.Reader, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *zip.File
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if strings.EqualFold(filepath.Ext(file.Name), extension) {
+			entry = file
+			break
+		}
+		if entry == nil {
+			entry = file
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("Zip archive contains no files")
+	}
+
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(content), nil
 }
 
 // WriteGpxFile writes a Gpx struct to a file
 // or return an error if the file could not be written.
+// Files ending with .gz are compressed by gzip and
+// files ending with .zip are written as zip archive.
 //
-// Beware, the output file may differ from the input file
-// because the XML marshalling will fill in the optional
-// fields that were not present in the input file.
+// Optional elements missing in the Gpx struct are not written.
 func WriteGpxFile(gpx Gpx, path string) (err error) {
+	return writeGpxFile(gpx, path, WriteOptions{Indent: "    "})
+}
+
+// WriteGpx10File converts the Gpx struct to GPX 1.0 and writes it
+// to a file or returns an error if the file could not be written.
+//
+// Data with no place in GPX 1.0 (copyright, additional links)
+// is not written, see ToGpx10.
+func WriteGpx10File(gpx Gpx, path string) (err error) {
+	return writeGpxFile(gpx, path, WriteOptions{Indent: "    ", Version: "1.0"})
+}
+
+func writeGpxFile(gpx Gpx, path string, opts WriteOptions) (err error) {
 	xmlFile, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	defer xmlFile.Close()
+	defer func() {
+		if closeErr := xmlFile.Close(); err == nil {
+			err = closeErr
+		}
+	}()
 
-	bytes, err := xml.MarshalIndent(gpx, "", "    ")
-	if err != nil {
-		return err
+	base := filepath.Base(path)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		opts.Compression = GzipCompression
+	case ".zip":
+		opts.Compression = ZipCompression
+		opts.ZipEntryName = strings.TrimSuffix(base, filepath.Ext(base))
+		if !strings.EqualFold(filepath.Ext(opts.ZipEntryName), ".gpx") {
+			opts.ZipEntryName += ".gpx"
+		}
+	}
+
+	return WriteGpx(xmlFile, gpx, opts)
+}
+
+// WriteGpx encodes the Gpx struct as XML document with XML declaration,
+// GPX namespace and schema location and writes it to w,
+// compressed if requested in opts.
+func WriteGpx(w io.Writer, gpx Gpx, opts WriteOptions) (err error) {
+	if opts.Creator != "" {
+		document := *gpx
+		document.CreatorAttr = opts.Creator
+		gpx = &document
+	}
+
+	var document any
+	switch opts.Version {
+	case "", "1.1":
+		document = gpx
+	case "1.0":
+		document = ToGpx10(gpx)
+	default:
+		return fmt.Errorf("Unsupported GPX version %q", opts.Version)
 	}
 
-	_, err = xmlFile.Write(bytes)
+	switch opts.Compression {
+	case NoCompression:
+		return encodeXml(w, document, opts.Indent)
+	case GzipCompression:
+		compressed := gzip.NewWriter(w)
+		if err := encodeXml(compressed, document, opts.Indent); err != nil {
+			return err
+		}
+		return compressed.Close()
+	case ZipCompression:
+		name := opts.ZipEntryName
+		if name == "" {
+			name = "track.gpx"
+		}
+		archive := zip.NewWriter(w)
+		entry, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		if err := encodeXml(entry, document, opts.Indent); err != nil {
+			return err
+		}
+		return archive.Close()
+	}
+	return fmt.Errorf("Unknown compression %d", opts.Compression)
+}
+
+func encodeXml(w io.Writer, document any, indent string) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", indent)
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
 	return err
 }
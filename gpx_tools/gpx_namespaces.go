@@ -0,0 +1,94 @@
+package gpx_tools
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// DefaultCreator is written as creator of documents without one,
+// as the attribute is required by GPX.
+const DefaultCreator = "gpx_tools"
+
+// Locations of GPX schemas written in xsi:schemaLocation.
+const (
+	Gpx11SchemaLocation = "http://www.topografix.com/GPX/1/1/gpx.xsd"
+	Gpx10SchemaLocation = "http://www.topografix.com/GPX/1/0/gpx.xsd"
+)
+
+// Create new empty GPX 1.1 document.
+// Returned *GpxType can be used wherever Gpx is expected.
+func NewGpx(creator string) *GpxType {
+	return &GpxType{
+		XMLName:     xml.Name{Local: "gpx"},
+		VersionAttr: "1.1",
+		CreatorAttr: creator,
+		Namespaces:  map[string]string{},
+	}
+}
+
+// UnmarshalXML decodes the gpx element and remembers namespace
+// prefixes and schema locations declared on it, so extensions
+// can be read and the document written back with them.
+func (gpx *GpxType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type gpxAlias GpxType
+	if err := d.DecodeElement((*gpxAlias)(gpx), &start); err != nil {
+		return err
+	}
+	gpx.Namespaces = namespaceDeclarations(start.Attr)
+	gpx.SchemaLocation = schemaLocation(start.Attr)
+	gpx.bindNamespaces()
+	return nil
+}
+
+// MarshalXML encodes the gpx element in the GPX 1.1 namespace
+// with schema location and declarations of namespace prefixes used
+// by extensions. Version is always written as 1.1 and missing
+// creator is replaced by DefaultCreator.
+func (gpx *GpxType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type gpxAlias GpxType
+	alias := gpxAlias(*gpx)
+	alias.VersionAttr = "1.1"
+	if alias.CreatorAttr == "" {
+		alias.CreatorAttr = DefaultCreator
+	}
+
+	start = xml.StartElement{
+		Name: xml.Name{Local: "gpx"},
+		Attr: rootNamespaceAttrs(Gpx11Namespace, Gpx11SchemaLocation, gpx.SchemaLocation),
+	}
+	for _, prefix := range sortedKeys(gpx.Namespaces) {
+		if prefix == "xsi" {
+			continue
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: gpx.Namespaces[prefix]})
+	}
+	return e.EncodeElement(&alias, start)
+}
+
+// Return value of xsi:schemaLocation attribute.
+func schemaLocation(attrs []xml.Attr) string {
+	for _, attr := range attrs {
+		if attr.Name.Space == xsiNamespace && attr.Name.Local == "schemaLocation" {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// Return default namespace declaration, xsi namespace declaration
+// and schema location with the GPX schema first followed
+// by other schemas from existing schema location.
+func rootNamespaceAttrs(namespace, location, existing string) []xml.Attr {
+	locations := []string{namespace, location}
+	pairs := strings.Fields(existing)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i] != namespace {
+			locations = append(locations, pairs[i], pairs[i+1])
+		}
+	}
+	return []xml.Attr{
+		{Name: xml.Name{Local: "xmlns"}, Value: namespace},
+		{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace},
+		{Name: xml.Name{Local: "xsi:schemaLocation"}, Value: strings.Join(locations, " ")},
+	}
+}
@@ -0,0 +1,140 @@
+package gpx_tools
+
+// Optional elements of GPX are represented by pointers,
+// nil means the element is not present in the document
+// and is not written back.
+
+// Optional returns pointer to a copy of value,
+// handy for filling optional fields.
+func Optional[T any](value T) *T {
+	return &value
+}
+
+// Return value of optional field or zero value if it is not present.
+func optionalValue[T any](value *T) T {
+	var zero T
+	if value == nil {
+		return zero
+	}
+	return *value
+}
+
+// Return true if waypoint has elevation.
+func (wpt *WptType) HasElevation() bool {
+	return wpt.Ele != nil
+}
+
+// Set elevation of waypoint in meters.
+func (wpt *WptType) SetElevation(ele float64) {
+	wpt.Ele = &ele
+}
+
+// Return true if waypoint has magnetic variation.
+func (wpt *WptType) HasMagvar() bool {
+	return wpt.Magvar != nil
+}
+
+// Set magnetic variation of waypoint in degrees.
+func (wpt *WptType) SetMagvar(magvar float64) {
+	wpt.Magvar = &magvar
+}
+
+// Return true if waypoint has height of geoid above WGS84 ellipsoid.
+func (wpt *WptType) HasGeoidheight() bool {
+	return wpt.Geoidheight != nil
+}
+
+// Set height of geoid above WGS84 ellipsoid in meters.
+func (wpt *WptType) SetGeoidheight(geoidheight float64) {
+	wpt.Geoidheight = &geoidheight
+}
+
+// Return true if waypoint has number of satellites.
+func (wpt *WptType) HasSat() bool {
+	return wpt.Sat != nil
+}
+
+// Set number of satellites used to calculate the fix.
+func (wpt *WptType) SetSat(sat int) {
+	wpt.Sat = &sat
+}
+
+// Return true if waypoint has horizontal dilution of precision.
+func (wpt *WptType) HasHdop() bool {
+	return wpt.Hdop != nil
+}
+
+// Set horizontal dilution of precision.
+func (wpt *WptType) SetHdop(hdop float64) {
+	wpt.Hdop = &hdop
+}
+
+// Return true if waypoint has vertical dilution of precision.
+func (wpt *WptType) HasVdop() bool {
+	return wpt.Vdop != nil
+}
+
+// Set vertical dilution of precision.
+func (wpt *WptType) SetVdop(vdop float64) {
+	wpt.Vdop = &vdop
+}
+
+// Return true if waypoint has position dilution of precision.
+func (wpt *WptType) HasPdop() bool {
+	return wpt.Pdop != nil
+}
+
+// Set position dilution of precision.
+func (wpt *WptType) SetPdop(pdop float64) {
+	wpt.Pdop = &pdop
+}
+
+// Return true if waypoint has age of DGPS data.
+func (wpt *WptType) HasAgeofdgpsdata() bool {
+	return wpt.Ageofdgpsdata != nil
+}
+
+// Set number of seconds since last DGPS update.
+func (wpt *WptType) SetAgeofdgpsdata(ageofdgpsdata float64) {
+	wpt.Ageofdgpsdata = &ageofdgpsdata
+}
+
+// Return true if waypoint has ID of DGPS station.
+func (wpt *WptType) HasDgpsid() bool {
+	return wpt.Dgpsid != nil
+}
+
+// Set ID of DGPS station used in differential correction.
+func (wpt *WptType) SetDgpsid(dgpsid int) {
+	wpt.Dgpsid = &dgpsid
+}
+
+// Return true if route has GPS route number.
+func (rte *RteType) HasNumber() bool {
+	return rte.Number != nil
+}
+
+// Set GPS route number.
+func (rte *RteType) SetNumber(number int) {
+	rte.Number = &number
+}
+
+// Return true if track has GPS track number.
+func (trk *TrkType) HasNumber() bool {
+	return trk.Number != nil
+}
+
+// Set GPS track number.
+func (trk *TrkType) SetNumber(number int) {
+	trk.Number = &number
+}
+
+// Return true if point has elevation.
+func (pt *PtType) HasElevation() bool {
+	return pt.Ele != nil
+}
+
+// Set elevation of point in meters.
+func (pt *PtType) SetElevation(ele float64) {
+	pt.Ele = &ele
+}
@@ -0,0 +1,174 @@
+package gpx_tools
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Namespaces of extensions holding sensor data of track points.
+const (
+	TrackPointExtensionNamespace = "http://www.garmin.com/xmlschemas/TrackPointExtension/v2"
+	PowerExtensionNamespace      = "http://www.garmin.com/xmlschemas/PowerExtension/v1"
+	GpxDataNamespace             = "http://www.cluetrust.com/XML/GPXDATA/1/0"
+)
+
+// Prefixes declared for the sensor data namespaces by SetSensorData.
+const (
+	trackPointExtensionPrefix = "gpxtpx"
+	powerExtensionPrefix      = "gpxpx"
+	gpxDataPrefix             = "gpxdata"
+)
+
+// SensorData holds values of sensors recorded with a track point.
+// Missing values are nil.
+type SensorData struct {
+	// HeartRate in beats per minute.
+	HeartRate *int
+	// Cadence in revolutions (or steps of one foot) per minute.
+	Cadence *int
+	// Temperature of air in degrees Celsius.
+	Temperature *float64
+	// Speed in meters per second.
+	Speed *float64
+	// Course over ground in degrees.
+	Course *float64
+	// Power in watts.
+	Power *int
+	// Distance from the start of the activity in meters.
+	Distance *float64
+}
+
+// IsEmpty reports whether no sensor value is present.
+func (data SensorData) IsEmpty() bool {
+	return data.HeartRate == nil && data.Cadence == nil && data.Temperature == nil &&
+		data.Speed == nil && data.Course == nil && data.Power == nil && data.Distance == nil
+}
+
+// GetSensorData reads sensor values from extensions of the point.
+// Garmin TrackPointExtension (v1 and v2), PowerExtension
+// and gpxdata distance are recognized.
+func (wpt *WptType) GetSensorData() (data SensorData, err error) {
+	if wpt.Extensions == nil {
+		return data, nil
+	}
+	elements, err := wpt.Extensions.Elements()
+	if err != nil {
+		return data, err
+	}
+
+	for _, element := range elements {
+		switch {
+		case element.Is("", "TrackPointExtension"):
+			for _, child := range element.Children {
+				switch child.XMLName.Local {
+				case "hr":
+					data.HeartRate = parseSensorInt(child.Text)
+				case "cad":
+					data.Cadence = parseSensorInt(child.Text)
+				case "atemp":
+					data.Temperature = parseSensorFloat(child.Text)
+				case "speed":
+					data.Speed = parseSensorFloat(child.Text)
+				case "course":
+					data.Course = parseSensorFloat(child.Text)
+				}
+			}
+		case element.Is("", "PowerInWatts"):
+			data.Power = parseSensorInt(element.Text)
+		case element.Is(GpxDataNamespace, "distance"):
+			data.Distance = parseSensorFloat(element.Text)
+		}
+	}
+	return data, nil
+}
+
+// SetSensorData replaces sensor values in extensions of the point, which
+// belongs to the document. Namespaces of the extensions are declared
+// on the document if they are not declared yet.
+func (gpx *GpxType) SetSensorData(wpt *WptType, data SensorData) error {
+	if wpt.Extensions == nil {
+		if data.IsEmpty() {
+			return nil
+		}
+		wpt.Extensions = gpx.NewExtensions()
+	}
+	for _, name := range []string{"TrackPointExtension", "PowerInWatts"} {
+		if _, err := wpt.Extensions.RemoveElements("", name); err != nil {
+			return err
+		}
+	}
+	if _, err := wpt.Extensions.RemoveElements(GpxDataNamespace, "distance"); err != nil {
+		return err
+	}
+
+	// Children are in the order required by the schema.
+	tpx := NewExtensionElement(TrackPointExtensionNamespace, "TrackPointExtension", "")
+	if data.Temperature != nil {
+		tpx.AddChild(NewExtensionElement(TrackPointExtensionNamespace, "atemp", formatFloat(*data.Temperature)))
+	}
+	if data.HeartRate != nil {
+		tpx.AddChild(NewExtensionElement(TrackPointExtensionNamespace, "hr", strconv.Itoa(*data.HeartRate)))
+	}
+	if data.Cadence != nil {
+		tpx.AddChild(NewExtensionElement(TrackPointExtensionNamespace, "cad", strconv.Itoa(*data.Cadence)))
+	}
+	if data.Speed != nil {
+		tpx.AddChild(NewExtensionElement(TrackPointExtensionNamespace, "speed", formatFloat(*data.Speed)))
+	}
+	if data.Course != nil {
+		tpx.AddChild(NewExtensionElement(TrackPointExtensionNamespace, "course", formatFloat(*data.Course)))
+	}
+	if len(tpx.Children) > 0 {
+		gpx.declareNamespace(trackPointExtensionPrefix, TrackPointExtensionNamespace)
+		if err := wpt.Extensions.AddElement(tpx); err != nil {
+			return err
+		}
+	}
+	if data.Power != nil {
+		gpx.declareNamespace(powerExtensionPrefix, PowerExtensionNamespace)
+		if err := wpt.Extensions.AddElement(NewExtensionElement(PowerExtensionNamespace, "PowerInWatts", strconv.Itoa(*data.Power))); err != nil {
+			return err
+		}
+	}
+	if data.Distance != nil {
+		gpx.declareNamespace(gpxDataPrefix, GpxDataNamespace)
+		if err := wpt.Extensions.AddElement(NewExtensionElement(GpxDataNamespace, "distance", formatFloat(*data.Distance))); err != nil {
+			return err
+		}
+	}
+	if wpt.Extensions.IsEmpty() {
+		wpt.Extensions = nil
+	}
+	return nil
+}
+
+// Declare the namespace unless it is declared already,
+// the prefix is changed if it is used for other namespace.
+func (gpx *GpxType) declareNamespace(prefix, uri string) {
+	for _, declared := range gpx.Namespaces {
+		if declared == uri {
+			return
+		}
+	}
+	candidate := prefix
+	for i := 2; gpx.Namespaces[candidate] != ""; i++ {
+		candidate = prefix + strconv.Itoa(i)
+	}
+	gpx.SetNamespace(candidate, uri)
+}
+
+func parseSensorInt(text string) *int {
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil {
+		return nil
+	}
+	return Optional(int(value))
+}
+
+func parseSensorFloat(text string) *float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil {
+		return nil
+	}
+	return Optional(value)
+}
@@ -0,0 +1,332 @@
+package gpx_tools
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+)
+
+// PointKind tells from which GPX element a streamed point comes.
+type PointKind int
+
+const (
+	WaypointKind PointKind = iota
+	RoutePointKind
+	TrackPointKind
+)
+
+// StreamPoint is a single point emitted by StreamGpx together
+// with its position in the document.
+//
+// Rte, Trk and Trkseg are zero based indexes of the enclosing
+// elements, or -1 if the point is not inside such element.
+// Index is the position of the point inside its parent.
+type StreamPoint struct {
+	Kind   PointKind
+	Rte    int
+	Trk    int
+	Trkseg int
+	Index  int
+	Point  *WptType
+}
+
+// StreamHandler holds callbacks called by StreamGpx.
+// Every callback is optional, nil callbacks are skipped.
+// If a callback returns an error, decoding stops and
+// the error is returned from StreamGpx.
+//
+// OnGpx receives the root element with attributes and namespaces only.
+// OnRoute and OnTrack are called when the element is closed
+// and receive the element without its points.
+type StreamHandler struct {
+	OnGpx      func(gpx *GpxType) error
+	OnMetadata func(metadata *MetadataType) error
+	OnPoint    func(point StreamPoint) error
+	OnRoute    func(index int, rte *RteType) error
+	OnTrack    func(index int, trk *TrkType) error
+}
+
+// StreamGpxFile opens a GPX 1.1 file and decodes it with StreamGpx.
+func StreamGpxFile(path string, handler StreamHandler) (err error) {
+	xmlFile, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer xmlFile.Close()
+
+	return StreamGpx(xmlFile, handler)
+}
+
+// StreamGpx walks the GPX 1.1 document token by token and
+// passes metadata, waypoints, route points and track points
+// to the handler as soon as they are read.
+//
+// Unlike ParseGpxFile the whole document is never held in memory,
+// so it is suitable for very large files.
+// Gzip compressed input is decompressed on the fly, zip archives
+// are supported too, but they are read into memory first.
+func StreamGpx(r io.Reader, handler StreamHandler) error {
+	return StreamGpxWithOptions(r, handler, ParseOptions{})
+}
+
+// StreamGpxWithOptions is like StreamGpx, configured by opts.
+func StreamGpxWithOptions(r io.Reader, handler StreamHandler, opts ParseOptions) error {
+	r, err := decompressReader(r)
+	if err != nil {
+		return err
+	}
+	s := gpxStreamer{decoder: xml.NewDecoder(r), handler: handler, opts: opts}
+	return s.run()
+}
+
+// StreamGpxChannel runs StreamGpx in a new goroutine and sends
+// all points to the returned channel.
+// The point channel is closed when decoding ends, after that the
+// error channel yields the result of decoding (nil on success).
+//
+// The caller must drain the point channel, otherwise the
+// goroutine is never finished.
+func StreamGpxChannel(r io.Reader) (<-chan StreamPoint, <-chan error) {
+	return StreamGpxChannelWithOptions(r, ParseOptions{})
+}
+
+// StreamGpxChannelWithOptions is like StreamGpxChannel, configured by opts.
+func StreamGpxChannelWithOptions(r io.Reader, opts ParseOptions) (<-chan StreamPoint, <-chan error) {
+	points := make(chan StreamPoint)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		err := StreamGpxWithOptions(r, StreamHandler{
+			OnPoint: func(point StreamPoint) error {
+				points <- point
+				return nil
+			},
+		}, opts)
+		close(points)
+		errs <- err
+	}()
+	return points, errs
+}
+
+type gpxStreamer struct {
+	decoder *xml.Decoder
+	handler StreamHandler
+	opts    ParseOptions
+	wpt     int
+	rte     int
+	trk     int
+
+	namespaces map[string]string
+}
+
+func (s *gpxStreamer) run() error {
+	for {
+		token, err := s.decoder.Token()
+		if err == io.EOF {
+			return fmt.Errorf("No gpx element found")
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if start.Name.Local != "gpx" {
+				return fmt.Errorf("Root element is <%s>, not <gpx>", start.Name.Local)
+			}
+			return s.readGpx(start)
+		}
+	}
+}
+
+func (s *gpxStreamer) readGpx(start xml.StartElement) error {
+	root := &GpxType{
+		XMLName:        start.Name,
+		Namespaces:     namespaceDeclarations(start.Attr),
+		SchemaLocation: schemaLocation(start.Attr),
+	}
+	s.namespaces = root.Namespaces
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "version":
+			root.VersionAttr = attr.Value
+		case "creator":
+			root.CreatorAttr = attr.Value
+		}
+	}
+	if s.handler.OnGpx != nil {
+		if err := s.handler.OnGpx(root); err != nil {
+			return err
+		}
+	}
+
+	return s.forEachChild(func(child xml.StartElement) error {
+		switch child.Name.Local {
+		case "metadata":
+			metadata := &MetadataType{}
+			if err := s.decoder.DecodeElement(metadata, &child); err != nil {
+				return err
+			}
+			metadata.bindNamespaces(s.namespaces)
+			if s.opts.TimeLocation != nil {
+				metadata.Time.relocate(s.opts.TimeLocation)
+			}
+			if s.handler.OnMetadata != nil {
+				return s.handler.OnMetadata(metadata)
+			}
+			return nil
+		case "wpt":
+			index := s.wpt
+			s.wpt++
+			return s.readPoint(child, StreamPoint{Kind: WaypointKind, Rte: -1, Trk: -1, Trkseg: -1, Index: index})
+		case "rte":
+			index := s.rte
+			s.rte++
+			return s.readRte(index)
+		case "trk":
+			index := s.trk
+			s.trk++
+			return s.readTrk(index)
+		default:
+			return s.decoder.Skip()
+		}
+	})
+}
+
+func (s *gpxStreamer) readRte(index int) error {
+	rte := &RteType{}
+	point := 0
+	err := s.forEachChild(func(child xml.StartElement) error {
+		switch child.Name.Local {
+		case "rtept":
+			p := StreamPoint{Kind: RoutePointKind, Rte: index, Trk: -1, Trkseg: -1, Index: point}
+			point++
+			return s.readPoint(child, p)
+		case "name":
+			return s.decoder.DecodeElement(&rte.Name, &child)
+		case "cmt":
+			return s.decoder.DecodeElement(&rte.Cmt, &child)
+		case "desc":
+			return s.decoder.DecodeElement(&rte.Desc, &child)
+		case "src":
+			return s.decoder.DecodeElement(&rte.Src, &child)
+		case "link":
+			link := &LinkType{}
+			rte.Link = append(rte.Link, link)
+			return s.decoder.DecodeElement(link, &child)
+		case "number":
+			return s.decoder.DecodeElement(&rte.Number, &child)
+		case "type":
+			return s.decoder.DecodeElement(&rte.Type, &child)
+		case "extensions":
+			rte.Extensions = &ExtensionsType{}
+			return s.decoder.DecodeElement(rte.Extensions, &child)
+		default:
+			return s.decoder.Skip()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	rte.bindNamespaces(s.namespaces)
+	if s.handler.OnRoute != nil {
+		return s.handler.OnRoute(index, rte)
+	}
+	return nil
+}
+
+func (s *gpxStreamer) readTrk(index int) error {
+	trk := &TrkType{}
+	segment := 0
+	err := s.forEachChild(func(child xml.StartElement) error {
+		switch child.Name.Local {
+		case "trkseg":
+			trkseg := &TrksegType{}
+			trk.Trkseg = append(trk.Trkseg, trkseg)
+			segment++
+			return s.readTrkseg(index, segment-1, trkseg)
+		case "name":
+			return s.decoder.DecodeElement(&trk.Name, &child)
+		case "cmt":
+			return s.decoder.DecodeElement(&trk.Cmt, &child)
+		case "desc":
+			return s.decoder.DecodeElement(&trk.Desc, &child)
+		case "src":
+			return s.decoder.DecodeElement(&trk.Src, &child)
+		case "link":
+			link := &LinkType{}
+			trk.Link = append(trk.Link, link)
+			return s.decoder.DecodeElement(link, &child)
+		case "number":
+			return s.decoder.DecodeElement(&trk.Number, &child)
+		case "type":
+			return s.decoder.DecodeElement(&trk.Type, &child)
+		case "extensions":
+			trk.Extensions = &ExtensionsType{}
+			return s.decoder.DecodeElement(trk.Extensions, &child)
+		default:
+			return s.decoder.Skip()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	trk.bindNamespaces(s.namespaces)
+	if s.handler.OnTrack != nil {
+		return s.handler.OnTrack(index, trk)
+	}
+	return nil
+}
+
+// Segment points are not stored in trkseg,
+// only its extensions are kept.
+func (s *gpxStreamer) readTrkseg(trk, index int, trkseg *TrksegType) error {
+	point := 0
+	return s.forEachChild(func(child xml.StartElement) error {
+		switch child.Name.Local {
+		case "trkpt":
+			p := StreamPoint{Kind: TrackPointKind, Rte: -1, Trk: trk, Trkseg: index, Index: point}
+			point++
+			return s.readPoint(child, p)
+		case "extensions":
+			trkseg.Extensions = &ExtensionsType{}
+			return s.decoder.DecodeElement(trkseg.Extensions, &child)
+		default:
+			return s.decoder.Skip()
+		}
+	})
+}
+
+func (s *gpxStreamer) readPoint(start xml.StartElement, point StreamPoint) error {
+	point.Point = &WptType{}
+	if err := s.decoder.DecodeElement(point.Point, &start); err != nil {
+		return err
+	}
+	point.Point.bindNamespaces(s.namespaces)
+	if s.opts.TimeLocation != nil {
+		point.Point.Time.relocate(s.opts.TimeLocation)
+	}
+	if s.handler.OnPoint != nil {
+		return s.handler.OnPoint(point)
+	}
+	return nil
+}
+
+// Calls fn for every direct child element of the element
+// that was read last, until its end element is reached.
+// fn must consume the whole child element.
+func (s *gpxStreamer) forEachChild(fn func(child xml.StartElement) error) error {
+	for {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if err := fn(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
@@ -2,6 +2,7 @@ package gpx_tools
 
 import (
 	"encoding/xml"
+	"fmt"
 	"time"
 )
 
@@ -18,58 +19,66 @@ type GpxType struct {
 	Rte         []*RteType      `xml:"rte"`
 	Trk         []*TrkType      `xml:"trk"`
 	Extensions  *ExtensionsType `xml:"extensions"`
+
+	// Namespaces maps prefixes declared on the gpx element to namespace URIs.
+	Namespaces map[string]string `xml:"-"`
+	// SchemaLocation is the xsi:schemaLocation attribute of the gpx element,
+	// the GPX schema is always added to it when writing.
+	SchemaLocation string `xml:"-"`
 }
 
 // MetadataType is You can add extend GPX by adding your own elements from another schema here.
 type MetadataType struct {
 	XMLName    xml.Name        `xml:"metadata"`
-	Name       string          `xml:"name"`
-	Desc       string          `xml:"desc"`
+	Name       string          `xml:"name,omitempty"`
+	Desc       string          `xml:"desc,omitempty"`
 	Author     *PersonType     `xml:"author"`
 	Copyright  *CopyrightType  `xml:"copyright"`
 	Link       []*LinkType     `xml:"link"`
-	Time       string          `xml:"time"`
-	Keywords   string          `xml:"keywords"`
+	Time       *GpxTime        `xml:"time,omitempty"`
+	Keywords   string          `xml:"keywords,omitempty"`
 	Bounds     *BoundsType     `xml:"bounds"`
 	Extensions *ExtensionsType `xml:"extensions"`
 }
 
 // WptType is You can add extend GPX by adding your own elements from another schema here.
+// The same type is used for wpt, rtept and trkpt elements, so the element
+// name is taken from the field it is stored in.
 type WptType struct {
-	XMLName       xml.Name        `xml:"wpt"`
+	XMLName       xml.Name        `xml:"-"`
 	LatAttr       float64         `xml:"lat,attr"`
 	LonAttr       float64         `xml:"lon,attr"`
-	Ele           float64         `xml:"ele"`
-	Time          string          `xml:"time"`
-	Magvar        float64         `xml:"magvar"`
-	Geoidheight   float64         `xml:"geoidheight"`
-	Name          string          `xml:"name"`
-	Cmt           string          `xml:"cmt"`
-	Desc          string          `xml:"desc"`
-	Src           string          `xml:"src"`
+	Ele           *float64        `xml:"ele,omitempty"`
+	Time          *GpxTime        `xml:"time,omitempty"`
+	Magvar        *float64        `xml:"magvar,omitempty"`
+	Geoidheight   *float64        `xml:"geoidheight,omitempty"`
+	Name          string          `xml:"name,omitempty"`
+	Cmt           string          `xml:"cmt,omitempty"`
+	Desc          string          `xml:"desc,omitempty"`
+	Src           string          `xml:"src,omitempty"`
 	Link          []*LinkType     `xml:"link"`
-	Sym           string          `xml:"sym"`
-	Type          string          `xml:"type"`
-	Fix           string          `xml:"fix"`
-	Sat           int             `xml:"sat"`
-	Hdop          float64         `xml:"hdop"`
-	Vdop          float64         `xml:"vdop"`
-	Pdop          float64         `xml:"pdop"`
-	Ageofdgpsdata float64         `xml:"ageofdgpsdata"`
-	Dgpsid        int             `xml:"dgpsid"`
+	Sym           string          `xml:"sym,omitempty"`
+	Type          string          `xml:"type,omitempty"`
+	Fix           string          `xml:"fix,omitempty"`
+	Sat           *int            `xml:"sat,omitempty"`
+	Hdop          *float64        `xml:"hdop,omitempty"`
+	Vdop          *float64        `xml:"vdop,omitempty"`
+	Pdop          *float64        `xml:"pdop,omitempty"`
+	Ageofdgpsdata *float64        `xml:"ageofdgpsdata,omitempty"`
+	Dgpsid        *int            `xml:"dgpsid,omitempty"`
 	Extensions    *ExtensionsType `xml:"extensions"`
 }
 
 // RteType is A list of route points.
 type RteType struct {
 	XMLName    xml.Name        `xml:"rte"`
-	Name       string          `xml:"name"`
-	Cmt        string          `xml:"cmt"`
-	Desc       string          `xml:"desc"`
-	Src        string          `xml:"src"`
+	Name       string          `xml:"name,omitempty"`
+	Cmt        string          `xml:"cmt,omitempty"`
+	Desc       string          `xml:"desc,omitempty"`
+	Src        string          `xml:"src,omitempty"`
 	Link       []*LinkType     `xml:"link"`
-	Number     int             `xml:"number"`
-	Type       string          `xml:"type"`
+	Number     *int            `xml:"number,omitempty"`
+	Type       string          `xml:"type,omitempty"`
 	Extensions *ExtensionsType `xml:"extensions"`
 	Rtept      []*WptType      `xml:"rtept"`
 }
@@ -78,26 +87,34 @@ type RteType struct {
 // To represent a single GPS track where GPS reception was lost, or the GPS receiver was turned off, start a new Track Segment for each continuous span of track data.
 type TrkType struct {
 	XMLName    xml.Name        `xml:"trk"`
-	Name       string          `xml:"name"`
-	Cmt        string          `xml:"cmt"`
-	Desc       string          `xml:"desc"`
-	Src        string          `xml:"src"`
+	Name       string          `xml:"name,omitempty"`
+	Cmt        string          `xml:"cmt,omitempty"`
+	Desc       string          `xml:"desc,omitempty"`
+	Src        string          `xml:"src,omitempty"`
 	Link       []*LinkType     `xml:"link"`
-	Number     int             `xml:"number"`
-	Type       string          `xml:"type"`
+	Number     *int            `xml:"number,omitempty"`
+	Type       string          `xml:"type,omitempty"`
 	Extensions *ExtensionsType `xml:"extensions"`
 	Trkseg     []*TrksegType   `xml:"trkseg"`
 }
 
 // ExtensionsType is You can add extend GPX by adding your own elements from another schema here.
+// The content is kept as raw XML, so elements of any schema survive
+// parsing and writing, use Elements, AddElement and RemoveElements to work with it.
 type ExtensionsType struct {
-	XMLName xml.Name `xml:"extensions"`
+	XMLName  xml.Name `xml:"extensions"`
+	InnerXML string   `xml:",innerxml"`
+
+	namespaces map[string]string
+	// Prefixes used by InnerXML and declared outside of it, recorded
+	// when parsing, as they may be declared below the gpx element.
+	scope map[string]string
 }
 
 // TrksegType is You can add extend GPX by adding your own elements from another schema here.
 type TrksegType struct {
 	XMLName    xml.Name        `xml:"trkseg"`
-	Trkpt      []*WptType      `xml:"wpt"`
+	Trkpt      []*WptType      `xml:"trkpt"`
 	Extensions *ExtensionsType `xml:"extensions"`
 }
 
@@ -105,16 +122,16 @@ type TrksegType struct {
 type CopyrightType struct {
 	XMLName    xml.Name `xml:"copyright"`
 	AuthorAttr string   `xml:"author,attr"`
-	Year       string   `xml:"year"`
-	License    string   `xml:"license"`
+	Year       string   `xml:"year,omitempty"`
+	License    string   `xml:"license,omitempty"`
 }
 
 // LinkType is Mime type of content (image/jpeg)
 type LinkType struct {
 	XMLName  xml.Name `xml:"link"`
 	HrefAttr string   `xml:"href,attr"`
-	Text     string   `xml:"text"`
-	Type     string   `xml:"type"`
+	Text     string   `xml:"text,omitempty"`
+	Type     string   `xml:"type,omitempty"`
 }
 
 // EmailType is An email address.  Broken into two parts (id and domain) to help prevent email harvesting.
@@ -127,7 +144,7 @@ type EmailType struct {
 // PersonType is Link to Web site or other external information about person.
 type PersonType struct {
 	XMLName xml.Name   `xml:"author"`
-	Name    string     `xml:"name"`
+	Name    string     `xml:"name,omitempty"`
 	Email   *EmailType `xml:"email"`
 	Link    *LinkType  `xml:"link"`
 }
@@ -137,8 +154,8 @@ type PtType struct {
 	XMLName xml.Name `xml:"ptType"`
 	LatAttr float64  `xml:"lat,attr"`
 	LonAttr float64  `xml:"lon,attr"`
-	Ele     float64  `xml:"ele"`
-	Time    string   `xml:"time"`
+	Ele     *float64 `xml:"ele,omitempty"`
+	Time    *GpxTime `xml:"time,omitempty"`
 }
 
 // PtsegType is Ordered list of geographic points.
@@ -171,49 +188,89 @@ type FixType string
 // DgpsStationType is Represents a differential GPS station.
 type DgpsStationType int
 
-// Convert BoundsType to a tuple of Coordinates.
+// Convert BoundsType to a tuple of Coordinates,
+// invalid latitudes are wrapped over the pole.
+// Use ToCoordinatesChecked to report invalid bounds instead.
 func (bounds *BoundsType) ToCoordinates() (minCoords Coordinates, maxCoords Coordinates) {
 	minCoords = NewCoordinates(bounds.MinlatAttr, bounds.MinlonAttr)
 	maxCoords = NewCoordinates(bounds.MaxlatAttr, bounds.MaxlonAttr)
 	return minCoords, maxCoords
 }
 
+// Convert BoundsType to a tuple of Coordinates and return error
+// if any coordinate is invalid or minimal latitude is greater
+// than maximal one.
+func (bounds *BoundsType) ToCoordinatesChecked() (minCoords Coordinates, maxCoords Coordinates, err error) {
+	minCoords, err = NewCoordinatesStrict(bounds.MinlatAttr, bounds.MinlonAttr)
+	if err != nil {
+		return minCoords, maxCoords, fmt.Errorf("Invalid minimum of bounds: %v", err)
+	}
+	maxCoords, err = NewCoordinatesStrict(bounds.MaxlatAttr, bounds.MaxlonAttr)
+	if err != nil {
+		return minCoords, maxCoords, fmt.Errorf("Invalid maximum of bounds: %v", err)
+	}
+	if minCoords.Latitude > maxCoords.Latitude {
+		return minCoords, maxCoords, fmt.Errorf("Minimal latitude %v of bounds is greater than maximal %v", bounds.MinlatAttr, bounds.MaxlatAttr)
+	}
+	return minCoords, maxCoords, nil
+}
+
 // Convert WptType to Coordinates
 // used by gpx_toolkit to represent
 // universal coordinates and perform
 // calculations on them.
+// Invalid latitude is wrapped over the pole, checked
+// functions of this package use ToCoordinatesChecked to report it.
 func (wpt *WptType) ToCoordinates() Coordinates {
 	return NewCoordinates(wpt.LatAttr, wpt.LonAttr)
 }
 
+// Convert WptType to Coordinates and return
+// error if its latitude or longitude is invalid.
+func (wpt *WptType) ToCoordinatesChecked() (Coordinates, error) {
+	return NewCoordinatesStrict(wpt.LatAttr, wpt.LonAttr)
+}
+
 // Convert WptType to Coordinates3D
 // used byt gpx_toolkit to represent
 // universal coordinates with elevation
 // and perform calculations on them.
+// Missing elevation is converted to 0,
+// use HasElevation to skip such points.
 func (wpt *WptType) ToCoordinates3D() Coordinates3D {
-	return NewCoordinates3D(wpt.LatAttr, wpt.LonAttr, wpt.Ele)
+	return NewCoordinates3D(wpt.LatAttr, wpt.LonAttr, optionalValue(wpt.Ele))
 }
 
 func (wpt *WptType) getTimestamp() (time.Time, error) {
-	return ParseGpxTimeStr(wpt.Time)
+	return wpt.Time.Get()
 }
 
 // Convert PtType to Coordinates
 // used by gpx_toolkit to represent
 // universal coordinates and perform
 // calculations on them.
+// Invalid latitude is wrapped over the pole, checked
+// functions of this package use ToCoordinatesChecked to report it.
 func (pt *PtType) ToCoordinates() Coordinates {
 	return NewCoordinates(pt.LatAttr, pt.LonAttr)
 }
 
+// Convert PtType to Coordinates and return
+// error if its latitude or longitude is invalid.
+func (pt *PtType) ToCoordinatesChecked() (Coordinates, error) {
+	return NewCoordinatesStrict(pt.LatAttr, pt.LonAttr)
+}
+
 // Convert PtType to Coordinates3D
 // used by gpx_toolkit to represent
 // universal coordinates with elevation
 // and perform calculations on them.
+// Missing elevation is converted to 0,
+// use HasElevation to skip such points.
 func (pt *PtType) ToCoordinates3D() Coordinates3D {
-	return NewCoordinates3D(pt.LatAttr, pt.LonAttr, pt.Ele)
+	return NewCoordinates3D(pt.LatAttr, pt.LonAttr, optionalValue(pt.Ele))
 }
 
 func (pt *PtType) getTimestamp() (time.Time, error) {
-	return ParseGpxTimeStr(pt.Time)
+	return pt.Time.Get()
 }
@@ -0,0 +1,174 @@
+package gpx_tools
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeLocation is used for timestamps without time zone
+// when no location is given by ParseOptions. GPX requires UTC, but
+// some devices write local time without zone, prefer
+// ParseOptions.TimeLocation to read such timestamps in their zone,
+// as changing this variable affects all parsing.
+var DefaultTimeLocation = time.UTC
+
+// GpxTime is a timestamp of a GPX element.
+//
+// Raw keeps the lexical form read from the document, so the
+// timestamp is written back exactly as it was read unless
+// Time is changed. Timestamps which cannot be parsed are kept
+// in Raw too, Get returns the parse error for them.
+type GpxTime struct {
+	Time time.Time
+	Raw  string
+
+	parsed time.Time
+	err    error
+}
+
+// Create new GpxTime holding t.
+func NewGpxTime(t time.Time) *GpxTime {
+	return &GpxTime{Time: t}
+}
+
+// Create new GpxTime from its lexical form,
+// zone-less timestamps are read in DefaultTimeLocation.
+func NewGpxTimeStr(timeStr string) *GpxTime {
+	t := &GpxTime{}
+	t.setRaw(timeStr)
+	return t
+}
+
+func (t *GpxTime) setRaw(raw string) {
+	t.Raw = raw
+	t.parsed, t.err = ParseGpxTimeStr(raw)
+	t.Time = t.parsed
+}
+
+// Read zone-less timestamp again in location,
+// timestamps with changed Time are kept.
+func (t *GpxTime) relocate(location *time.Location) {
+	if t == nil || t.Raw == "" || !t.Time.Equal(t.parsed) {
+		return
+	}
+	t.parsed, t.err = ParseGpxTimeStrIn(t.Raw, location)
+	t.Time = t.parsed
+}
+
+// Get returns the timestamp or an error if it is not
+// defined or could not be parsed.
+func (t *GpxTime) Get() (time.Time, error) {
+	if t == nil {
+		return time.Time{}, fmt.Errorf("Time is not defined")
+	}
+	if t.err != nil && t.Time.Equal(t.parsed) {
+		return time.Time{}, t.err
+	}
+	return t.Time, nil
+}
+
+// String returns the lexical form of the timestamp,
+// the original one if Time was not changed after parsing.
+func (t *GpxTime) String() string {
+	if t.Raw != "" && t.Time.Equal(t.parsed) {
+		return t.Raw
+	}
+	return FormatTime(t.Time)
+}
+
+// UnmarshalXML reads the timestamp, invalid timestamps
+// do not fail the parsing, see GpxTime.
+func (t *GpxTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw string
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+	t.setRaw(strings.TrimSpace(raw))
+	return nil
+}
+
+// MarshalXML writes the lexical form returned by String.
+func (t *GpxTime) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(t.String(), start)
+}
+
+// FormatTime formats time as xsd:dateTime used by GPX,
+// with fractional seconds only when they are not zero.
+func FormatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+var gpxTimePattern = regexp.MustCompile(`^(-?\d{4,})-(\d{2})-(\d{2})[Tt ](\d{2}):(\d{2}):(\d{2})(?:[.,](\d+))?\s*([Zz]|[+-]\d{2}(?::?\d{2})?)?$`)
+
+// Gpx uses xsd:dateTime format for time, this function
+// accepts all its variants (fractional seconds, zone
+// offsets, no zone) and common deviations of devices
+// (offsets without colon, space instead of T).
+// Timestamps without zone are read in DefaultTimeLocation.
+func ParseGpxTimeStr(timeStr string) (time.Time, error) {
+	return ParseGpxTimeStrIn(timeStr, DefaultTimeLocation)
+}
+
+// ParseGpxTimeStrIn is like ParseGpxTimeStr, but timestamps
+// without zone are read in given location.
+func ParseGpxTimeStrIn(timeStr string, location *time.Location) (time.Time, error) {
+	match := gpxTimePattern.FindStringSubmatch(strings.TrimSpace(timeStr))
+	if match == nil {
+		return time.Time{}, fmt.Errorf("Time %q is not in xsd:dateTime format", timeStr)
+	}
+
+	year, err := strconv.Atoi(match[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Year of time %q is out of range", timeStr)
+	}
+	month, _ := strconv.Atoi(match[2])
+	day, _ := strconv.Atoi(match[3])
+	hour, _ := strconv.Atoi(match[4])
+	minute, _ := strconv.Atoi(match[5])
+	second, _ := strconv.Atoi(match[6])
+
+	if month < 1 || month > 12 || day < 1 || day > daysInMonth(year, time.Month(month)) {
+		return time.Time{}, fmt.Errorf("Date of time %q is invalid", timeStr)
+	}
+	// 24:00:00 is allowed and means the start of the next day.
+	if hour == 24 && (minute != 0 || second != 0 || strings.Trim(match[7], "0") != "") ||
+		hour > 24 || minute > 59 || second > 59 {
+		return time.Time{}, fmt.Errorf("Time of day of time %q is invalid", timeStr)
+	}
+
+	nanoseconds := 0
+	if fraction := match[7]; fraction != "" {
+		fraction = (fraction + "000000000")[:9]
+		nanoseconds, _ = strconv.Atoi(fraction)
+	}
+
+	zone := location
+	if offset := match[8]; offset == "Z" || offset == "z" {
+		zone = time.UTC
+	} else if offset != "" {
+		digits := strings.ReplaceAll(offset[1:], ":", "")
+		hours, _ := strconv.Atoi(digits[:2])
+		minutes := 0
+		if len(digits) == 4 {
+			minutes, _ = strconv.Atoi(digits[2:])
+		}
+		if hours > 14 || minutes > 59 {
+			return time.Time{}, fmt.Errorf("Zone of time %q is invalid", timeStr)
+		}
+		seconds := hours*3600 + minutes*60
+		if offset[0] == '-' {
+			seconds = -seconds
+		}
+		zone = time.FixedZone("", seconds)
+	}
+
+	return time.Date(year, time.Month(month), day, hour, minute, second, nanoseconds, zone), nil
+}
+
+func daysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -10,11 +10,23 @@ func (p *EmailType) GetEmailStr() string {
 	return p.IdAttr + "@" + p.DomainAttr
 }
 
-// Gpx uses ISO 8601 format for time, but
-// with a small difference, this function
-// accomodates for this.
-func ParseGpxTimeStr(timeStr string) (time.Time, error) {
-	return time.Parse("2006-01-02T15:04:05Z0700", timeStr)
+// Sorts points together with their timestamps.
+type timeSorter struct {
+	points     []CoordConvertible
+	timestamps []time.Time
+}
+
+func (s timeSorter) Len() int {
+	return len(s.points)
+}
+
+func (s timeSorter) Less(i, j int) bool {
+	return s.timestamps[i].Before(s.timestamps[j])
+}
+
+func (s timeSorter) Swap(i, j int) {
+	s.points[i], s.points[j] = s.points[j], s.points[i]
+	s.timestamps[i], s.timestamps[j] = s.timestamps[j], s.timestamps[i]
 }
 
 func pointsToTimeSlice(points *[]CoordConvertible) (*[]time.Time, error) {
@@ -37,9 +49,7 @@ func SortByTime(points *[]CoordConvertible) error {
 	if err != nil {
 		return err
 	}
-	sort.Slice(points, func(i, j int) bool {
-		return (*timestamps)[i].Before((*timestamps)[j])
-	})
+	sort.Stable(timeSorter{points: *points, timestamps: *timestamps})
 	return nil
 }
 
@@ -64,6 +74,21 @@ func TotalLength(track *[]CoordConvertible, algorithm DistanceAlgorithm) float64
 	return distance
 }
 
+// TotalLengthChecked is like TotalLength, but returns error
+// if latitude or longitude of any element is invalid,
+// see CheckedCoordConvertible.
+func TotalLengthChecked(track *[]CoordConvertible, algorithm DistanceAlgorithm) (float64, error) {
+	distance := 0.0
+	for i := 1; i < len(*track); i++ {
+		step, err := distanceBetween((*track)[i-1], (*track)[i], algorithm)
+		if err != nil {
+			return 0, err
+		}
+		distance += step
+	}
+	return distance, nil
+}
+
 // TotalTime returns the total time between the first and last
 // element in a slice of CoordConvertible (WptType, PtType).
 //
@@ -103,9 +128,13 @@ func TotalTime(points *[]CoordConvertible) (time.Duration, error) {
 // Result is in m/s.
 // Returns error if the two points have the same timestamp as the
 // denominator would be 0.
-// Returns error if timestamp is not defined or could not be parsed.
+// Returns error if timestamp is not defined or could not be parsed
+// or if latitude or longitude of any point is invalid.
 func VelocityBetweenPoints(p1 CoordConvertible, p2 CoordConvertible, algorithm DistanceAlgorithm) (float64, error) {
-	distance := algorithm(p1.ToCoordinates(), p2.ToCoordinates())
+	distance, err := distanceBetween(p1, p2, algorithm)
+	if err != nil {
+		return 0, err
+	}
 	p1Time, err := p1.getTimestamp()
 	if err != nil {
 		return 0, err
@@ -133,14 +162,18 @@ func VelocityBetweenPoints(p1 CoordConvertible, p2 CoordConvertible, algorithm D
 //
 // Returns error if the two points have the same timestamp as the
 // denominator would be 0.
-// Returns error if any timestamp is not defined or could not be parsed.
+// Returns error if any timestamp is not defined or could not be parsed
+// or if latitude or longitude of any point is invalid.
 func AverageVelocity(points *[]CoordConvertible, algorithm DistanceAlgorithm) (float64, error) {
 	totalTime, err := TotalTime(points)
 	if err != nil {
 		return 0, err
 	}
 
-	totalDistance := TotalLength(points, algorithm)
+	totalDistance, err := TotalLengthChecked(points, algorithm)
+	if err != nil {
+		return 0, err
+	}
 
 	if totalTime == 0.0 {
 		return 0, fmt.Errorf("The time difference between start and end points is zero")
@@ -148,3 +181,26 @@ func AverageVelocity(points *[]CoordConvertible, algorithm DistanceAlgorithm) (f
 
 	return totalDistance / totalTime.Seconds(), nil
 }
+
+// Return distance between points calculated by algorithm
+// or error if latitude or longitude of any point is invalid.
+func distanceBetween(p1, p2 CoordConvertible, algorithm DistanceAlgorithm) (float64, error) {
+	c1, err := checkedCoordinates(p1)
+	if err != nil {
+		return 0, err
+	}
+	c2, err := checkedCoordinates(p2)
+	if err != nil {
+		return 0, err
+	}
+	return algorithm(c1, c2), nil
+}
+
+// Return points as slice of CoordConvertible for functions of this file.
+func wptConvertibles(points []*WptType) []CoordConvertible {
+	convertibles := make([]CoordConvertible, 0, len(points))
+	for _, wpt := range points {
+		convertibles = append(convertibles, wpt)
+	}
+	return convertibles
+}
@@ -0,0 +1,585 @@
+package gpx_tools
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Gpx11Namespace is the XML namespace of GPX version 1.1.
+const Gpx11Namespace = "http://www.topografix.com/GPX/1/1"
+
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// ValidationError is a single violation of the GPX 1.1 schema.
+//
+// Path points to the offending element in XPath like notation,
+// e.g. /gpx/trk[1]/trkseg[2]/trkpt[10]/time.
+// Line is the line in the document or 0 if it is not known.
+type ValidationError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s (line %d): %s", e.Path, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// The rules below are taken from resources/gpx.xsd.xml.
+
+// Checks lexical value of a simple type and
+// returns description of the problem or empty string.
+type simpleType func(value string) string
+
+type schemaAttr struct {
+	name     string
+	required bool
+	check    simpleType
+}
+
+type schemaChild struct {
+	name    string
+	many    bool
+	complex *schemaType
+	check   simpleType
+}
+
+type schemaType struct {
+	attrs    []schemaAttr
+	children []schemaChild
+	// Content is not validated (extensions).
+	any bool
+}
+
+var (
+	extensionsSchema = &schemaType{any: true}
+	emailSchema      = &schemaType{attrs: []schemaAttr{
+		{"id", true, checkString},
+		{"domain", true, checkString},
+	}}
+	linkSchema = &schemaType{
+		attrs: []schemaAttr{{"href", true, checkString}},
+		children: []schemaChild{
+			{name: "text", check: checkString},
+			{name: "type", check: checkString},
+		},
+	}
+	personSchema = &schemaType{children: []schemaChild{
+		{name: "name", check: checkString},
+		{name: "email", complex: emailSchema},
+		{name: "link", complex: linkSchema},
+	}}
+	copyrightSchema = &schemaType{
+		attrs: []schemaAttr{{"author", true, checkString}},
+		children: []schemaChild{
+			{name: "year", check: checkYear},
+			{name: "license", check: checkString},
+		},
+	}
+	boundsSchema = &schemaType{attrs: []schemaAttr{
+		{"minlat", true, checkLatitude},
+		{"minlon", true, checkLongitude},
+		{"maxlat", true, checkLatitude},
+		{"maxlon", true, checkLongitude},
+	}}
+	metadataSchema = &schemaType{children: []schemaChild{
+		{name: "name", check: checkString},
+		{name: "desc", check: checkString},
+		{name: "author", complex: personSchema},
+		{name: "copyright", complex: copyrightSchema},
+		{name: "link", many: true, complex: linkSchema},
+		{name: "time", check: checkDateTime},
+		{name: "keywords", check: checkString},
+		{name: "bounds", complex: boundsSchema},
+		{name: "extensions", complex: extensionsSchema},
+	}}
+	wptSchema = &schemaType{
+		attrs: []schemaAttr{
+			{"lat", true, checkLatitude},
+			{"lon", true, checkLongitude},
+		},
+		children: []schemaChild{
+			{name: "ele", check: checkDecimal},
+			{name: "time", check: checkDateTime},
+			{name: "magvar", check: checkDegrees},
+			{name: "geoidheight", check: checkDecimal},
+			{name: "name", check: checkString},
+			{name: "cmt", check: checkString},
+			{name: "desc", check: checkString},
+			{name: "src", check: checkString},
+			{name: "link", many: true, complex: linkSchema},
+			{name: "sym", check: checkString},
+			{name: "type", check: checkString},
+			{name: "fix", check: checkFix},
+			{name: "sat", check: checkNonNegativeInteger},
+			{name: "hdop", check: checkDecimal},
+			{name: "vdop", check: checkDecimal},
+			{name: "pdop", check: checkDecimal},
+			{name: "ageofdgpsdata", check: checkDecimal},
+			{name: "dgpsid", check: checkDgpsStation},
+			{name: "extensions", complex: extensionsSchema},
+		},
+	}
+	rteSchema = &schemaType{children: []schemaChild{
+		{name: "name", check: checkString},
+		{name: "cmt", check: checkString},
+		{name: "desc", check: checkString},
+		{name: "src", check: checkString},
+		{name: "link", many: true, complex: linkSchema},
+		{name: "number", check: checkNonNegativeInteger},
+		{name: "type", check: checkString},
+		{name: "extensions", complex: extensionsSchema},
+		{name: "rtept", many: true, complex: wptSchema},
+	}}
+	trksegSchema = &schemaType{children: []schemaChild{
+		{name: "trkpt", many: true, complex: wptSchema},
+		{name: "extensions", complex: extensionsSchema},
+	}}
+	trkSchema = &schemaType{children: []schemaChild{
+		{name: "name", check: checkString},
+		{name: "cmt", check: checkString},
+		{name: "desc", check: checkString},
+		{name: "src", check: checkString},
+		{name: "link", many: true, complex: linkSchema},
+		{name: "number", check: checkNonNegativeInteger},
+		{name: "type", check: checkString},
+		{name: "extensions", complex: extensionsSchema},
+		{name: "trkseg", many: true, complex: trksegSchema},
+	}}
+	gpxSchema = &schemaType{
+		attrs: []schemaAttr{
+			{"version", true, checkVersion},
+			{"creator", true, checkString},
+		},
+		children: []schemaChild{
+			{name: "metadata", complex: metadataSchema},
+			{name: "wpt", many: true, complex: wptSchema},
+			{name: "rte", many: true, complex: rteSchema},
+			{name: "trk", many: true, complex: trkSchema},
+			{name: "extensions", complex: extensionsSchema},
+		},
+	}
+)
+
+var (
+	decimalPattern  = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+	integerPattern  = regexp.MustCompile(`^[+-]?\d+$`)
+	dateTimePattern = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+	yearPattern     = regexp.MustCompile(`^-?\d{4,}(Z|[+-]\d{2}:\d{2})?$`)
+)
+
+func checkString(value string) string {
+	return ""
+}
+
+func checkVersion(value string) string {
+	if value != "1.1" {
+		return fmt.Sprintf("version is %q, must be \"1.1\"", value)
+	}
+	return ""
+}
+
+func checkDecimal(value string) string {
+	if !decimalPattern.MatchString(value) {
+		return fmt.Sprintf("%q is not a decimal number", value)
+	}
+	return ""
+}
+
+func checkLatitude(value string) string {
+	if problem := checkDecimal(value); problem != "" {
+		return problem
+	}
+	latitude, _ := strconv.ParseFloat(value, 64)
+	return latitudeProblem(latitude)
+}
+
+func checkLongitude(value string) string {
+	if problem := checkDecimal(value); problem != "" {
+		return problem
+	}
+	longitude, _ := strconv.ParseFloat(value, 64)
+	return longitudeProblem(longitude)
+}
+
+func checkDegrees(value string) string {
+	if problem := checkDecimal(value); problem != "" {
+		return problem
+	}
+	degrees, _ := strconv.ParseFloat(value, 64)
+	return degreesProblem(degrees)
+}
+
+func checkNonNegativeInteger(value string) string {
+	if !integerPattern.MatchString(value) {
+		return fmt.Sprintf("%q is not an integer", value)
+	}
+	if strings.HasPrefix(value, "-") && strings.Trim(value, "-0") != "" {
+		return fmt.Sprintf("%s is negative", value)
+	}
+	return ""
+}
+
+func checkDgpsStation(value string) string {
+	if !integerPattern.MatchString(value) {
+		return fmt.Sprintf("%q is not an integer", value)
+	}
+	station, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Sprintf("%q is out of range [0, 1023]", value)
+	}
+	return dgpsStationProblem(station)
+}
+
+func checkFix(value string) string {
+	return fixProblem(value)
+}
+
+func checkDateTime(value string) string {
+	if !dateTimePattern.MatchString(value) {
+		return fmt.Sprintf("%q is not a valid xsd:dateTime", value)
+	}
+	// The parser rejects values like month 13 or hour 25.
+	if _, err := ParseGpxTimeStrIn(value, time.UTC); err != nil {
+		return fmt.Sprintf("%q is not a valid xsd:dateTime, it has no such date or time of day", value)
+	}
+	return ""
+}
+
+func checkYear(value string) string {
+	if !yearPattern.MatchString(value) {
+		return fmt.Sprintf("%q is not a valid xsd:gYear", value)
+	}
+	return ""
+}
+
+func latitudeProblem(latitude float64) string {
+	if !(latitude >= -90 && latitude <= 90) {
+		return fmt.Sprintf("latitude %v is out of range [-90, 90]", latitude)
+	}
+	return ""
+}
+
+func longitudeProblem(longitude float64) string {
+	if !(longitude >= -180 && longitude < 180) {
+		return fmt.Sprintf("longitude %v is out of range [-180, 180)", longitude)
+	}
+	return ""
+}
+
+func degreesProblem(degrees float64) string {
+	if !(degrees >= 0 && degrees < 360) {
+		return fmt.Sprintf("degrees %v are out of range [0, 360)", degrees)
+	}
+	return ""
+}
+
+func dgpsStationProblem(station int) string {
+	if station < 0 || station > 1023 {
+		return fmt.Sprintf("DGPS station %d is out of range [0, 1023]", station)
+	}
+	return ""
+}
+
+func fixProblem(fix string) string {
+	switch fix {
+	case "none", "2d", "3d", "dgps", "pps":
+		return ""
+	}
+	return fmt.Sprintf("fix %q is not one of none, 2d, 3d, dgps, pps", fix)
+}
+
+// ValidateBytes checks the XML document against the GPX 1.1 schema
+// and returns all found violations with line numbers.
+// Malformed XML is reported as a violation too and stops the validation.
+//
+// Content of extensions is not validated.
+func ValidateBytes(data []byte) []ValidationError {
+	v := byteValidator{decoder: xml.NewDecoder(bytes.NewReader(data))}
+	for {
+		token, err := v.decoder.Token()
+		if err == io.EOF {
+			v.report("/", "document has no gpx element")
+			return v.errors
+		}
+		if err != nil {
+			v.syntaxError("/", err)
+			return v.errors
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			path := "/" + start.Name.Local
+			if start.Name.Local != "gpx" {
+				v.report(path, "root element must be <gpx>")
+				return v.errors
+			}
+			if start.Name.Space != Gpx11Namespace {
+				v.report(path, fmt.Sprintf("gpx element is not in namespace %s", Gpx11Namespace))
+			}
+			if err := v.element(start, gpxSchema, path); err != nil {
+				v.syntaxError(path, err)
+			}
+			return v.errors
+		}
+	}
+}
+
+type byteValidator struct {
+	decoder *xml.Decoder
+	errors  []ValidationError
+}
+
+func (v *byteValidator) report(path, message string) {
+	line, _ := v.decoder.InputPos()
+	v.errors = append(v.errors, ValidationError{Path: path, Line: line, Message: message})
+}
+
+func (v *byteValidator) syntaxError(path string, err error) {
+	if syntaxErr, ok := err.(*xml.SyntaxError); ok {
+		v.errors = append(v.errors, ValidationError{Path: path, Line: syntaxErr.Line, Message: syntaxErr.Msg})
+		return
+	}
+	v.report(path, err.Error())
+}
+
+func (v *byteValidator) element(start xml.StartElement, schema *schemaType, path string) error {
+	v.attributes(start, schema, path)
+	if schema.any {
+		return v.decoder.Skip()
+	}
+
+	position := -1
+	counts := map[string]int{}
+	for {
+		token, err := v.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			counts[name]++
+			index := -1
+			for i, child := range schema.children {
+				if child.name == name {
+					index = i
+					break
+				}
+			}
+			if index < 0 || t.Name.Space != start.Name.Space {
+				v.report(path+"/"+name, fmt.Sprintf("unexpected element <%s>", name))
+				if err := v.decoder.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			child := schema.children[index]
+			childPath := path + "/" + name
+			if child.many {
+				childPath = fmt.Sprintf("%s[%d]", childPath, counts[name])
+			}
+			if index < position {
+				v.report(childPath, fmt.Sprintf("element <%s> is not allowed after <%s>", name, schema.children[position].name))
+			} else if index == position && !child.many {
+				v.report(childPath, fmt.Sprintf("element <%s> may occur only once", name))
+			}
+			if index > position {
+				position = index
+			}
+
+			if child.complex != nil {
+				err = v.element(t, child.complex, childPath)
+			} else {
+				err = v.simple(child.check, childPath)
+			}
+			if err != nil {
+				return err
+			}
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				v.report(path, "unexpected text content")
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func (v *byteValidator) attributes(start xml.StartElement, schema *schemaType, path string) {
+	present := map[string]bool{}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Space == xsiNamespace ||
+			(attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		known := false
+		for _, declared := range schema.attrs {
+			if attr.Name.Space == "" && attr.Name.Local == declared.name {
+				known = true
+				present[declared.name] = true
+				if problem := declared.check(attr.Value); problem != "" {
+					v.report(path+"/@"+declared.name, problem)
+				}
+			}
+		}
+		if !known {
+			v.report(path+"/@"+attr.Name.Local, fmt.Sprintf("unexpected attribute %s", attr.Name.Local))
+		}
+	}
+	for _, declared := range schema.attrs {
+		if declared.required && !present[declared.name] {
+			v.report(path, fmt.Sprintf("required attribute %s is missing", declared.name))
+		}
+	}
+}
+
+// Reads text content of element with simple type.
+func (v *byteValidator) simple(check simpleType, path string) error {
+	var text strings.Builder
+	for {
+		token, err := v.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			v.report(path+"/"+t.Name.Local, fmt.Sprintf("unexpected element <%s>", t.Name.Local))
+			if err := v.decoder.Skip(); err != nil {
+				return err
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if problem := check(strings.TrimSpace(text.String())); problem != "" {
+				v.report(path, problem)
+			}
+			return nil
+		}
+	}
+}
+
+// Validate checks values of the Gpx struct against the GPX 1.1 schema
+// and returns all found violations.
+// Element order is given by the struct, so only values
+// and required attributes are checked and lines are always 0.
+func Validate(gpx Gpx) []ValidationError {
+	v := &structValidator{}
+	if gpx == nil {
+		v.report("/gpx", "document is nil")
+		return v.errors
+	}
+	v.check("/gpx/@version", checkVersion(gpx.VersionAttr))
+	v.required("/gpx", "creator", gpx.CreatorAttr)
+
+	if metadata := gpx.Metadata; metadata != nil {
+		path := "/gpx/metadata"
+		if author := metadata.Author; author != nil {
+			if author.Email != nil {
+				v.required(path+"/author/email", "id", author.Email.IdAttr)
+				v.required(path+"/author/email", "domain", author.Email.DomainAttr)
+			}
+			if author.Link != nil {
+				v.required(path+"/author/link", "href", author.Link.HrefAttr)
+			}
+		}
+		if copyright := metadata.Copyright; copyright != nil {
+			v.required(path+"/copyright", "author", copyright.AuthorAttr)
+			if copyright.Year != "" {
+				v.check(path+"/copyright/year", checkYear(copyright.Year))
+			}
+		}
+		v.links(path, metadata.Link)
+		if metadata.Time != nil {
+			v.check(path+"/time", checkDateTime(metadata.Time.String()))
+		}
+		if bounds := metadata.Bounds; bounds != nil {
+			v.check(path+"/bounds/@minlat", latitudeProblem(bounds.MinlatAttr))
+			v.check(path+"/bounds/@minlon", longitudeProblem(bounds.MinlonAttr))
+			v.check(path+"/bounds/@maxlat", latitudeProblem(bounds.MaxlatAttr))
+			v.check(path+"/bounds/@maxlon", longitudeProblem(bounds.MaxlonAttr))
+		}
+	}
+
+	for i, wpt := range gpx.Wpt {
+		v.wpt(fmt.Sprintf("/gpx/wpt[%d]", i+1), wpt)
+	}
+	for i, rte := range gpx.Rte {
+		path := fmt.Sprintf("/gpx/rte[%d]", i+1)
+		v.links(path, rte.Link)
+		if rte.Number != nil && *rte.Number < 0 {
+			v.report(path+"/number", fmt.Sprintf("%d is negative", *rte.Number))
+		}
+		for j, rtept := range rte.Rtept {
+			v.wpt(fmt.Sprintf("%s/rtept[%d]", path, j+1), rtept)
+		}
+	}
+	for i, trk := range gpx.Trk {
+		path := fmt.Sprintf("/gpx/trk[%d]", i+1)
+		v.links(path, trk.Link)
+		if trk.Number != nil && *trk.Number < 0 {
+			v.report(path+"/number", fmt.Sprintf("%d is negative", *trk.Number))
+		}
+		for j, trkseg := range trk.Trkseg {
+			for k, trkpt := range trkseg.Trkpt {
+				v.wpt(fmt.Sprintf("%s/trkseg[%d]/trkpt[%d]", path, j+1, k+1), trkpt)
+			}
+		}
+	}
+	return v.errors
+}
+
+type structValidator struct {
+	errors []ValidationError
+}
+
+func (v *structValidator) report(path, message string) {
+	v.errors = append(v.errors, ValidationError{Path: path, Message: message})
+}
+
+func (v *structValidator) check(path, problem string) {
+	if problem != "" {
+		v.report(path, problem)
+	}
+}
+
+func (v *structValidator) required(path, name, value string) {
+	if value == "" {
+		v.report(path, fmt.Sprintf("required attribute %s is missing", name))
+	}
+}
+
+func (v *structValidator) links(path string, links []*LinkType) {
+	for i, link := range links {
+		v.required(fmt.Sprintf("%s/link[%d]", path, i+1), "href", link.HrefAttr)
+	}
+}
+
+func (v *structValidator) wpt(path string, wpt *WptType) {
+	v.check(path+"/@lat", latitudeProblem(wpt.LatAttr))
+	v.check(path+"/@lon", longitudeProblem(wpt.LonAttr))
+	if wpt.Time != nil {
+		v.check(path+"/time", checkDateTime(wpt.Time.String()))
+	}
+	if wpt.Magvar != nil {
+		v.check(path+"/magvar", degreesProblem(*wpt.Magvar))
+	}
+	v.links(path, wpt.Link)
+	if wpt.Fix != "" {
+		v.check(path+"/fix", fixProblem(wpt.Fix))
+	}
+	if wpt.Sat != nil && *wpt.Sat < 0 {
+		v.report(path+"/sat", fmt.Sprintf("%d is negative", *wpt.Sat))
+	}
+	if wpt.Dgpsid != nil {
+		v.check(path+"/dgpsid", dgpsStationProblem(*wpt.Dgpsid))
+	}
+}
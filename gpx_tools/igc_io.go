@@ -0,0 +1,334 @@
+package gpx_tools
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IgcNamespace is the namespace of extensions holding IGC data
+// which have no GPX field.
+const IgcNamespace = "urn:gpx-tools:igc"
+
+// Prefix declared for IgcNamespace by ParseIgc.
+const igcPrefix = "igc"
+
+// Length of B record without extensions.
+const igcFixLength = 35
+
+// IgcAltitudes are altitudes of IGC fix in meters, missing values are nil.
+type IgcAltitudes struct {
+	// Pressure altitude referenced to ISA sea level pressure 1013.25 hPa.
+	Pressure *float64
+	// GNSS altitude above the WGS84 ellipsoid.
+	Gnss *float64
+}
+
+// ParseIgcFile parses an IGC flight log, see ParseIgc.
+func ParseIgcFile(path string) (gpx Gpx, err error) {
+	igcFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer igcFile.Close()
+
+	return ParseIgc(igcFile)
+}
+
+// ParseIgc parses an IGC flight log into a track of B records.
+//
+// Pilot of the H records is the author of metadata, glider type is
+// the name of the track and glider ID its comment, competition ID and
+// class are kept in extensions of the track. Time of metadata is
+// the time of the first fix.
+//
+// Date of the HFDTE record is advanced when time of fix rolls over
+// UTC midnight. Fix is "3d" for valid fixes and "2d" otherwise.
+// Elevation is GNSS altitude of valid fixes, pressure altitude when
+// it is missing. Both altitudes are kept in extensions, see
+// GetIgcAltitudes, together with values of fix extensions declared
+// by the I record, named by their three letter codes. SIU extension
+// is stored as number of satellites.
+func ParseIgc(r io.Reader) (gpx Gpx, err error) {
+	parser := &igcParser{gpx: NewGpx(DefaultCreator), trk: &TrkType{}}
+	parser.gpx.Metadata = &MetadataType{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if err := parser.record(strings.TrimRight(scanner.Text(), " \r")); err != nil {
+			return nil, fmt.Errorf("Line %d: %v", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(parser.trkseg.Trkpt) > 0 {
+		parser.trk.Trkseg = []*TrksegType{&parser.trkseg}
+		parser.gpx.Trk = append(parser.gpx.Trk, parser.trk)
+		parser.gpx.Metadata.Time = parser.trkseg.Trkpt[0].Time
+	}
+	if parser.gpx.Metadata.Author == nil && parser.gpx.Metadata.Time == nil {
+		parser.gpx.Metadata = nil
+	}
+	return parser.gpx, nil
+}
+
+// Fix extension declared by I record, positions are 1-based
+// and inclusive like in the record.
+type igcExtension struct {
+	start, end int
+	code       string
+}
+
+type igcParser struct {
+	gpx    *GpxType
+	trk    *TrkType
+	trkseg TrksegType
+
+	extensions []igcExtension
+	date       time.Time
+	hasDate    bool
+	previous   time.Duration
+}
+
+func (p *igcParser) record(line string) error {
+	if line == "" {
+		return nil
+	}
+	switch line[0] {
+	case 'H':
+		return p.header(line)
+	case 'I':
+		return p.fixExtensions(line)
+	case 'B':
+		return p.fix(line)
+	}
+	return nil
+}
+
+// Parse H record, e.g. "HFPLTPILOTINCHARGE:John Doe".
+// Long names of the fields are optional.
+func (p *igcParser) header(line string) error {
+	if len(line) < 5 {
+		return nil
+	}
+	code := line[2:5]
+	value := line[5:]
+	if colon := strings.IndexByte(value, ':'); colon >= 0 {
+		value = value[colon+1:]
+	}
+	value = strings.TrimSpace(value)
+
+	switch code {
+	case "DTE":
+		// DDMMYY, optionally followed by the flight number.
+		if len(value) < 6 {
+			return fmt.Errorf("Invalid date %q", value)
+		}
+		date, err := time.Parse("020106", value[:6])
+		if err != nil {
+			return fmt.Errorf("Invalid date %q", value)
+		}
+		p.date, p.hasDate = date, true
+	case "PLT":
+		if value != "" {
+			p.gpx.Metadata.Author = &PersonType{Name: value}
+		}
+	case "GTY":
+		p.trk.Name = value
+	case "GID":
+		p.trk.Cmt = value
+	case "CID":
+		return p.trackExtension("competitionId", value)
+	case "CCL":
+		return p.trackExtension("competitionClass", value)
+	}
+	return nil
+}
+
+func (p *igcParser) trackExtension(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	if p.trk.Extensions == nil {
+		p.trk.Extensions = p.gpx.NewExtensions()
+	}
+	p.gpx.declareNamespace(igcPrefix, IgcNamespace)
+	return p.trk.Extensions.SetElement(NewExtensionElement(IgcNamespace, name, value))
+}
+
+// Parse I record, e.g. "I023638FXA3940SIU", which declares
+// number of extensions and their positions in B records.
+func (p *igcParser) fixExtensions(line string) error {
+	if len(line) < 3 {
+		return fmt.Errorf("Invalid I record %q", line)
+	}
+	count, err := strconv.Atoi(line[1:3])
+	if err != nil || len(line) < 3+7*count {
+		return fmt.Errorf("Invalid I record %q", line)
+	}
+	p.extensions = make([]igcExtension, count)
+	for i := range p.extensions {
+		field := line[3+7*i : 10+7*i]
+		start, errStart := strconv.Atoi(field[0:2])
+		end, errEnd := strconv.Atoi(field[2:4])
+		if errStart != nil || errEnd != nil || start < igcFixLength+1 || end < start {
+			return fmt.Errorf("Invalid extension %q of I record", field)
+		}
+		p.extensions[i] = igcExtension{start: start, end: end, code: field[4:7]}
+	}
+	return nil
+}
+
+// Parse B record, e.g. "B1101355206343N00006198WA0058700558" with
+// time HHMMSS, latitude DDMMmmm, longitude DDDMMmmm, validity and
+// pressure and GNSS altitudes, followed by declared extensions.
+func (p *igcParser) fix(line string) error {
+	if len(line) < igcFixLength {
+		return fmt.Errorf("Invalid B record %q", line)
+	}
+	if !p.hasDate {
+		return fmt.Errorf("B record before HFDTE date record")
+	}
+
+	hours, errHours := strconv.Atoi(line[1:3])
+	minutes, errMinutes := strconv.Atoi(line[3:5])
+	seconds, errSeconds := strconv.Atoi(line[5:7])
+	if errHours != nil || errMinutes != nil || errSeconds != nil || hours > 23 || minutes > 59 || seconds > 59 {
+		return fmt.Errorf("Invalid time %q", line[1:7])
+	}
+	timeOfDay := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+	if len(p.trkseg.Trkpt) > 0 && timeOfDay < p.previous-12*time.Hour {
+		p.date = p.date.AddDate(0, 0, 1)
+	}
+	p.previous = timeOfDay
+
+	lat, err := igcDegrees(line[7:15], 2, "N", "S")
+	if err != nil {
+		return err
+	}
+	lon, err := igcDegrees(line[15:24], 3, "E", "W")
+	if err != nil {
+		return err
+	}
+	wpt := &WptType{LatAttr: lat, LonAttr: lon, Time: NewGpxTime(p.date.Add(timeOfDay))}
+
+	valid := false
+	switch line[24] {
+	case 'A':
+		valid = true
+		wpt.Fix = "3d"
+	case 'V':
+		wpt.Fix = "2d"
+	default:
+		return fmt.Errorf("Invalid fix validity %q", line[24:25])
+	}
+
+	pressure, err := igcAltitude(line[25:30])
+	if err != nil {
+		return err
+	}
+	gnss, err := igcAltitude(line[30:35])
+	if err != nil {
+		return err
+	}
+	// Loggers write zero GNSS altitude when it is not available.
+	if gnss != nil && *gnss == 0 && !valid {
+		gnss = nil
+	}
+	if valid && gnss != nil {
+		wpt.Ele = Optional(*gnss)
+	} else if pressure != nil {
+		wpt.Ele = Optional(*pressure)
+	}
+
+	wpt.Extensions = p.gpx.NewExtensions()
+	p.gpx.declareNamespace(igcPrefix, IgcNamespace)
+	if pressure != nil {
+		if err := wpt.Extensions.AddElement(NewExtensionElement(IgcNamespace, "pressureAltitude", formatFloat(*pressure))); err != nil {
+			return err
+		}
+	}
+	if gnss != nil {
+		if err := wpt.Extensions.AddElement(NewExtensionElement(IgcNamespace, "gnssAltitude", formatFloat(*gnss))); err != nil {
+			return err
+		}
+	}
+	for _, extension := range p.extensions {
+		if extension.end > len(line) {
+			continue
+		}
+		value := strings.TrimSpace(line[extension.start-1 : extension.end])
+		if value == "" {
+			continue
+		}
+		if extension.code == "SIU" {
+			if sat, err := strconv.Atoi(value); err == nil {
+				wpt.Sat = &sat
+			}
+		}
+		if err := wpt.Extensions.AddElement(NewExtensionElement(IgcNamespace, extension.code, value)); err != nil {
+			return err
+		}
+	}
+	if wpt.Extensions.IsEmpty() {
+		wpt.Extensions = nil
+	}
+
+	p.trkseg.Trkpt = append(p.trkseg.Trkpt, wpt)
+	return nil
+}
+
+// GetIgcAltitudes returns pressure and GNSS altitudes
+// stored in extensions of the point by ParseIgc.
+func (wpt *WptType) GetIgcAltitudes() (altitudes IgcAltitudes, err error) {
+	if wpt.Extensions == nil {
+		return altitudes, nil
+	}
+	pressure, err := wpt.Extensions.GetElement(IgcNamespace, "pressureAltitude")
+	if err != nil {
+		return altitudes, err
+	}
+	if pressure != nil {
+		altitudes.Pressure = parseSensorFloat(pressure.Text)
+	}
+	gnss, err := wpt.Extensions.GetElement(IgcNamespace, "gnssAltitude")
+	if err != nil {
+		return altitudes, err
+	}
+	if gnss != nil {
+		altitudes.Gnss = parseSensorFloat(gnss.Text)
+	}
+	return altitudes, nil
+}
+
+// Parse coordinate with given number of digits of degrees,
+// followed by minutes with three decimals and hemisphere.
+func igcDegrees(value string, digits int, positive, negative string) (float64, error) {
+	degrees, errDegrees := strconv.Atoi(value[:digits])
+	thousandths, errMinutes := strconv.Atoi(value[digits : digits+5])
+	if errDegrees != nil || errMinutes != nil || thousandths >= 60000 {
+		return 0, fmt.Errorf("Invalid coordinate %q", value)
+	}
+	number := float64(degrees) + float64(thousandths)/60000
+	switch value[digits+5:] {
+	case positive:
+		return number, nil
+	case negative:
+		return -number, nil
+	}
+	return 0, fmt.Errorf("Invalid hemisphere %q", value[digits+5:])
+}
+
+// Parse altitude of five characters, e.g. "00587" or "-0012".
+func igcAltitude(value string) (*float64, error) {
+	altitude, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid altitude %q", value)
+	}
+	return Optional(float64(altitude)), nil
+}
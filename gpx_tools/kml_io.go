@@ -0,0 +1,428 @@
+package gpx_tools
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Names of folders written by ToKml, lines in the routes
+// folder are read back as routes instead of tracks.
+const (
+	kmlWaypointsFolder = "Waypoints"
+	kmlRoutesFolder    = "Routes"
+	kmlTracksFolder    = "Tracks"
+)
+
+// KmlOptions configure WriteKml and ToKml.
+// Zero value writes plain KML without indentation.
+type KmlOptions struct {
+	// Indent is used for every level of nested elements,
+	// empty string writes the whole document on one line.
+	Indent string
+	// Kmz writes zip archive with the document stored as doc.kml.
+	Kmz bool
+	// LineStrings writes all tracks as LineString, by default tracks
+	// with timestamps of all points are written as gx:Track.
+	LineStrings bool
+}
+
+// ParseKmlFile parses a .kml or .kmz file and converts it to Gpx,
+// see KmlType.ToGpx.
+func ParseKmlFile(path string) (gpx Gpx, err error) {
+	kmlFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer kmlFile.Close()
+
+	return ParseKml(kmlFile)
+}
+
+// ParseKml reads the whole KML document from the reader
+// and parses it like ParseKmlBytes. KMZ archives are detected
+// by their first bytes and the first .kml file in them is read.
+func ParseKml(r io.Reader) (gpx Gpx, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		entry, err := openZipEntry(data, ".kml")
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return ParseKmlBytes(data)
+}
+
+// ParseKmlBytes parses KML document and converts it to Gpx.
+func ParseKmlBytes(data []byte) (gpx Gpx, err error) {
+	var kml KmlType
+	if err := xml.Unmarshal(data, &kml); err != nil {
+		return nil, err
+	}
+	return kml.ToGpx()
+}
+
+// ToGpx converts KML document to GPX 1.1.
+//
+// Name and description of the document become metadata. Points
+// become waypoints, lines and gx:Track elements become tracks with
+// a segment for every line of a placemark. Lines inside a folder
+// named "Routes" become routes, as written by ToKml.
+func (kml *KmlType) ToGpx() (gpx Gpx, err error) {
+	c := kmlConverter{gpx: NewGpx(DefaultCreator)}
+	if document := kml.Document; document != nil {
+		if document.Name != "" || document.Description != "" {
+			c.gpx.Metadata = &MetadataType{Name: document.Name, Desc: document.Description}
+		}
+		if err := c.container(document.Folder, document.Placemark, false); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.container(kml.Folder, kml.Placemark, false); err != nil {
+		return nil, err
+	}
+	return c.gpx, nil
+}
+
+type kmlConverter struct {
+	gpx *GpxType
+}
+
+func (c *kmlConverter) container(folders []*KmlFolder, placemarks []*KmlPlacemark, routes bool) error {
+	for _, placemark := range placemarks {
+		if err := c.placemark(placemark, routes); err != nil {
+			return err
+		}
+	}
+	for _, folder := range folders {
+		inRoutes := routes || strings.EqualFold(folder.Name, kmlRoutesFolder)
+		if err := c.container(folder.Folder, folder.Placemark, inRoutes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *kmlConverter) placemark(placemark *KmlPlacemark, routes bool) error {
+	geometry := &KmlMultiGeometry{}
+	if placemark.Point != nil {
+		geometry.Point = append(geometry.Point, placemark.Point)
+	}
+	if placemark.LineString != nil {
+		geometry.LineString = append(geometry.LineString, placemark.LineString)
+	}
+	if placemark.Track != nil {
+		geometry.Track = append(geometry.Track, placemark.Track)
+	}
+	if placemark.MultiTrack != nil {
+		geometry.Track = append(geometry.Track, placemark.MultiTrack.Track...)
+	}
+	if placemark.MultiGeometry != nil {
+		geometry.MultiGeometry = append(geometry.MultiGeometry, placemark.MultiGeometry)
+	}
+
+	var points []*WptType
+	var lines, tracks [][]*WptType
+	if err := collectKmlGeometry(geometry, &points, &lines, &tracks); err != nil {
+		return fmt.Errorf("Placemark %q: %v", placemark.Name, err)
+	}
+
+	for _, wpt := range points {
+		wpt.Name = placemark.Name
+		wpt.Desc = placemark.Description
+		if placemark.TimeStamp != nil && placemark.TimeStamp.When != "" {
+			wpt.Time = NewGpxTimeStr(placemark.TimeStamp.When)
+		}
+		c.gpx.Wpt = append(c.gpx.Wpt, wpt)
+	}
+
+	if routes {
+		for _, line := range lines {
+			c.gpx.Rte = append(c.gpx.Rte, &RteType{Name: placemark.Name, Desc: placemark.Description, Rtept: line})
+		}
+		lines = nil
+	}
+	if len(lines)+len(tracks) == 0 {
+		return nil
+	}
+	trk := &TrkType{Name: placemark.Name, Desc: placemark.Description}
+	for _, line := range append(lines, tracks...) {
+		trk.Trkseg = append(trk.Trkseg, &TrksegType{Trkpt: line})
+	}
+	c.gpx.Trk = append(c.gpx.Trk, trk)
+	return nil
+}
+
+// Collect points, lines and tracks of the geometry and its
+// nested geometries.
+func collectKmlGeometry(geometry *KmlMultiGeometry, points *[]*WptType, lines, tracks *[][]*WptType) error {
+	for _, point := range geometry.Point {
+		wpts, err := parseKmlCoordinates(point.Coordinates)
+		if err != nil {
+			return err
+		}
+		*points = append(*points, wpts...)
+	}
+	for _, line := range geometry.LineString {
+		wpts, err := parseKmlCoordinates(line.Coordinates)
+		if err != nil {
+			return err
+		}
+		*lines = append(*lines, wpts)
+	}
+	for _, track := range geometry.Track {
+		wpts, err := parseKmlTrack(track)
+		if err != nil {
+			return err
+		}
+		*tracks = append(*tracks, wpts)
+	}
+	for _, nested := range geometry.MultiGeometry {
+		if err := collectKmlGeometry(nested, points, lines, tracks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse "longitude,latitude[,altitude]" tuples separated by white space.
+func parseKmlCoordinates(coordinates string) ([]*WptType, error) {
+	var wpts []*WptType
+	for _, tuple := range strings.Fields(coordinates) {
+		wpt, err := parseKmlTuple(strings.Split(tuple, ","))
+		if err != nil {
+			return nil, err
+		}
+		wpts = append(wpts, wpt)
+	}
+	return wpts, nil
+}
+
+// Parse gx:coord triples and assign when timestamps to them.
+func parseKmlTrack(track *KmlTrack) ([]*WptType, error) {
+	var wpts []*WptType
+	for i, coord := range track.Coord {
+		wpt, err := parseKmlTuple(strings.Fields(coord))
+		if err != nil {
+			return nil, err
+		}
+		if i < len(track.When) && strings.TrimSpace(track.When[i]) != "" {
+			wpt.Time = NewGpxTimeStr(strings.TrimSpace(track.When[i]))
+		}
+		wpts = append(wpts, wpt)
+	}
+	return wpts, nil
+}
+
+func parseKmlTuple(values []string) (*WptType, error) {
+	if len(values) < 2 || len(values) > 3 {
+		return nil, fmt.Errorf("Invalid KML coordinates %q", strings.Join(values, ","))
+	}
+	numbers := make([]float64, len(values))
+	for i, value := range values {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid KML coordinates %q", strings.Join(values, ","))
+		}
+		numbers[i] = number
+	}
+
+	coordinates, err := NewCoordinatesStrict(numbers[1], numbers[0])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid KML coordinates %q: %v", strings.Join(values, ","), err)
+	}
+	wpt := &WptType{LatAttr: coordinates.Latitude, LonAttr: coordinates.GetLongitude()}
+	if len(numbers) == 3 {
+		wpt.SetElevation(numbers[2])
+	}
+	return wpt, nil
+}
+
+// ToKml converts Gpx to KML document.
+//
+// Metadata name and description become name and description of the
+// document. Waypoints, routes and tracks are written to folders
+// "Waypoints", "Routes" and "Tracks" as placemarks with Point,
+// LineString and gx:Track (or LineString, see KmlOptions) geometry.
+// Elevations are written as absolute altitudes.
+func ToKml(gpx Gpx, opts KmlOptions) *KmlType {
+	document := &KmlDocument{}
+	if gpx.Metadata != nil {
+		document.Name = gpx.Metadata.Name
+		document.Description = gpx.Metadata.Desc
+	}
+
+	if len(gpx.Wpt) > 0 {
+		folder := &KmlFolder{Name: kmlWaypointsFolder}
+		for _, wpt := range gpx.Wpt {
+			placemark := &KmlPlacemark{Name: wpt.Name, Description: wpt.Desc}
+			if wpt.Time != nil {
+				placemark.TimeStamp = &KmlTimeStamp{When: wpt.Time.String()}
+			}
+			point := []*WptType{wpt}
+			placemark.Point = &KmlPoint{
+				AltitudeMode: kmlAltitudeMode(point),
+				Coordinates:  formatKmlCoordinates(point),
+			}
+			folder.Placemark = append(folder.Placemark, placemark)
+		}
+		document.Folder = append(document.Folder, folder)
+	}
+
+	if len(gpx.Rte) > 0 {
+		folder := &KmlFolder{Name: kmlRoutesFolder}
+		for _, rte := range gpx.Rte {
+			folder.Placemark = append(folder.Placemark, &KmlPlacemark{
+				Name:        rte.Name,
+				Description: rte.Desc,
+				LineString:  kmlLineString(rte.Rtept),
+			})
+		}
+		document.Folder = append(document.Folder, folder)
+	}
+
+	if len(gpx.Trk) > 0 {
+		folder := &KmlFolder{Name: kmlTracksFolder}
+		for _, trk := range gpx.Trk {
+			folder.Placemark = append(folder.Placemark, kmlTrackPlacemark(trk, opts.LineStrings))
+		}
+		document.Folder = append(document.Folder, folder)
+	}
+
+	return &KmlType{Document: document}
+}
+
+func kmlTrackPlacemark(trk *TrkType, lineStrings bool) *KmlPlacemark {
+	placemark := &KmlPlacemark{Name: trk.Name, Description: trk.Desc}
+
+	var segments [][]*WptType
+	timed := !lineStrings
+	for _, trkseg := range trk.Trkseg {
+		if len(trkseg.Trkpt) == 0 {
+			continue
+		}
+		segments = append(segments, trkseg.Trkpt)
+		for _, trkpt := range trkseg.Trkpt {
+			timed = timed && trkpt.Time != nil
+		}
+	}
+
+	switch {
+	case len(segments) == 0:
+	case timed && len(segments) == 1:
+		placemark.Track = kmlTrack(segments[0])
+	case timed:
+		placemark.MultiTrack = &KmlMultiTrack{}
+		for _, segment := range segments {
+			placemark.MultiTrack.Track = append(placemark.MultiTrack.Track, kmlTrack(segment))
+		}
+	case len(segments) == 1:
+		placemark.LineString = kmlLineString(segments[0])
+	default:
+		placemark.MultiGeometry = &KmlMultiGeometry{}
+		for _, segment := range segments {
+			placemark.MultiGeometry.LineString = append(placemark.MultiGeometry.LineString, kmlLineString(segment))
+		}
+	}
+	return placemark
+}
+
+func kmlLineString(points []*WptType) *KmlLineString {
+	return &KmlLineString{
+		Tessellate:   1,
+		AltitudeMode: kmlAltitudeMode(points),
+		Coordinates:  formatKmlCoordinates(points),
+	}
+}
+
+func kmlTrack(points []*WptType) *KmlTrack {
+	track := &KmlTrack{AltitudeMode: kmlAltitudeMode(points)}
+	for _, wpt := range points {
+		track.When = append(track.When, wpt.Time.String())
+		track.Coord = append(track.Coord, strings.Join([]string{
+			formatFloat(wpt.LonAttr),
+			formatFloat(wpt.LatAttr),
+			formatFloat(optionalValue(wpt.Ele)),
+		}, " "))
+	}
+	return track
+}
+
+// Return "absolute" if any of the points has elevation,
+// otherwise the default altitude mode (clamp to ground).
+func kmlAltitudeMode(points []*WptType) string {
+	for _, wpt := range points {
+		if wpt.HasElevation() {
+			return "absolute"
+		}
+	}
+	return ""
+}
+
+// Format points as "longitude,latitude[,altitude]" tuples, altitude
+// is written for all points if any of them has elevation.
+func formatKmlCoordinates(points []*WptType) string {
+	altitude := kmlAltitudeMode(points) != ""
+	tuples := make([]string, 0, len(points))
+	for _, wpt := range points {
+		// Coordinates are written as they are, Validate reports invalid ones.
+		tuple := formatFloat(wpt.LonAttr) + "," + formatFloat(wpt.LatAttr)
+		if altitude {
+			tuple += "," + formatFloat(optionalValue(wpt.Ele))
+		}
+		tuples = append(tuples, tuple)
+	}
+	return strings.Join(tuples, " ")
+}
+
+// WriteKmlFile converts the Gpx struct to KML and writes it to a file
+// or returns an error if the file could not be written.
+// Files ending with .kmz are written as KMZ archive.
+func WriteKmlFile(gpx Gpx, path string) (err error) {
+	kmlFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := kmlFile.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	opts := KmlOptions{Indent: "    "}
+	opts.Kmz = strings.EqualFold(filepath.Ext(path), ".kmz")
+	return WriteKml(kmlFile, gpx, opts)
+}
+
+// WriteKml converts the Gpx struct to KML document, see ToKml,
+// and writes it to w, as KMZ archive if requested in opts.
+func WriteKml(w io.Writer, gpx Gpx, opts KmlOptions) error {
+	document := ToKml(gpx, opts)
+	if !opts.Kmz {
+		return encodeXml(w, document, opts.Indent)
+	}
+
+	archive := zip.NewWriter(w)
+	entry, err := archive.Create("doc.kml")
+	if err != nil {
+		return err
+	}
+	if err := encodeXml(entry, document, opts.Indent); err != nil {
+		return err
+	}
+	return archive.Close()
+}
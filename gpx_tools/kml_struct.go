@@ -0,0 +1,141 @@
+package gpx_tools
+
+import (
+	"encoding/xml"
+)
+
+// KmlNamespace is the XML namespace of KML version 2.2.
+const KmlNamespace = "http://www.opengis.net/kml/2.2"
+
+// KmlGxNamespace is the namespace of Google extensions to KML,
+// it is used for tracks with timestamps (gx:Track).
+const KmlGxNamespace = "http://www.google.com/kml/ext/2.2"
+
+// KmlType is the root element of KML document.
+// Only the subset of KML needed to represent GPX data is modelled,
+// styles, regions and other elements are skipped when reading.
+type KmlType struct {
+	XMLName   xml.Name        `xml:"kml"`
+	Document  *KmlDocument    `xml:"Document"`
+	Folder    []*KmlFolder    `xml:"Folder"`
+	Placemark []*KmlPlacemark `xml:"Placemark"`
+}
+
+// KmlDocument is a container of folders and placemarks
+// with name and description of the whole document.
+type KmlDocument struct {
+	Name        string          `xml:"name,omitempty"`
+	Description string          `xml:"description,omitempty"`
+	Folder      []*KmlFolder    `xml:"Folder"`
+	Placemark   []*KmlPlacemark `xml:"Placemark"`
+}
+
+// KmlFolder is a named container of folders and placemarks.
+type KmlFolder struct {
+	Name        string          `xml:"name,omitempty"`
+	Description string          `xml:"description,omitempty"`
+	Folder      []*KmlFolder    `xml:"Folder"`
+	Placemark   []*KmlPlacemark `xml:"Placemark"`
+}
+
+// KmlPlacemark is a feature with one geometry, which is
+// a point, a line, a track or a collection of them.
+type KmlPlacemark struct {
+	Name          string            `xml:"name,omitempty"`
+	Description   string            `xml:"description,omitempty"`
+	TimeStamp     *KmlTimeStamp     `xml:"TimeStamp"`
+	Point         *KmlPoint         `xml:"Point"`
+	LineString    *KmlLineString    `xml:"LineString"`
+	Track         *KmlTrack         `xml:"Track"`
+	MultiTrack    *KmlMultiTrack    `xml:"MultiTrack"`
+	MultiGeometry *KmlMultiGeometry `xml:"MultiGeometry"`
+}
+
+// KmlTimeStamp is a moment in time of a placemark.
+type KmlTimeStamp struct {
+	When string `xml:"when"`
+}
+
+// KmlPoint is a single position, Coordinates
+// hold one "longitude,latitude[,altitude]" tuple.
+type KmlPoint struct {
+	AltitudeMode string `xml:"altitudeMode,omitempty"`
+	Coordinates  string `xml:"coordinates"`
+}
+
+// KmlLineString is a connected set of line segments, Coordinates hold
+// "longitude,latitude[,altitude]" tuples separated by white space.
+type KmlLineString struct {
+	Tessellate   int    `xml:"tessellate,omitempty"`
+	AltitudeMode string `xml:"altitudeMode,omitempty"`
+	Coordinates  string `xml:"coordinates"`
+}
+
+// KmlTrack is gx:Track, a line with timestamp of every point.
+// Coord holds "longitude latitude altitude" triples,
+// When holds timestamps of the same points.
+type KmlTrack struct {
+	AltitudeMode string   `xml:"altitudeMode,omitempty"`
+	When         []string `xml:"when"`
+	Coord        []string `xml:"coord"`
+}
+
+// KmlMultiTrack is gx:MultiTrack, a set of gx:Track elements.
+type KmlMultiTrack struct {
+	AltitudeMode string      `xml:"altitudeMode,omitempty"`
+	Track        []*KmlTrack `xml:"Track"`
+}
+
+// KmlMultiGeometry is a collection of geometries of one placemark.
+type KmlMultiGeometry struct {
+	Point         []*KmlPoint         `xml:"Point"`
+	LineString    []*KmlLineString    `xml:"LineString"`
+	Track         []*KmlTrack         `xml:"Track"`
+	MultiGeometry []*KmlMultiGeometry `xml:"MultiGeometry"`
+}
+
+// MarshalXML encodes the kml element in the KML 2.2 namespace
+// with declaration of the gx prefix.
+func (kml *KmlType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type kmlAlias KmlType
+	start = xml.StartElement{
+		Name: xml.Name{Local: "kml"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: KmlNamespace},
+			{Name: xml.Name{Local: "xmlns:gx"}, Value: KmlGxNamespace},
+		},
+	}
+	return e.EncodeElement((*kmlAlias)(kml), start)
+}
+
+// MarshalXML encodes the track as gx:Track, its coordinates as gx:coord
+// and timestamps as when elements of the KML namespace.
+func (track *KmlTrack) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "gx:Track"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if track.AltitudeMode != "" {
+		if err := e.EncodeElement(track.AltitudeMode, xml.StartElement{Name: xml.Name{Local: "altitudeMode"}}); err != nil {
+			return err
+		}
+	}
+	for _, when := range track.When {
+		if err := e.EncodeElement(when, xml.StartElement{Name: xml.Name{Local: "when"}}); err != nil {
+			return err
+		}
+	}
+	for _, coord := range track.Coord {
+		if err := e.EncodeElement(coord, xml.StartElement{Name: xml.Name{Local: "gx:coord"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// MarshalXML encodes the multi track as gx:MultiTrack.
+func (multiTrack *KmlMultiTrack) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type multiTrackAlias KmlMultiTrack
+	start = xml.StartElement{Name: xml.Name{Local: "gx:MultiTrack"}}
+	return e.EncodeElement((*multiTrackAlias)(multiTrack), start)
+}
@@ -0,0 +1,500 @@
+package gpx_tools
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Meters per second in one knot.
+const knotInMetersPerSecond = 1852.0 / 3600
+
+// NmeaOptions configure ParseNmea.
+type NmeaOptions struct {
+	// StartDate is the date of sentences logged before the first RMC
+	// sentence. Zero value means the date is derived from the first
+	// RMC sentence, points have no time if the log has no RMC sentence.
+	StartDate time.Time
+	// SegmentGap starts a new track segment when time between
+	// two points is longer, zero means a single segment.
+	SegmentGap time.Duration
+	// Strict returns an error for sentences with invalid checksum
+	// or invalid fields, by default such sentences are skipped.
+	Strict bool
+}
+
+// ParseNmeaFile parses a log of NMEA 0183 sentences
+// with default options, see ParseNmea.
+func ParseNmeaFile(path string) (gpx Gpx, err error) {
+	nmeaFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer nmeaFile.Close()
+
+	return ParseNmea(nmeaFile, NmeaOptions{})
+}
+
+// ParseNmea parses a log of NMEA 0183 sentences into a track.
+//
+// GGA, RMC, GSA, VTG and GLL sentences of any talker are read, other
+// sentences are ignored. Sentences with the same time of day are merged
+// into one point, GSA and VTG sentences, which have no time, belong
+// to the last point. Points without valid fix are skipped.
+//
+// Date is taken from RMC sentences, between them it is advanced
+// when time of day rolls over midnight. Fix, satellites, dilutions
+// of precision, DGPS data, geoid height and magnetic variation are
+// stored in their GPX fields, speed and course in extensions,
+// see SensorData.
+//
+// Checksums are validated when present.
+func ParseNmea(r io.Reader, opts NmeaOptions) (gpx Gpx, err error) {
+	parser := &nmeaParser{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if err := parser.sentence(scanner.Text()); err != nil && opts.Strict {
+			return nil, fmt.Errorf("Line %d: %v", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return parser.toGpx(opts)
+}
+
+// Data of all sentences of one moment.
+type nmeaEpoch struct {
+	timeOfDay time.Duration
+	hasTime   bool
+	date      time.Time
+	hasDate   bool
+
+	wpt         *WptType
+	hasPosition bool
+	valid       bool
+	quality     int
+	fixType     int
+	speed       *float64
+	course      *float64
+}
+
+type nmeaParser struct {
+	epochs  []*nmeaEpoch
+	current *nmeaEpoch
+}
+
+// Return epoch for sentence with given time of day,
+// a new one if the time differs from the current epoch.
+func (p *nmeaParser) epoch(timeOfDay time.Duration) *nmeaEpoch {
+	if p.current == nil || !p.current.hasTime || p.current.timeOfDay != timeOfDay {
+		if p.current != nil && !p.current.hasTime {
+			p.current.timeOfDay, p.current.hasTime = timeOfDay, true
+			return p.current
+		}
+		p.current = &nmeaEpoch{timeOfDay: timeOfDay, hasTime: true, wpt: &WptType{}, valid: true}
+		p.epochs = append(p.epochs, p.current)
+	}
+	return p.current
+}
+
+// Return the current epoch for sentences without time.
+func (p *nmeaParser) untimedEpoch() *nmeaEpoch {
+	if p.current == nil {
+		p.current = &nmeaEpoch{wpt: &WptType{}, valid: true}
+		p.epochs = append(p.epochs, p.current)
+	}
+	return p.current
+}
+
+func (p *nmeaParser) sentence(line string) error {
+	start := strings.IndexByte(line, '$')
+	if start < 0 {
+		return nil
+	}
+	sentence := strings.TrimSpace(line[start+1:])
+	if star := strings.LastIndexByte(sentence, '*'); star >= 0 {
+		checksum, err := strconv.ParseUint(sentence[star+1:], 16, 8)
+		if err != nil {
+			return fmt.Errorf("Invalid checksum %q", sentence[star+1:])
+		}
+		sentence = sentence[:star]
+		var sum byte
+		for i := 0; i < len(sentence); i++ {
+			sum ^= sentence[i]
+		}
+		if sum != byte(checksum) {
+			return fmt.Errorf("Checksum mismatch, %02X computed, %02X in sentence", sum, checksum)
+		}
+	}
+
+	fields := strings.Split(sentence, ",")
+	if len(fields[0]) < 3 {
+		return fmt.Errorf("Invalid sentence address %q", fields[0])
+	}
+	f := nmeaFields(fields)
+	switch fields[0][len(fields[0])-3:] {
+	case "GGA":
+		return p.gga(f)
+	case "RMC":
+		return p.rmc(f)
+	case "GSA":
+		return p.gsa(f)
+	case "VTG":
+		return p.vtg(f)
+	case "GLL":
+		return p.gll(f)
+	}
+	return nil
+}
+
+func (p *nmeaParser) gga(f nmeaFields) error {
+	timeOfDay, err := f.timeOfDay(1)
+	if err != nil {
+		return err
+	}
+	epoch := p.epoch(timeOfDay)
+	quality, err := f.integer(6)
+	if err != nil {
+		return err
+	}
+	if quality == nil || *quality == 0 {
+		epoch.valid = false
+		return nil
+	}
+	epoch.quality = *quality
+	if err := f.position(epoch, 2); err != nil {
+		return err
+	}
+
+	wpt := epoch.wpt
+	if wpt.Sat, err = f.integer(7); err != nil {
+		return err
+	}
+	if hdop, err := f.float(8); err != nil {
+		return err
+	} else if hdop != nil {
+		wpt.Hdop = hdop
+	}
+	if wpt.Ele, err = f.float(9); err != nil {
+		return err
+	}
+	if wpt.Geoidheight, err = f.float(11); err != nil {
+		return err
+	}
+	if wpt.Ageofdgpsdata, err = f.float(13); err != nil {
+		return err
+	}
+	if wpt.Dgpsid, err = f.integer(14); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *nmeaParser) rmc(f nmeaFields) error {
+	timeOfDay, err := f.timeOfDay(1)
+	if err != nil {
+		return err
+	}
+	epoch := p.epoch(timeOfDay)
+	if date := f.get(9); date != "" {
+		parsed, err := time.Parse("020106", date)
+		if err != nil {
+			return fmt.Errorf("Invalid date %q", date)
+		}
+		epoch.date, epoch.hasDate = parsed, true
+	}
+	if f.get(2) != "A" {
+		epoch.valid = false
+		return nil
+	}
+	if err := f.position(epoch, 3); err != nil {
+		return err
+	}
+
+	if speed, err := f.float(7); err != nil {
+		return err
+	} else if speed != nil {
+		epoch.speed = Optional(*speed * knotInMetersPerSecond)
+	}
+	if epoch.course, err = f.float(8); err != nil {
+		return err
+	}
+	if magvar, err := f.float(10); err != nil {
+		return err
+	} else if magvar != nil {
+		// Magnetic variation to the west is subtracted from true course.
+		if f.get(11) == "W" {
+			*magvar = math.Mod(360-*magvar, 360)
+		}
+		epoch.wpt.Magvar = magvar
+	}
+	return nil
+}
+
+func (p *nmeaParser) gsa(f nmeaFields) error {
+	epoch := p.untimedEpoch()
+	// Talkers of several systems send a GSA sentence for each of them,
+	// the first one is used.
+	if epoch.fixType != 0 {
+		return nil
+	}
+	fixType, err := f.integer(2)
+	if err != nil {
+		return err
+	}
+	if fixType != nil {
+		epoch.fixType = *fixType
+	}
+
+	wpt := epoch.wpt
+	if wpt.Pdop, err = f.float(15); err != nil {
+		return err
+	}
+	if hdop, err := f.float(16); err != nil {
+		return err
+	} else if hdop != nil && wpt.Hdop == nil {
+		wpt.Hdop = hdop
+	}
+	if wpt.Vdop, err = f.float(17); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *nmeaParser) vtg(f nmeaFields) error {
+	epoch := p.untimedEpoch()
+	course, err := f.float(1)
+	if err != nil {
+		return err
+	}
+	if course != nil {
+		epoch.course = course
+	}
+	if speed, err := f.float(7); err != nil {
+		return err
+	} else if speed != nil {
+		epoch.speed = Optional(*speed / 3.6)
+	} else if speed, err := f.float(5); err != nil {
+		return err
+	} else if speed != nil {
+		epoch.speed = Optional(*speed * knotInMetersPerSecond)
+	}
+	return nil
+}
+
+func (p *nmeaParser) gll(f nmeaFields) error {
+	timeOfDay, err := f.timeOfDay(5)
+	if err != nil {
+		return err
+	}
+	epoch := p.epoch(timeOfDay)
+	if f.get(6) != "A" {
+		epoch.valid = false
+		return nil
+	}
+	if epoch.hasPosition {
+		return nil
+	}
+	return f.position(epoch, 1)
+}
+
+// Assign dates to epochs and build track of them.
+func (p *nmeaParser) toGpx(opts NmeaOptions) (Gpx, error) {
+	p.assignDates(opts.StartDate)
+
+	gpx := NewGpx(DefaultCreator)
+	trk := &TrkType{}
+	var trkseg *TrksegType
+	var last time.Time
+	for _, epoch := range p.epochs {
+		if !epoch.valid || !epoch.hasPosition {
+			continue
+		}
+		wpt := epoch.wpt
+		wpt.Fix = nmeaFix(epoch.quality, epoch.fixType)
+		var timestamp time.Time
+		if epoch.hasDate && epoch.hasTime {
+			timestamp = epoch.date.Add(epoch.timeOfDay)
+			wpt.Time = NewGpxTime(timestamp)
+		}
+		if err := gpx.SetSensorData(wpt, SensorData{Speed: epoch.speed, Course: epoch.course}); err != nil {
+			return nil, err
+		}
+
+		if trkseg == nil || opts.SegmentGap > 0 && !timestamp.IsZero() && !last.IsZero() && timestamp.Sub(last) > opts.SegmentGap {
+			trkseg = &TrksegType{}
+			trk.Trkseg = append(trk.Trkseg, trkseg)
+		}
+		if !timestamp.IsZero() {
+			last = timestamp
+		}
+		trkseg.Trkpt = append(trkseg.Trkpt, wpt)
+	}
+	if len(trk.Trkseg) > 0 {
+		gpx.Trk = append(gpx.Trk, trk)
+	}
+	return gpx, nil
+}
+
+// Propagate dates of RMC sentences to following epochs, advancing
+// them when time of day rolls over midnight, and back to preceding
+// epochs, which get start date if it is set.
+func (p *nmeaParser) assignDates(startDate time.Time) {
+	var date time.Time
+	hasDate := !startDate.IsZero()
+	if hasDate {
+		date = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	first := -1
+	var previous time.Duration
+	for i, epoch := range p.epochs {
+		if !epoch.hasTime {
+			continue
+		}
+		switch {
+		case epoch.hasDate:
+			date, hasDate = epoch.date, true
+			if first < 0 {
+				first = i
+			}
+		case hasDate:
+			if epoch.timeOfDay < previous-12*time.Hour {
+				date = date.AddDate(0, 0, 1)
+			}
+			epoch.date, epoch.hasDate = date, true
+		}
+		previous = epoch.timeOfDay
+	}
+
+	if first <= 0 || !startDate.IsZero() {
+		return
+	}
+	date = p.epochs[first].date
+	next := p.epochs[first].timeOfDay
+	for i := first - 1; i >= 0; i-- {
+		epoch := p.epochs[i]
+		if !epoch.hasTime {
+			continue
+		}
+		if epoch.timeOfDay > next+12*time.Hour {
+			date = date.AddDate(0, 0, -1)
+		}
+		epoch.date, epoch.hasDate = date, true
+		next = epoch.timeOfDay
+	}
+}
+
+// Return GPX fix of GGA quality and GSA fix type.
+func nmeaFix(quality, fixType int) string {
+	switch quality {
+	case 2, 4, 5:
+		return "dgps"
+	case 3:
+		return "pps"
+	case 6:
+		return "none"
+	}
+	switch fixType {
+	case 1:
+		return "none"
+	case 2:
+		return "2d"
+	case 3:
+		return "3d"
+	}
+	return ""
+}
+
+type nmeaFields []string
+
+func (f nmeaFields) get(i int) string {
+	if i >= len(f) {
+		return ""
+	}
+	return strings.TrimSpace(f[i])
+}
+
+func (f nmeaFields) float(i int) (*float64, error) {
+	if f.get(i) == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(f.get(i), 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid number %q in field %d", f.get(i), i)
+	}
+	return &value, nil
+}
+
+func (f nmeaFields) integer(i int) (*int, error) {
+	if f.get(i) == "" {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(f.get(i))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid integer %q in field %d", f.get(i), i)
+	}
+	return &value, nil
+}
+
+// Parse time of day in hhmmss.ss format.
+func (f nmeaFields) timeOfDay(i int) (time.Duration, error) {
+	value := f.get(i)
+	if len(value) < 6 {
+		return 0, fmt.Errorf("Invalid time %q", value)
+	}
+	hours, errHours := strconv.Atoi(value[0:2])
+	minutes, errMinutes := strconv.Atoi(value[2:4])
+	seconds, errSeconds := strconv.ParseFloat(value[4:], 64)
+	if errHours != nil || errMinutes != nil || errSeconds != nil || hours > 23 || minutes > 59 || seconds >= 61 {
+		return 0, fmt.Errorf("Invalid time %q", value)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(math.Round(seconds*1000))*time.Millisecond, nil
+}
+
+// Parse position in ddmm.mm,N,dddmm.mm,E format starting at field i.
+func (f nmeaFields) position(epoch *nmeaEpoch, i int) error {
+	lat, err := nmeaDegrees(f.get(i), f.get(i+1), "N", "S")
+	if err != nil {
+		return err
+	}
+	lon, err := nmeaDegrees(f.get(i+2), f.get(i+3), "E", "W")
+	if err != nil {
+		return err
+	}
+	epoch.wpt.LatAttr, epoch.wpt.LonAttr = lat, lon
+	epoch.hasPosition = true
+	return nil
+}
+
+func nmeaDegrees(value, hemisphere, positive, negative string) (float64, error) {
+	// Degrees and minutes are parsed separately to keep precision of minutes.
+	split := strings.IndexByte(value, '.')
+	if split < 0 {
+		split = len(value)
+	}
+	split -= 2
+	if split < 0 {
+		return 0, fmt.Errorf("Invalid coordinate %q", value)
+	}
+	degrees, errDegrees := strconv.ParseUint("0"+value[:split], 10, 16)
+	minutes, errMinutes := strconv.ParseFloat(value[split:], 64)
+	if errDegrees != nil || errMinutes != nil || minutes < 0 || minutes >= 60 {
+		return 0, fmt.Errorf("Invalid coordinate %q", value)
+	}
+	number := float64(degrees) + minutes/60
+	switch hemisphere {
+	case positive:
+		return number, nil
+	case negative:
+		return -number, nil
+	}
+	return 0, fmt.Errorf("Invalid hemisphere %q", hemisphere)
+}
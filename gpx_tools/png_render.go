@@ -0,0 +1,166 @@
+package gpx_tools
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"os"
+)
+
+// WritePngFile renders the Gpx struct to a PNG file, see RenderImage.
+func WritePngFile(gpx Gpx, path string, opts RenderOptions) (err error) {
+	pngFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := pngFile.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	return WritePng(pngFile, gpx, opts)
+}
+
+// WritePng renders the Gpx struct as PNG image, see RenderImage.
+func WritePng(w io.Writer, gpx Gpx, opts RenderOptions) error {
+	img, err := RenderImage(gpx, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// RenderImage draws routes and tracks of the Gpx struct as
+// anti-aliased lines and waypoints as markers, optionally
+// with markers of start and finish of tracks. Labels and scale
+// bar are not drawn as there are no fonts. Background is
+// transparent unless opts.Background is set.
+//
+// Rendering has no shared state, so it can run concurrently
+// when thumbnails of many files are made.
+func RenderImage(gpx Gpx, opts RenderOptions) (*image.RGBA, error) {
+	scene, err := newRenderScene(gpx, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts = scene.opts
+
+	canvas := &rasterCanvas{img: image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))}
+	if opts.Background != nil {
+		draw.Draw(canvas.img, canvas.img.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+	}
+
+	for _, line := range scene.lines {
+		if line.values == nil {
+			canvas.polyline(line.points, line.style)
+			continue
+		}
+		for i, value := range line.values {
+			style := RenderStyle{scene.color(line, value), line.style.Width}
+			canvas.polyline(line.points[i:i+2], style)
+		}
+	}
+	for i := range scene.starts {
+		canvas.marker(scene.starts[i], StartStyle)
+		canvas.marker(scene.finishes[i], FinishStyle)
+	}
+	for _, marker := range scene.markers {
+		canvas.marker(marker.renderPoint, opts.WaypointStyle)
+	}
+	return canvas.img, nil
+}
+
+// Canvas drawing shapes by coverage of pixels,
+// which is estimated from distance of their centres.
+type rasterCanvas struct {
+	img *image.RGBA
+	// Coverage of pixels by the current shape, so overlapping
+	// parts of one polyline are not blended twice.
+	coverage []float64
+}
+
+// Draw line through points with round joins and caps.
+func (c *rasterCanvas) polyline(points []renderPoint, style RenderStyle) {
+	if len(points) == 0 {
+		return
+	}
+	bounds := c.img.Bounds()
+	if c.coverage == nil {
+		c.coverage = make([]float64, bounds.Dx()*bounds.Dy())
+	}
+	radius := style.Width / 2
+	area := image.Rectangle{}
+	for i := range points {
+		a, b := points[i], points[i]
+		if i > 0 {
+			a = points[i-1]
+		}
+		segment := image.Rect(
+			int(math.Floor(math.Min(a.x, b.x)-radius-1)), int(math.Floor(math.Min(a.y, b.y)-radius-1)),
+			int(math.Ceil(math.Max(a.x, b.x)+radius+1)), int(math.Ceil(math.Max(a.y, b.y)+radius+1)),
+		).Intersect(bounds)
+		area = area.Union(segment)
+		for y := segment.Min.Y; y < segment.Max.Y; y++ {
+			for x := segment.Min.X; x < segment.Max.X; x++ {
+				distance := segmentDistance(renderPoint{float64(x) + 0.5, float64(y) + 0.5}, a, b)
+				index := (y-bounds.Min.Y)*bounds.Dx() + x - bounds.Min.X
+				c.coverage[index] = math.Max(c.coverage[index], pixelCoverage(radius, distance))
+			}
+		}
+	}
+	c.fill(area, style.Color)
+}
+
+// Draw circle with white outline.
+func (c *rasterCanvas) marker(center renderPoint, style RenderStyle) {
+	c.disc(center, style.Width+1, color.White)
+	c.disc(center, style.Width, style.Color)
+}
+
+func (c *rasterCanvas) disc(center renderPoint, radius float64, fill color.Color) {
+	c.polyline([]renderPoint{center}, RenderStyle{fill, 2 * radius})
+}
+
+// Blend colour to pixels of the area by their coverage and reset it.
+func (c *rasterCanvas) fill(area image.Rectangle, fill color.Color) {
+	bounds := c.img.Bounds()
+	r, g, b, a := fill.RGBA()
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			index := (y-bounds.Min.Y)*bounds.Dx() + x - bounds.Min.X
+			coverage := c.coverage[index]
+			if coverage == 0 {
+				continue
+			}
+			c.coverage[index] = 0
+			// Source over destination with premultiplied colours.
+			alpha := float64(a) * coverage / 0xffff
+			offset := c.img.PixOffset(x, y)
+			pixel := c.img.Pix[offset : offset+4]
+			for i, channel := range []uint32{r, g, b, a} {
+				value := float64(channel)*coverage/0x101 + float64(pixel[i])*(1-alpha)
+				pixel[i] = uint8(math.Min(255, math.Round(value)))
+			}
+		}
+	}
+}
+
+// Return part of pixel covered by shape of given radius
+// when the centre of pixel has given distance from its axis.
+func pixelCoverage(radius, distance float64) float64 {
+	return math.Max(0, math.Min(1, radius+0.5-distance))
+}
+
+// Return distance of point p from segment ab.
+func segmentDistance(p, a, b renderPoint) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, ((p.x-a.x)*dx+(p.y-a.y)*dy)/length))
+	}
+	return math.Hypot(p.x-a.x-t*dx, p.y-a.y-t*dy)
+}
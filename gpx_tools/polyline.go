@@ -0,0 +1,189 @@
+package gpx_tools
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// PolylineOptions configure encoding and decoding of polylines.
+// Zero value is the Google encoded polyline with precision 5.
+type PolylineOptions struct {
+	// Precision is number of decimal digits of coordinates,
+	// zero means 5, Google Maps uses 5, OSRM and Valhalla 6.
+	Precision int
+	// Elevation in meters is encoded as the third value of every point.
+	Elevation bool
+	// ElevationPrecision is number of decimal digits of elevation,
+	// zero means 2 as used by openrouteservice.
+	ElevationPrecision int
+}
+
+// Points which have elevation, e.g. WptType and PtType.
+type coordConvertible3D interface {
+	ToCoordinates3D() Coordinates3D
+}
+
+func (opts PolylineOptions) factors() (coordFactor, eleFactor float64) {
+	precision, elePrecision := opts.Precision, opts.ElevationPrecision
+	if precision == 0 {
+		precision = 5
+	}
+	if elePrecision == 0 {
+		elePrecision = 2
+	}
+	return math.Pow10(precision), math.Pow10(elePrecision)
+}
+
+// EncodePolyline encodes points into an encoded polyline.
+// Missing elevation is encoded as 0.
+func EncodePolyline(points *[]CoordConvertible, opts PolylineOptions) string {
+	coordFactor, eleFactor := opts.factors()
+	var b strings.Builder
+	var lastLat, lastLon, lastEle int64
+	for _, point := range *points {
+		coords := point.ToCoordinates()
+		lat := int64(math.Round(coords.Latitude * coordFactor))
+		lon := int64(math.Round(coords.longitude * coordFactor))
+		writePolylineValue(&b, lat-lastLat)
+		writePolylineValue(&b, lon-lastLon)
+		lastLat, lastLon = lat, lon
+		if opts.Elevation {
+			var altitude float64
+			if point3D, ok := point.(coordConvertible3D); ok {
+				altitude = point3D.ToCoordinates3D().Altitude
+			}
+			ele := int64(math.Round(altitude * eleFactor))
+			writePolylineValue(&b, ele-lastEle)
+			lastEle = ele
+		}
+	}
+	return b.String()
+}
+
+// EncodePolyline encodes points of the route into an encoded polyline.
+func (rte *RteType) EncodePolyline(opts PolylineOptions) string {
+	points := wptConvertibles(rte.Rtept)
+	return EncodePolyline(&points, opts)
+}
+
+// EncodePolyline encodes points of all segments
+// of the track into one encoded polyline.
+func (trk *TrkType) EncodePolyline(opts PolylineOptions) string {
+	var points []CoordConvertible
+	for _, trkseg := range trk.Trkseg {
+		points = append(points, wptConvertibles(trkseg.Trkpt)...)
+	}
+	return EncodePolyline(&points, opts)
+}
+
+// DecodePolyline decodes an encoded polyline into coordinates,
+// altitude is 0 unless opts.Elevation is set. Returns error if
+// latitude or longitude is out of range, e.g. when opts.Precision
+// differs from the one used for encoding.
+func DecodePolyline(polyline string, opts PolylineOptions) ([]Coordinates3D, error) {
+	coordFactor, eleFactor := opts.factors()
+	dimensions := 2
+	if opts.Elevation {
+		dimensions = 3
+	}
+
+	var coordinates []Coordinates3D
+	var values [3]int64
+	for position := 0; position < len(polyline); {
+		for i := 0; i < dimensions; i++ {
+			delta, next, err := readPolylineValue(polyline, position)
+			if err != nil {
+				return nil, err
+			}
+			values[i] += delta
+			position = next
+		}
+		latitude, longitude := float64(values[0])/coordFactor, float64(values[1])/coordFactor
+		// Encoded longitudes are wrapped too, values out of range
+		// mean the polyline was encoded with other precision.
+		c, err := NewCoordinatesStrict(latitude, longitude)
+		if err == nil && math.Abs(longitude) > 180 {
+			err = fmt.Errorf("Longitude %v is out of range [-180, 180]", longitude)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Point %d of polyline: %v, check the precision", len(coordinates), err)
+		}
+		coordinates = append(coordinates, Coordinates3D{c, float64(values[2]) / eleFactor})
+	}
+	return coordinates, nil
+}
+
+// PolylineToRte decodes an encoded polyline into route points,
+// which have elevation if opts.Elevation is set.
+func PolylineToRte(polyline string, opts PolylineOptions) (*RteType, error) {
+	points, err := decodePolylinePoints(polyline, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &RteType{Rtept: points}, nil
+}
+
+// PolylineToTrk decodes an encoded polyline into a track of one
+// segment, points have elevation if opts.Elevation is set.
+func PolylineToTrk(polyline string, opts PolylineOptions) (*TrkType, error) {
+	points, err := decodePolylinePoints(polyline, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TrkType{Trkseg: []*TrksegType{{Trkpt: points}}}, nil
+}
+
+func decodePolylinePoints(polyline string, opts PolylineOptions) ([]*WptType, error) {
+	coordinates, err := DecodePolyline(polyline, opts)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]*WptType, len(coordinates))
+	for i, coords := range coordinates {
+		points[i] = &WptType{LatAttr: coords.Coordinates.Latitude, LonAttr: coords.Coordinates.longitude}
+		if opts.Elevation {
+			points[i].Ele = Optional(coords.Altitude)
+		}
+	}
+	return points, nil
+}
+
+// Write signed value as chunks of 5 bits, least significant
+// first, each offset by 63 and ORed with 0x20 if it is followed
+// by another chunk.
+func writePolylineValue(b *strings.Builder, value int64) {
+	encoded := uint64(value) << 1
+	if value < 0 {
+		encoded = ^encoded
+	}
+	for encoded >= 0x20 {
+		b.WriteByte(byte(0x20|encoded&0x1f) + 63)
+		encoded >>= 5
+	}
+	b.WriteByte(byte(encoded) + 63)
+}
+
+// Read value starting at position and return it with position of the next value.
+func readPolylineValue(polyline string, position int) (value int64, next int, err error) {
+	var encoded uint64
+	for shift := uint(0); ; shift += 5 {
+		if position >= len(polyline) {
+			return 0, 0, fmt.Errorf("Unexpected end of polyline")
+		}
+		chunk := int(polyline[position]) - 63
+		position++
+		if chunk < 0 || chunk > 0x3f || shift > 60 {
+			return 0, 0, fmt.Errorf("Invalid character %q at position %d of polyline", polyline[position-1], position-1)
+		}
+		encoded |= uint64(chunk&0x1f) << shift
+		if chunk < 0x20 {
+			break
+		}
+	}
+	value = int64(encoded >> 1)
+	if encoded&1 != 0 {
+		value = ^value
+	}
+	return value, position, nil
+}
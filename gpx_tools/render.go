@@ -0,0 +1,371 @@
+package gpx_tools
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// Projection of coordinates to the canvas.
+type Projection int
+
+const (
+	// WebMercatorProjection is the projection of web maps.
+	WebMercatorProjection Projection = iota
+	// EquirectangularProjection scales longitude by cosine
+	// of the latitude in the middle of the canvas.
+	EquirectangularProjection
+)
+
+// ColorBy selects the value which colours lines.
+type ColorBy int
+
+const (
+	// ColorByNone draws lines in the colour of their style.
+	ColorByNone ColorBy = iota
+	// ColorBySpeed colours parts of lines by speed between their
+	// points, parts without time are drawn in the colour of style.
+	ColorBySpeed
+	// ColorByElevation colours parts of lines by average elevation
+	// of their points.
+	ColorByElevation
+)
+
+// RenderStyle is a style of line or marker.
+type RenderStyle struct {
+	Color color.Color
+	// Width of line or radius of marker in pixels.
+	Width float64
+}
+
+// RenderOptions configure rendering of Gpx struct.
+// Zero value draws 800×600 pixels in Web Mercator projection.
+type RenderOptions struct {
+	// Width and Height of the canvas in pixels, zero means 800×600.
+	Width, Height int
+	// Padding around drawing in pixels, zero means 20.
+	Padding    int
+	Projection Projection
+	// Background colour, nil means transparent.
+	Background color.Color
+	// TrackStyles are used for tracks in turn, nil or empty
+	// means DefaultTrackStyles.
+	TrackStyles []RenderStyle
+	// RouteStyles are used for routes in turn, nil or empty
+	// means DefaultRouteStyles.
+	RouteStyles []RenderStyle
+	// WaypointStyle of markers, zero value means DefaultWaypointStyle.
+	WaypointStyle RenderStyle
+	HideWaypoints bool
+	HideLabels    bool
+	HideScaleBar  bool
+	// StartFinish draws markers at the start and the finish of every track.
+	StartFinish bool
+	ColorBy     ColorBy
+	// Gradient from the lowest to the highest value of ColorBy,
+	// nil or empty means green, yellow and red.
+	Gradient []color.Color
+	// FontSize of labels in pixels, zero means 12.
+	FontSize float64
+}
+
+var (
+	// DefaultTrackStyles are styles of tracks used in turn.
+	DefaultTrackStyles = []RenderStyle{
+		{color.RGBA{0xe4, 0x1a, 0x1c, 0xff}, 3},
+		{color.RGBA{0x37, 0x7e, 0xb8, 0xff}, 3},
+		{color.RGBA{0x4d, 0xaf, 0x4a, 0xff}, 3},
+		{color.RGBA{0x98, 0x4e, 0xa3, 0xff}, 3},
+		{color.RGBA{0xff, 0x7f, 0x00, 0xff}, 3},
+	}
+	// DefaultRouteStyles are styles of routes used in turn.
+	DefaultRouteStyles = []RenderStyle{{color.RGBA{0x00, 0x00, 0xcc, 0xb0}, 2}}
+	// DefaultWaypointStyle is the style of waypoint markers.
+	DefaultWaypointStyle = RenderStyle{color.RGBA{0xcc, 0x00, 0x00, 0xff}, 4}
+	// StartStyle and FinishStyle are styles of markers of start and finish.
+	StartStyle  = RenderStyle{color.RGBA{0x00, 0xa0, 0x00, 0xff}, 5}
+	FinishStyle = RenderStyle{color.RGBA{0x20, 0x20, 0x20, 0xff}, 5}
+
+	defaultGradient = []color.Color{
+		color.RGBA{0x1a, 0x96, 0x41, 0xff},
+		color.RGBA{0xff, 0xd7, 0x00, 0xff},
+		color.RGBA{0xd7, 0x19, 0x1c, 0xff},
+	}
+)
+
+// Line projected to the canvas, values are values
+// of ColorBy between its points or NaN.
+type renderLine struct {
+	points []renderPoint
+	values []float64
+	style  RenderStyle
+}
+
+type renderPoint struct {
+	x, y float64
+}
+
+type renderMarker struct {
+	renderPoint
+	label string
+}
+
+type renderScaleBar struct {
+	start, end renderPoint
+	label      string
+}
+
+// Drawing of Gpx struct shared by renderers.
+type renderScene struct {
+	opts     RenderOptions
+	lines    []renderLine
+	markers  []renderMarker
+	starts   []renderPoint
+	finishes []renderPoint
+	scaleBar *renderScaleBar
+	minValue float64
+	maxValue float64
+
+	lat0    float64
+	scale   float64
+	centerX float64
+	centerY float64
+}
+
+// Fill default values of options.
+func (opts RenderOptions) withDefaults() RenderOptions {
+	if opts.Width == 0 {
+		opts.Width = 800
+	}
+	if opts.Height == 0 {
+		opts.Height = 600
+	}
+	if opts.Padding == 0 {
+		opts.Padding = 20
+	}
+	if len(opts.TrackStyles) == 0 {
+		opts.TrackStyles = DefaultTrackStyles
+	}
+	if len(opts.RouteStyles) == 0 {
+		opts.RouteStyles = DefaultRouteStyles
+	}
+	if opts.WaypointStyle.Color == nil {
+		opts.WaypointStyle = DefaultWaypointStyle
+	}
+	if len(opts.Gradient) == 0 {
+		opts.Gradient = defaultGradient
+	}
+	if opts.FontSize == 0 {
+		opts.FontSize = 12
+	}
+	return opts
+}
+
+// Project all points of the Gpx struct to the canvas.
+func newRenderScene(gpx Gpx, opts RenderOptions) (*renderScene, error) {
+	opts = opts.withDefaults()
+	if opts.Width <= 2*opts.Padding || opts.Height <= 2*opts.Padding {
+		return nil, fmt.Errorf("Canvas %d×%d is smaller than padding", opts.Width, opts.Height)
+	}
+	scene := &renderScene{opts: opts, minValue: math.Inf(1), maxValue: math.Inf(-1)}
+
+	var lines [][]*WptType
+	var styles []RenderStyle
+	var starts, finishes []*WptType
+	for i, rte := range gpx.Rte {
+		lines = append(lines, rte.Rtept)
+		styles = append(styles, opts.RouteStyles[i%len(opts.RouteStyles)])
+	}
+	for i, trk := range gpx.Trk {
+		for _, trkseg := range trk.Trkseg {
+			lines = append(lines, trkseg.Trkpt)
+			styles = append(styles, opts.TrackStyles[i%len(opts.TrackStyles)])
+		}
+		var points []*WptType
+		for _, trkseg := range trk.Trkseg {
+			points = append(points, trkseg.Trkpt...)
+		}
+		if opts.StartFinish && len(points) > 0 {
+			starts = append(starts, points[0])
+			finishes = append(finishes, points[len(points)-1])
+		}
+	}
+	var waypoints []*WptType
+	if !opts.HideWaypoints {
+		waypoints = gpx.Wpt
+	}
+
+	// Bounding box of projected points.
+	all := append(lines[:len(lines):len(lines)], waypoints)
+	minLat, maxLat := math.Inf(1), math.Inf(-1)
+	for _, points := range all {
+		for _, wpt := range points {
+			if _, err := wpt.ToCoordinatesChecked(); err != nil {
+				return nil, err
+			}
+			minLat, maxLat = math.Min(minLat, wpt.LatAttr), math.Max(maxLat, wpt.LatAttr)
+		}
+	}
+	if math.IsInf(minLat, 1) {
+		return nil, fmt.Errorf("Nothing to render")
+	}
+	scene.lat0 = (minLat + maxLat) / 2
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, points := range all {
+		for _, wpt := range points {
+			x, y := scene.project(wpt.LatAttr, wpt.LonAttr)
+			minX, minY = math.Min(minX, x), math.Min(minY, y)
+			maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+		}
+	}
+	scene.centerX, scene.centerY = (minX+maxX)/2, (minY+maxY)/2
+	drawWidth, drawHeight := float64(opts.Width-2*opts.Padding), float64(opts.Height-2*opts.Padding)
+	scene.scale = math.Inf(1)
+	if maxX > minX {
+		scene.scale = drawWidth / (maxX - minX)
+	}
+	if maxY > minY {
+		scene.scale = math.Min(scene.scale, drawHeight/(maxY-minY))
+	}
+	if math.IsInf(scene.scale, 1) {
+		// Single point is drawn at scale of about one meter per pixel.
+		scene.scale = 6371000
+	}
+
+	for i, points := range lines {
+		line := renderLine{style: styles[i], points: make([]renderPoint, len(points))}
+		for j, wpt := range points {
+			line.points[j] = scene.toCanvas(wpt.LatAttr, wpt.LonAttr)
+			if j > 0 && opts.ColorBy != ColorByNone {
+				value := renderValue(points[j-1], wpt, opts.ColorBy)
+				if !math.IsNaN(value) {
+					scene.minValue, scene.maxValue = math.Min(scene.minValue, value), math.Max(scene.maxValue, value)
+				}
+				line.values = append(line.values, value)
+			}
+		}
+		scene.lines = append(scene.lines, line)
+	}
+	for _, wpt := range waypoints {
+		marker := renderMarker{renderPoint: scene.toCanvas(wpt.LatAttr, wpt.LonAttr)}
+		if !opts.HideLabels {
+			marker.label = wpt.Name
+		}
+		scene.markers = append(scene.markers, marker)
+	}
+	for i := range starts {
+		scene.starts = append(scene.starts, scene.toCanvas(starts[i].LatAttr, starts[i].LonAttr))
+		scene.finishes = append(scene.finishes, scene.toCanvas(finishes[i].LatAttr, finishes[i].LonAttr))
+	}
+	if !opts.HideScaleBar {
+		scene.scaleBar = scene.newScaleBar()
+	}
+	return scene, nil
+}
+
+// Return value of ColorBy between two points or NaN if it is not known.
+func renderValue(previous, wpt *WptType, colorBy ColorBy) float64 {
+	switch colorBy {
+	case ColorBySpeed:
+		speed, err := VelocityBetweenPoints(previous, wpt, Haversine)
+		if err == nil && !math.IsInf(speed, 0) {
+			return speed
+		}
+	case ColorByElevation:
+		if previous.Ele != nil && wpt.Ele != nil {
+			return (*previous.Ele + *wpt.Ele) / 2
+		}
+	}
+	return math.NaN()
+}
+
+// Project coordinates in degrees to plane with y axis pointing north.
+func (scene *renderScene) project(lat, lon float64) (x, y float64) {
+	lonRadians := lon * math.Pi / 180
+	if scene.opts.Projection == EquirectangularProjection {
+		return lonRadians * math.Cos(scene.lat0*math.Pi/180), lat * math.Pi / 180
+	}
+	c := Coordinates{Latitude: lat, longitude: lon}
+	x, y = c.ToWebMercator()
+	return x / WebMercatorRadius, y / WebMercatorRadius
+}
+
+// Inverse of project.
+func (scene *renderScene) unproject(x, y float64) (lat, lon float64) {
+	if scene.opts.Projection == EquirectangularProjection {
+		return y * 180 / math.Pi, x / math.Cos(scene.lat0*math.Pi/180) * 180 / math.Pi
+	}
+	c := WebMercatorToCoordinates(x*WebMercatorRadius, y*WebMercatorRadius)
+	return c.GetLatitude(), c.GetLongitude()
+}
+
+func (scene *renderScene) toCanvas(lat, lon float64) renderPoint {
+	x, y := scene.project(lat, lon)
+	return renderPoint{
+		x: float64(scene.opts.Width)/2 + (x-scene.centerX)*scene.scale,
+		y: float64(scene.opts.Height)/2 - (y-scene.centerY)*scene.scale,
+	}
+}
+
+func (scene *renderScene) fromCanvas(point renderPoint) (lat, lon float64) {
+	return scene.unproject(
+		scene.centerX+(point.x-float64(scene.opts.Width)/2)/scene.scale,
+		scene.centerY-(point.y-float64(scene.opts.Height)/2)/scene.scale)
+}
+
+// Return scale bar of round length at most quarter of the
+// canvas width in the bottom left corner. Meters per pixel
+// are measured in the middle of the canvas.
+func (scene *renderScene) newScaleBar() *renderScaleBar {
+	middle := renderPoint{float64(scene.opts.Width) / 2, float64(scene.opts.Height) / 2}
+	lat1, lon1 := scene.fromCanvas(renderPoint{middle.x - 50, middle.y})
+	lat2, lon2 := scene.fromCanvas(renderPoint{middle.x + 50, middle.y})
+	metersPerPixel := Haversine(NewCoordinates(lat1, lon1), NewCoordinates(lat2, lon2)) / 100
+	if metersPerPixel <= 0 || math.IsNaN(metersPerPixel) {
+		return nil
+	}
+
+	maxLength := metersPerPixel * float64(scene.opts.Width) / 4
+	length := math.Pow(10, math.Floor(math.Log10(maxLength)))
+	for _, factor := range []float64{5, 2} {
+		if length*factor <= maxLength {
+			length *= factor
+			break
+		}
+	}
+	label := fmt.Sprintf("%s m", formatFloat(length))
+	if length >= 1000 {
+		label = fmt.Sprintf("%s km", formatFloat(length/1000))
+	}
+
+	y := float64(scene.opts.Height - scene.opts.Padding/2)
+	x := float64(scene.opts.Padding)
+	return &renderScaleBar{
+		start: renderPoint{x, y},
+		end:   renderPoint{x + length/metersPerPixel, y},
+		label: label,
+	}
+}
+
+// Return colour of a part of line with given value.
+func (scene *renderScene) color(line renderLine, value float64) color.Color {
+	if math.IsNaN(value) {
+		return line.style.Color
+	}
+	gradient := scene.opts.Gradient
+	if len(gradient) == 1 || scene.maxValue <= scene.minValue {
+		return gradient[0]
+	}
+	position := (value - scene.minValue) / (scene.maxValue - scene.minValue) * float64(len(gradient)-1)
+	index := int(math.Min(math.Floor(position), float64(len(gradient)-2)))
+	return interpolateColor(gradient[index], gradient[index+1], position-float64(index))
+}
+
+func interpolateColor(from, to color.Color, ratio float64) color.Color {
+	r1, g1, b1, a1 := from.RGBA()
+	r2, g2, b2, a2 := to.RGBA()
+	mix := func(c1, c2 uint32) uint16 {
+		return uint16(math.Round(float64(c1) + (float64(c2)-float64(c1))*ratio))
+	}
+	return color.RGBA64{mix(r1, r2), mix(g1, g2), mix(b1, b2), mix(a1, a2)}
+}
@@ -0,0 +1,142 @@
+package gpx_tools
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// WriteSvgFile renders the Gpx struct to an SVG file, see WriteSvg.
+func WriteSvgFile(gpx Gpx, path string, opts RenderOptions) (err error) {
+	svgFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := svgFile.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	return WriteSvg(svgFile, gpx, opts)
+}
+
+// WriteSvg renders routes and tracks of the Gpx struct as paths,
+// waypoints as markers with their names and a scale bar,
+// optionally with markers of start and finish of tracks.
+// Every segment of track is a separate path. Lines coloured
+// by speed or elevation are drawn as separate lines between
+// their points.
+func WriteSvg(w io.Writer, gpx Gpx, opts RenderOptions) error {
+	scene, err := newRenderScene(gpx, opts)
+	if err != nil {
+		return err
+	}
+	opts = scene.opts
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		opts.Width, opts.Height, opts.Width, opts.Height)
+	if opts.Background != nil {
+		fmt.Fprintf(b, `<rect width="100%%" height="100%%" fill="%s"%s/>`+"\n",
+			svgColor(opts.Background), svgOpacity("fill-opacity", opts.Background))
+	}
+
+	for _, line := range scene.lines {
+		if len(line.points) == 0 {
+			continue
+		}
+		if line.values == nil {
+			fmt.Fprintf(b, `<path d="%s" fill="none" stroke="%s"%s stroke-width="%s" stroke-linejoin="round" stroke-linecap="round"/>`+"\n",
+				svgPath(line.points), svgColor(line.style.Color), svgOpacity("stroke-opacity", line.style.Color), svgNumber(line.style.Width))
+			continue
+		}
+		fmt.Fprintf(b, `<g stroke-width="%s" stroke-linecap="round">`+"\n", svgNumber(line.style.Width))
+		for i, value := range line.values {
+			c := scene.color(line, value)
+			fmt.Fprintf(b, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="%s"%s/>`+"\n",
+				svgNumber(line.points[i].x), svgNumber(line.points[i].y),
+				svgNumber(line.points[i+1].x), svgNumber(line.points[i+1].y),
+				svgColor(c), svgOpacity("stroke-opacity", c))
+		}
+		b.WriteString("</g>\n")
+	}
+
+	for i := range scene.starts {
+		writeSvgMarker(b, scene.starts[i], StartStyle)
+		writeSvgMarker(b, scene.finishes[i], FinishStyle)
+	}
+
+	style := opts.WaypointStyle
+	for _, marker := range scene.markers {
+		writeSvgMarker(b, marker.renderPoint, style)
+		if marker.label != "" {
+			fmt.Fprintf(b, `<text x="%s" y="%s" font-family="sans-serif" font-size="%s">%s</text>`+"\n",
+				svgNumber(marker.x+style.Width+2), svgNumber(marker.y+opts.FontSize/3),
+				svgNumber(opts.FontSize), svgText(marker.label))
+		}
+	}
+
+	if bar := scene.scaleBar; bar != nil {
+		tick := opts.FontSize / 3
+		fmt.Fprintf(b, `<path d="M%s %sV%sH%sV%s" fill="none" stroke="black" stroke-width="1.5"/>`+"\n",
+			svgNumber(bar.start.x), svgNumber(bar.start.y-tick), svgNumber(bar.start.y),
+			svgNumber(bar.end.x), svgNumber(bar.end.y-tick))
+		fmt.Fprintf(b, `<text x="%s" y="%s" font-family="sans-serif" font-size="%s">%s</text>`+"\n",
+			svgNumber(bar.end.x+4), svgNumber(bar.end.y), svgNumber(opts.FontSize), svgText(bar.label))
+	}
+
+	b.WriteString("</svg>\n")
+	return b.Flush()
+}
+
+func writeSvgMarker(b *bufio.Writer, point renderPoint, style RenderStyle) {
+	fmt.Fprintf(b, `<circle cx="%s" cy="%s" r="%s" fill="%s"%s stroke="white" stroke-width="1"/>`+"\n",
+		svgNumber(point.x), svgNumber(point.y), svgNumber(style.Width),
+		svgColor(style.Color), svgOpacity("fill-opacity", style.Color))
+}
+
+func svgPath(points []renderPoint) string {
+	var b strings.Builder
+	for i, point := range points {
+		if i == 0 {
+			b.WriteString("M")
+		} else {
+			b.WriteString("L")
+		}
+		b.WriteString(svgNumber(point.x))
+		b.WriteString(" ")
+		b.WriteString(svgNumber(point.y))
+	}
+	return b.String()
+}
+
+// Format number with two decimals, which is precise enough for pixels.
+func svgNumber(value float64) string {
+	return formatFloat(math.Round(value*100) / 100)
+}
+
+func svgColor(c color.Color) string {
+	rgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+}
+
+// Return opacity attribute of translucent colour.
+func svgOpacity(attr string, c color.Color) string {
+	rgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if rgba.A == 0xff {
+		return ""
+	}
+	return fmt.Sprintf(` %s="%s"`, attr, formatFloat(math.Round(float64(rgba.A)/255*1000)/1000))
+}
+
+func svgText(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
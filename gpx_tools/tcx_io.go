@@ -0,0 +1,390 @@
+package gpx_tools
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// TcxOptions configure WriteTcx and ToTcx.
+// Zero value writes activities without indentation.
+type TcxOptions struct {
+	// Indent is used for every level of nested elements,
+	// empty string writes the whole document on one line.
+	Indent string
+	// Course writes tracks as courses instead of activities.
+	Course bool
+	// Sport of activities ("Running", "Biking" or "Other") used for
+	// tracks whose type is not a TCX sport, empty string means "Other".
+	Sport string
+	// Algorithm used to calculate distances, nil means Haversine.
+	Algorithm DistanceAlgorithm
+}
+
+// ParseTcxFile parses a .tcx file and converts it to Gpx,
+// see TcxType.ToGpx.
+func ParseTcxFile(path string) (gpx Gpx, err error) {
+	tcxFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer tcxFile.Close()
+
+	return ParseTcx(tcxFile)
+}
+
+// ParseTcx reads the whole TCX document from the reader
+// and parses it like ParseTcxBytes.
+func ParseTcx(r io.Reader) (gpx Gpx, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTcxBytes(data)
+}
+
+// ParseTcxBytes parses TCX document and converts it to Gpx.
+// Byte order mark and white space before the XML declaration,
+// which some devices write, are skipped.
+func ParseTcxBytes(data []byte) (gpx Gpx, err error) {
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	var tcx TcxType
+	if err := xml.Unmarshal(data, &tcx); err != nil {
+		return nil, err
+	}
+	return tcx.ToGpx()
+}
+
+// ToGpx converts TCX document to GPX 1.1.
+//
+// Every activity and course becomes a track with a segment for
+// every Track element of its laps. Sport of activity is kept in type
+// of the track and its id in name. Altitude is kept in elevation,
+// heart rate, cadence, distance, speed and power in extensions,
+// see SensorData. Track points without position are skipped.
+// Course points become waypoints.
+func (tcx *TcxType) ToGpx() (gpx Gpx, err error) {
+	result := NewGpx(DefaultCreator)
+
+	if tcx.Activities != nil {
+		for _, activity := range tcx.Activities.Activity {
+			trk := &TrkType{Name: activity.Id, Desc: activity.Notes, Type: activity.Sport}
+			for _, lap := range activity.Lap {
+				if err := tcxTrksegs(result, trk, lap.Track); err != nil {
+					return nil, err
+				}
+			}
+			result.Trk = append(result.Trk, trk)
+		}
+	}
+
+	if tcx.Courses != nil {
+		for _, course := range tcx.Courses.Course {
+			trk := &TrkType{Name: course.Name, Desc: course.Notes}
+			if err := tcxTrksegs(result, trk, course.Track); err != nil {
+				return nil, err
+			}
+			result.Trk = append(result.Trk, trk)
+
+			for _, point := range course.CoursePoint {
+				wpt := &WptType{
+					LatAttr: point.Position.LatitudeDegrees,
+					LonAttr: point.Position.LongitudeDegrees,
+					Ele:     point.AltitudeMeters,
+					Name:    point.Name,
+					Desc:    point.Notes,
+					Type:    point.PointType,
+				}
+				if point.Time != "" {
+					wpt.Time = NewGpxTimeStr(point.Time)
+				}
+				result.Wpt = append(result.Wpt, wpt)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func tcxTrksegs(gpx *GpxType, trk *TrkType, tracks []*TcxTrack) error {
+	for _, track := range tracks {
+		trkseg := &TrksegType{}
+		for _, point := range track.Trackpoint {
+			if point.Position == nil {
+				continue
+			}
+			wpt := &WptType{
+				LatAttr: point.Position.LatitudeDegrees,
+				LonAttr: point.Position.LongitudeDegrees,
+				Ele:     point.AltitudeMeters,
+			}
+			if point.Time != "" {
+				wpt.Time = NewGpxTimeStr(point.Time)
+			}
+
+			data := SensorData{Cadence: point.Cadence, Distance: point.DistanceMeters}
+			if point.HeartRateBpm != nil {
+				data.HeartRate = Optional(point.HeartRateBpm.Value)
+			}
+			if point.Extensions != nil && point.Extensions.TPX != nil {
+				tpx := point.Extensions.TPX
+				data.Speed = tpx.Speed
+				data.Power = tpx.Watts
+				if data.Cadence == nil {
+					data.Cadence = tpx.RunCadence
+				}
+			}
+			if err := gpx.SetSensorData(wpt, data); err != nil {
+				return err
+			}
+			trkseg.Trkpt = append(trkseg.Trkpt, wpt)
+		}
+		if len(trkseg.Trkpt) > 0 {
+			trk.Trkseg = append(trk.Trkseg, trkseg)
+		}
+	}
+	return nil
+}
+
+// ToTcx converts tracks of Gpx to TCX activities or courses.
+//
+// Every segment of a track becomes a lap of activity with distance
+// calculated by TotalLength. Cumulative distance of track points
+// is taken from their extensions or calculated by opts.Algorithm.
+// Courses have a single lap. Sensor data in extensions of points
+// are written too, see SensorData.
+//
+// TCX requires time of every track point, error is returned
+// for points without valid time.
+func ToTcx(gpx Gpx, opts TcxOptions) (*TcxType, error) {
+	if opts.Algorithm == nil {
+		opts.Algorithm = Haversine
+	}
+
+	tcx := &TcxType{}
+	for _, trk := range gpx.Trk {
+		var segments [][]*WptType
+		for _, trkseg := range trk.Trkseg {
+			if len(trkseg.Trkpt) > 0 {
+				segments = append(segments, trkseg.Trkpt)
+			}
+		}
+		if len(segments) == 0 {
+			continue
+		}
+		for _, segment := range segments {
+			for i, wpt := range segment {
+				if _, err := wpt.Time.Get(); err != nil {
+					return nil, fmt.Errorf("Track %q, point %d: %v", trk.Name, i, err)
+				}
+			}
+		}
+
+		if opts.Course {
+			course, err := tcxCourse(trk, segments, opts.Algorithm)
+			if err != nil {
+				return nil, err
+			}
+			if tcx.Courses == nil {
+				tcx.Courses = &TcxCourses{}
+			}
+			tcx.Courses.Course = append(tcx.Courses.Course, course)
+		} else {
+			activity, err := tcxActivity(trk, segments, opts)
+			if err != nil {
+				return nil, err
+			}
+			if tcx.Activities == nil {
+				tcx.Activities = &TcxActivities{}
+			}
+			tcx.Activities.Activity = append(tcx.Activities.Activity, activity)
+		}
+	}
+	return tcx, nil
+}
+
+func tcxActivity(trk *TrkType, segments [][]*WptType, opts TcxOptions) (*TcxActivity, error) {
+	activity := &TcxActivity{Sport: tcxSport(trk.Type, opts.Sport), Id: segments[0][0].Time.String(), Notes: trk.Desc}
+	distance := 0.0
+	for _, segment := range segments {
+		convertibles := wptConvertibles(segment)
+		duration, err := TotalTime(&convertibles)
+		if err != nil {
+			return nil, err
+		}
+		length, err := TotalLengthChecked(&convertibles, opts.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		lap := &TcxLap{
+			StartTime:        segment[0].Time.String(),
+			TotalTimeSeconds: duration.Seconds(),
+			DistanceMeters:   length,
+			Intensity:        "Active",
+			TriggerMethod:    "Manual",
+		}
+
+		track := &TcxTrack{}
+		heartRateSum, heartRateCount := 0, 0
+		// Gaps between segments are not counted, as in DistanceMeters of laps.
+		var prev *WptType
+		for _, wpt := range segment {
+			if prev != nil {
+				step, err := distanceBetween(prev, wpt, opts.Algorithm)
+				if err != nil {
+					return nil, err
+				}
+				distance += step
+			}
+			prev = wpt
+			point, err := tcxTrackpoint(wpt, &distance)
+			if err != nil {
+				return nil, err
+			}
+			track.Trackpoint = append(track.Trackpoint, point)
+
+			if point.HeartRateBpm != nil {
+				heartRateSum += point.HeartRateBpm.Value
+				heartRateCount++
+				if lap.MaximumHeartRateBpm == nil || point.HeartRateBpm.Value > lap.MaximumHeartRateBpm.Value {
+					lap.MaximumHeartRateBpm = &TcxHeartRate{Value: point.HeartRateBpm.Value}
+				}
+			}
+			if point.Extensions != nil && point.Extensions.TPX.Speed != nil {
+				if lap.MaximumSpeed == nil || *point.Extensions.TPX.Speed > *lap.MaximumSpeed {
+					lap.MaximumSpeed = Optional(*point.Extensions.TPX.Speed)
+				}
+			}
+		}
+		if heartRateCount > 0 {
+			lap.AverageHeartRateBpm = &TcxHeartRate{Value: heartRateSum / heartRateCount}
+		}
+		lap.Track = append(lap.Track, track)
+		activity.Lap = append(activity.Lap, lap)
+	}
+	return activity, nil
+}
+
+func tcxCourse(trk *TrkType, segments [][]*WptType, algorithm DistanceAlgorithm) (*TcxCourse, error) {
+	// Name of course is limited to 15 characters.
+	name := []rune(trk.Name)
+	if len(name) > 15 {
+		name = name[:15]
+	}
+	course := &TcxCourse{Name: string(name), Notes: trk.Desc}
+
+	var all []*WptType
+	distance := 0.0
+	var prev *WptType
+	for _, segment := range segments {
+		all = append(all, segment...)
+		track := &TcxTrack{}
+		for _, wpt := range segment {
+			if prev != nil {
+				step, err := distanceBetween(prev, wpt, algorithm)
+				if err != nil {
+					return nil, err
+				}
+				distance += step
+			}
+			prev = wpt
+			point, err := tcxTrackpoint(wpt, &distance)
+			if err != nil {
+				return nil, err
+			}
+			track.Trackpoint = append(track.Trackpoint, point)
+		}
+		course.Track = append(course.Track, track)
+	}
+
+	convertibles := wptConvertibles(all)
+	duration, err := TotalTime(&convertibles)
+	if err != nil {
+		return nil, err
+	}
+	length, err := TotalLengthChecked(&convertibles, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	first, last := all[0], all[len(all)-1]
+	course.Lap = append(course.Lap, &TcxCourseLap{
+		TotalTimeSeconds:    duration.Seconds(),
+		DistanceMeters:      length,
+		BeginPosition:       &TcxPosition{LatitudeDegrees: first.LatAttr, LongitudeDegrees: first.LonAttr},
+		BeginAltitudeMeters: first.Ele,
+		EndPosition:         &TcxPosition{LatitudeDegrees: last.LatAttr, LongitudeDegrees: last.LonAttr},
+		EndAltitudeMeters:   last.Ele,
+		Intensity:           "Active",
+	})
+	return course, nil
+}
+
+// Convert the point to TCX track point, distance is the calculated
+// cumulative distance, it is replaced by distance from extensions
+// of the point if they have it.
+func tcxTrackpoint(wpt *WptType, distance *float64) (*TcxTrackpoint, error) {
+	data, err := wpt.GetSensorData()
+	if err != nil {
+		return nil, err
+	}
+	if data.Distance != nil {
+		*distance = *data.Distance
+	}
+
+	point := &TcxTrackpoint{
+		Time:           wpt.Time.String(),
+		Position:       &TcxPosition{LatitudeDegrees: wpt.LatAttr, LongitudeDegrees: wpt.LonAttr},
+		AltitudeMeters: wpt.Ele,
+		DistanceMeters: Optional(*distance),
+		Cadence:        data.Cadence,
+	}
+	if data.HeartRate != nil {
+		point.HeartRateBpm = &TcxHeartRate{Value: *data.HeartRate}
+	}
+	if data.Speed != nil || data.Power != nil {
+		point.Extensions = &TcxExtensions{TPX: &TcxTPX{Speed: data.Speed, Watts: data.Power}}
+	}
+	return point, nil
+}
+
+// Return sport of activity, TCX knows only Running, Biking and Other.
+func tcxSport(trkType, defaultSport string) string {
+	for _, sport := range []string{"Running", "Biking", "Other"} {
+		if strings.EqualFold(trkType, sport) {
+			return sport
+		}
+	}
+	if defaultSport != "" {
+		return defaultSport
+	}
+	return "Other"
+}
+
+// WriteTcxFile converts tracks of the Gpx struct to TCX activities and
+// writes them to a file or returns an error if the file could not be written.
+func WriteTcxFile(gpx Gpx, path string) (err error) {
+	tcxFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := tcxFile.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	return WriteTcx(tcxFile, gpx, TcxOptions{Indent: "    "})
+}
+
+// WriteTcx converts tracks of the Gpx struct to TCX document,
+// see ToTcx, and writes it to w.
+func WriteTcx(w io.Writer, gpx Gpx, opts TcxOptions) error {
+	tcx, err := ToTcx(gpx, opts)
+	if err != nil {
+		return err
+	}
+	return encodeXml(w, tcx, opts.Indent)
+}
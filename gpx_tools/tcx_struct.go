@@ -0,0 +1,138 @@
+package gpx_tools
+
+import (
+	"encoding/xml"
+)
+
+// TcxNamespace is the XML namespace of Garmin Training Center Database version 2.
+const TcxNamespace = "http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2"
+
+// TcxActivityExtensionNamespace is the namespace of the TPX
+// extension of track points holding speed and power.
+const TcxActivityExtensionNamespace = "http://www.garmin.com/xmlschemas/ActivityExtension/v2"
+
+// TcxType is the root element of TCX document.
+// Only activities and courses are modelled, other elements
+// (workouts, author, folders) are skipped when reading.
+type TcxType struct {
+	XMLName    xml.Name       `xml:"TrainingCenterDatabase"`
+	Activities *TcxActivities `xml:"Activities"`
+	Courses    *TcxCourses    `xml:"Courses"`
+}
+
+// TcxActivities is a list of recorded activities.
+type TcxActivities struct {
+	Activity []*TcxActivity `xml:"Activity"`
+}
+
+// TcxActivity is a recorded activity, Id is its start time.
+type TcxActivity struct {
+	Sport string    `xml:"Sport,attr"`
+	Id    string    `xml:"Id"`
+	Lap   []*TcxLap `xml:"Lap"`
+	Notes string    `xml:"Notes,omitempty"`
+}
+
+// TcxLap is a part of activity with its summary.
+type TcxLap struct {
+	StartTime           string        `xml:"StartTime,attr"`
+	TotalTimeSeconds    float64       `xml:"TotalTimeSeconds"`
+	DistanceMeters      float64       `xml:"DistanceMeters"`
+	MaximumSpeed        *float64      `xml:"MaximumSpeed,omitempty"`
+	Calories            int           `xml:"Calories"`
+	AverageHeartRateBpm *TcxHeartRate `xml:"AverageHeartRateBpm"`
+	MaximumHeartRateBpm *TcxHeartRate `xml:"MaximumHeartRateBpm"`
+	Intensity           string        `xml:"Intensity"`
+	Cadence             *int          `xml:"Cadence,omitempty"`
+	TriggerMethod       string        `xml:"TriggerMethod"`
+	Track               []*TcxTrack   `xml:"Track"`
+	Notes               string        `xml:"Notes,omitempty"`
+}
+
+// TcxTrack is a list of track points.
+type TcxTrack struct {
+	Trackpoint []*TcxTrackpoint `xml:"Trackpoint"`
+}
+
+// TcxTrackpoint is a single record of activity or course.
+// Position is missing for records of sensors without GPS fix.
+type TcxTrackpoint struct {
+	Time           string         `xml:"Time"`
+	Position       *TcxPosition   `xml:"Position"`
+	AltitudeMeters *float64       `xml:"AltitudeMeters,omitempty"`
+	DistanceMeters *float64       `xml:"DistanceMeters,omitempty"`
+	HeartRateBpm   *TcxHeartRate  `xml:"HeartRateBpm"`
+	Cadence        *int           `xml:"Cadence,omitempty"`
+	SensorState    string         `xml:"SensorState,omitempty"`
+	Extensions     *TcxExtensions `xml:"Extensions"`
+}
+
+// TcxPosition is a position in degrees.
+type TcxPosition struct {
+	LatitudeDegrees  float64 `xml:"LatitudeDegrees"`
+	LongitudeDegrees float64 `xml:"LongitudeDegrees"`
+}
+
+// TcxHeartRate is heart rate in beats per minute.
+type TcxHeartRate struct {
+	Value int `xml:"Value"`
+}
+
+// TcxExtensions holds the TPX extension of track point.
+type TcxExtensions struct {
+	TPX *TcxTPX `xml:"http://www.garmin.com/xmlschemas/ActivityExtension/v2 TPX"`
+}
+
+// TcxTPX holds speed in meters per second, cadence
+// of running in steps per minute and power in watts.
+type TcxTPX struct {
+	Speed      *float64 `xml:"Speed,omitempty"`
+	RunCadence *int     `xml:"RunCadence,omitempty"`
+	Watts      *int     `xml:"Watts,omitempty"`
+}
+
+// TcxCourses is a list of planned courses.
+type TcxCourses struct {
+	Course []*TcxCourse `xml:"Course"`
+}
+
+// TcxCourse is a planned course with points of interest.
+type TcxCourse struct {
+	Name        string            `xml:"Name"`
+	Lap         []*TcxCourseLap   `xml:"Lap"`
+	Track       []*TcxTrack       `xml:"Track"`
+	Notes       string            `xml:"Notes,omitempty"`
+	CoursePoint []*TcxCoursePoint `xml:"CoursePoint"`
+}
+
+// TcxCourseLap is a summary of part of course.
+type TcxCourseLap struct {
+	TotalTimeSeconds    float64      `xml:"TotalTimeSeconds"`
+	DistanceMeters      float64      `xml:"DistanceMeters"`
+	BeginPosition       *TcxPosition `xml:"BeginPosition"`
+	BeginAltitudeMeters *float64     `xml:"BeginAltitudeMeters,omitempty"`
+	EndPosition         *TcxPosition `xml:"EndPosition"`
+	EndAltitudeMeters   *float64     `xml:"EndAltitudeMeters,omitempty"`
+	Intensity           string       `xml:"Intensity"`
+}
+
+// TcxCoursePoint is a point of interest of course,
+// PointType is e.g. "Summit", "Water", "Left" or "Generic".
+type TcxCoursePoint struct {
+	Name           string      `xml:"Name"`
+	Time           string      `xml:"Time"`
+	Position       TcxPosition `xml:"Position"`
+	AltitudeMeters *float64    `xml:"AltitudeMeters,omitempty"`
+	PointType      string      `xml:"PointType"`
+	Notes          string      `xml:"Notes,omitempty"`
+}
+
+// MarshalXML encodes the root element in the TCX namespace.
+func (tcx *TcxType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type tcxAlias TcxType
+	start = xml.StartElement{
+		Name: xml.Name{Local: "TrainingCenterDatabase"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: TcxNamespace}},
+	}
+	return e.EncodeElement((*tcxAlias)(tcx), start)
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"gpx_tools"
+	"math"
+	"testing"
+)
+
+func TestFormatCoordinates(t *testing.T) {
+	c := gpx_tools.NewCoordinates(37.774889, -122.419389)
+	for _, test := range []struct {
+		format   gpx_tools.CoordinateFormat
+		expected string
+	}{
+		{gpx_tools.CoordinateFormat{}, `37.774889°N 122.419389°W`},
+		{gpx_tools.CoordinateFormat{Notation: gpx_tools.DegreesDecimalMinutes}, `37°46.493'N 122°25.163'W`},
+		{gpx_tools.CoordinateFormat{Notation: gpx_tools.DegreesMinutesSeconds}, `37°46'29.6"N 122°25'09.8"W`},
+		{gpx_tools.CoordinateFormat{Notation: gpx_tools.DegreesMinutesSeconds, Precision: gpx_tools.Optional(0),
+			HemisphereFirst: true, PartSeparator: " "}, `N 37° 46' 30" W 122° 25' 10"`},
+		{gpx_tools.CoordinateFormat{Precision: gpx_tools.Optional(4), Signed: true, NoSymbols: true, Separator: ", "}, `37.7749, -122.4194`},
+		{gpx_tools.CoordinateFormat{Notation: gpx_tools.DegreesDecimalMinutes, NoSymbols: true, Signed: true}, `37 46.493 -122 25.163`},
+	} {
+		if formatted := c.Format(test.format); formatted != test.expected {
+			t.Errorf(`Format(%+v) = %s; want %s`, test.format, formatted, test.expected)
+		}
+	}
+
+	// Rounding carries to minutes and degrees.
+	if formatted := gpx_tools.FormatLatitude(-0.99999, gpx_tools.CoordinateFormat{Notation: gpx_tools.DegreesMinutesSeconds}); formatted != `1°00'00.0"S` {
+		t.Errorf(`FormatLatitude(-0.99999) = %s; want 1°00'00.0"S`, formatted)
+	}
+	if formatted := gpx_tools.FormatLongitude(-0.0000001, gpx_tools.CoordinateFormat{Signed: true}); formatted != `0.000000°` {
+		t.Errorf(`FormatLongitude(-0.0000001) = %s; want 0.000000°`, formatted)
+	}
+	if formatted := gpx_tools.FormatLatitude(37.7749, gpx_tools.CoordinateFormat{Notation: 7}); formatted != `37.774900°N` {
+		t.Errorf(`FormatLatitude(37.7749) of unknown notation = %s; want 37.774900°N`, formatted)
+	}
+
+	c = gpx_tools.NewCoordinates(-37.5, -0.25)
+	if c.GetLatitudeDegrees() != -37 || c.GetLatitudeMinutes() != 30 || math.Abs(c.GetLatitudeSeconds()) > 1e-9 ||
+		c.GetLongitudeDegrees() != 0 || c.GetLongitudeMinutes() != 15 {
+		t.Errorf(`NewCoordinates(-37.5, -0.25) parts = %d %d %f, %d %d`, c.GetLatitudeDegrees(), c.GetLatitudeMinutes(),
+			c.GetLatitudeSeconds(), c.GetLongitudeDegrees(), c.GetLongitudeMinutes())
+	}
+	if c.ToString() != `37°30'00.0"S, 0°15'00.0"W` {
+		t.Errorf(`ToString() = %s; want 37°30'00.0"S, 0°15'00.0"W`, c.ToString())
+	}
+}
+
+func TestParseCoordinates(t *testing.T) {
+	for _, test := range []struct {
+		text     string
+		lat, lon float64
+	}{
+		{`37°46'29.6"N 122°25'9.8"W`, 37 + 46/60.0 + 29.6/3600, -(122 + 25/60.0 + 9.8/3600)},
+		{`N 37° 46.5' W 122° 25.2'`, 37.775, -122.42},
+		{`37.7749, -122.4194`, 37.7749, -122.4194},
+		{`37.7749 -122.4194`, 37.7749, -122.4194},
+		{`122.5W 37.5N`, 37.5, -122.5},
+		{`37 46 30 S 122 25 12 E`, -37.775, 122.42},
+		{`37°46′30″ 122°25′12″`, 37.775, 122.42},
+		{`-37 30; 0 30`, -37.5, 0.5},
+		{`37°46'30''N, 180°E`, 37.775, -180},
+	} {
+		c, err := gpx_tools.ParseCoordinates(test.text)
+		if err != nil {
+			t.Errorf(`ParseCoordinates(%s) = %v; want nil`, test.text, err)
+			continue
+		}
+		if math.Abs(c.Latitude-test.lat) > 1e-9 || math.Abs(c.GetLongitude()-test.lon) > 1e-9 {
+			t.Errorf(`ParseCoordinates(%s) = %v, %v; want %v, %v`, test.text, c.Latitude, c.GetLongitude(), test.lat, test.lon)
+		}
+	}
+
+	for _, invalid := range []string{`91 0`, `37.5N 12.5N`, `37 61 N 12 E`, `37.5 30 N 12 E`, `-37 S 12 E`, `37.5`, `37 x 12`, `1, 2, 3`, `37 -30 12 5`} {
+		if c, err := gpx_tools.ParseCoordinates(invalid); err == nil {
+			t.Errorf(`ParseCoordinates(%s) = %v; want error`, invalid, c)
+		}
+	}
+
+	if lat, err := gpx_tools.ParseLatitude(`12°30.5'S`); err != nil || math.Abs(lat+12+30.5/60) > 1e-9 {
+		t.Errorf(`ParseLatitude(12°30.5'S) = %v, %v; want -12.508333`, lat, err)
+	}
+	if _, err := gpx_tools.ParseLatitude(`12°E`); err == nil {
+		t.Errorf(`ParseLatitude(12°E) = nil; want error`)
+	}
+	if lon, err := gpx_tools.ParseLongitude(`+180`); err != nil || lon != -180 {
+		t.Errorf(`ParseLongitude(+180) = %v, %v; want -180`, lon, err)
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"gpx_tools"
+	"math"
+	"testing"
+)
+
+func assertCoordinates(t *testing.T, name string, c gpx_tools.Coordinates, lat, lon, tolerance float64) {
+	t.Helper()
+	if math.Abs(c.Latitude-lat) > tolerance || math.Abs(c.GetLongitude()-lon) > tolerance {
+		t.Errorf(`%s = %v, %v; want %v, %v`, name, c.Latitude, c.GetLongitude(), lat, lon)
+	}
+}
+
+func TestBearings(t *testing.T) {
+	cambridge := gpx_tools.NewCoordinates(52.205, 0.119)
+	paris := gpx_tools.NewCoordinates(48.857, 2.351)
+	if bearing := cambridge.InitialBearingTo(paris); math.Abs(float64(bearing)-156.1666) > 1e-4 {
+		t.Errorf(`InitialBearingTo() = %v; want 156.1666`, bearing)
+	}
+	if bearing := cambridge.FinalBearingTo(paris); math.Abs(float64(bearing)-157.8904) > 1e-4 {
+		t.Errorf(`FinalBearingTo() = %v; want 157.8904`, bearing)
+	}
+	west := gpx_tools.NewCoordinates(0, -1)
+	if bearing := cambridge.InitialBearingTo(west); bearing < 180 || bearing >= 360 {
+		t.Errorf(`InitialBearingTo() = %v; want in [180, 360)`, bearing)
+	}
+}
+
+func TestGreatCirclePoints(t *testing.T) {
+	cambridge := gpx_tools.NewCoordinates(52.205, 0.119)
+	paris := gpx_tools.NewCoordinates(48.857, 2.351)
+	assertCoordinates(t, "MidpointTo()", cambridge.MidpointTo(paris), 50.5363, 1.2746, 1e-4)
+	assertCoordinates(t, "IntermediatePointTo(0.25)", cambridge.IntermediatePointTo(paris, 0.25), 51.3721, 0.7073, 1e-4)
+
+	greenwich := gpx_tools.NewCoordinates(51.4778, -0.0015)
+	assertCoordinates(t, "DestinationPoint()", greenwich.DestinationPoint(7794, 300.7), 51.5135, -0.0983, 1e-4)
+}
+
+func TestIntersection(t *testing.T) {
+	stansted := gpx_tools.NewCoordinates(51.8853, 0.2545)
+	cdg := gpx_tools.NewCoordinates(49.0034, 2.5735)
+	intersection, err := stansted.Intersection(108.547, cdg, 32.435)
+	if err != nil {
+		t.Fatalf(`Intersection() = %v; want nil`, err)
+	}
+	assertCoordinates(t, "Intersection()", intersection, 50.9078, 4.5084, 1e-4)
+
+	// Paths heading away from each other meet only behind the starting points.
+	if _, err := stansted.Intersection(300, cdg, 120); err == nil {
+		t.Errorf(`Intersection() = nil; want error`)
+	}
+
+	ellipsoidal, err := stansted.VincentyIntersection(108.547, cdg, 32.435)
+	if err != nil {
+		t.Fatalf(`VincentyIntersection() = %v; want nil`, err)
+	}
+	assertCoordinates(t, "VincentyIntersection()", ellipsoidal, 50.9078, 4.5084, 0.05)
+	for _, start := range []struct {
+		c       gpx_tools.Coordinates
+		bearing float64
+	}{{stansted, 108.547}, {cdg, 32.435}} {
+		bearing, err := start.c.VincentyInitialBearingTo(ellipsoidal)
+		if err != nil || math.Abs(float64(bearing)-start.bearing) > 1e-6 {
+			t.Errorf(`VincentyInitialBearingTo() = %v, %v; want %v`, bearing, err, start.bearing)
+		}
+	}
+}
+
+func TestVincenty(t *testing.T) {
+	flindersPeak := gpx_tools.NewCoordinates(-37.95103342, 144.42486789)
+	buninyong := gpx_tools.NewCoordinates(-37.65282114, 143.92649554)
+	distance, initial, final, err := gpx_tools.VincentyInverse(flindersPeak, buninyong)
+	if err != nil || math.Abs(distance-54972.271) > 1e-3 ||
+		math.Abs(float64(initial)-306.86816) > 1e-5 || math.Abs(float64(final)-307.17363) > 1e-5 {
+		t.Errorf(`VincentyInverse() = %v, %v, %v, %v; want 54972.271, 306.86816, 307.17363`, distance, initial, final, err)
+	}
+
+	destination, final := gpx_tools.VincentyDirect(flindersPeak, 54972.271, 306+52/60.0+5.37/3600)
+	assertCoordinates(t, "VincentyDirect()", destination, -37.65282114, 143.92649554, 1e-7)
+	if math.Abs(float64(final)-307.17363) > 1e-5 {
+		t.Errorf(`VincentyDirect() final bearing = %v; want 307.17363`, final)
+	}
+
+	midpoint, err := flindersPeak.VincentyMidpointTo(buninyong)
+	if err != nil {
+		t.Fatalf(`VincentyMidpointTo() = %v; want nil`, err)
+	}
+	first, _, _, _ := gpx_tools.VincentyInverse(flindersPeak, midpoint)
+	second, _, _, _ := gpx_tools.VincentyInverse(midpoint, buninyong)
+	if math.Abs(first-distance/2) > 1e-3 || math.Abs(second-distance/2) > 1e-3 {
+		t.Errorf(`VincentyMidpointTo() splits distance to %v and %v; want %v`, first, second, distance/2)
+	}
+
+	if _, _, _, err := gpx_tools.VincentyInverse(gpx_tools.NewCoordinates(0, 0), gpx_tools.NewCoordinates(0.5, 179.7)); err == nil {
+		t.Errorf(`VincentyInverse() of antipodal points = nil; want error`)
+	}
+}
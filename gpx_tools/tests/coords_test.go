@@ -3,6 +3,7 @@ package tests
 import (
 	"encoding/xml"
 	"gpx_tools"
+	"math"
 	"os"
 	"testing"
 )
@@ -96,3 +97,79 @@ func TestWrite(t *testing.T) {
 	})
 
 }
+
+func TestNewCoordinates(t *testing.T) {
+	for _, test := range []struct {
+		lat, lon, expectedLat, expectedLon float64
+	}{
+		{95, 10, 85, -170},
+		{-100, -170, -80, 10},
+		{90, 180, 90, -180},
+		{270, 0, -90, 0},
+		{45, 540, 45, -180},
+		{45, -181, 45, 179},
+	} {
+		c := gpx_tools.NewCoordinates(test.lat, test.lon)
+		if c.GetLatitude() != test.expectedLat || c.GetLongitude() != test.expectedLon {
+			t.Errorf(`NewCoordinates(%v, %v) = %v, %v; want %v, %v`, test.lat, test.lon,
+				c.GetLatitude(), c.GetLongitude(), test.expectedLat, test.expectedLon)
+		}
+	}
+
+	if c, err := gpx_tools.NewCoordinatesStrict(-90, 190); err != nil || c.Latitude != -90 || c.GetLongitude() != -170 {
+		t.Errorf(`NewCoordinatesStrict(-90, 190) = %v, %v; want -90, -170`, c, err)
+	}
+	for _, invalid := range [][2]float64{{95, 10}, {-90.5, 0}, {math.NaN(), 0}, {0, math.Inf(1)}} {
+		if _, err := gpx_tools.NewCoordinatesStrict(invalid[0], invalid[1]); err == nil {
+			t.Errorf(`NewCoordinatesStrict(%v, %v) = nil; want error`, invalid[0], invalid[1])
+		}
+	}
+
+	var c gpx_tools.Coordinates
+	if err := c.SetLatitude(91); err == nil || c.Latitude != 0 {
+		t.Errorf(`SetLatitude(91) = %v; want error and unchanged latitude`, err)
+	}
+	if err := c.SetLongitude(180); err != nil || c.GetLongitude() != -180 {
+		t.Errorf(`SetLongitude(180) = %v, longitude %v; want -180`, err, c.GetLongitude())
+	}
+	if lon := gpx_tools.WrapLongitude(math.Nextafter(-180, -200)); lon < -180 || lon >= 180 {
+		t.Errorf(`WrapLongitude(just below -180) = %v; want in [-180, 180)`, lon)
+	}
+}
+
+func TestToCoordinatesChecked(t *testing.T) {
+	wpt := &gpx_tools.WptType{LatAttr: 95, LonAttr: 10}
+	if _, err := wpt.ToCoordinatesChecked(); err == nil {
+		t.Errorf(`ToCoordinatesChecked() = nil; want error of latitude 95`)
+	}
+	if c := wpt.ToCoordinates(); c.Latitude != 85 || c.GetLongitude() != -170 {
+		t.Errorf(`ToCoordinates() = %v; want 85, -170`, c)
+	}
+	pt := &gpx_tools.PtType{LatAttr: 50, LonAttr: 14}
+	if c, err := pt.ToCoordinatesChecked(); err != nil || c.Latitude != 50 || c.GetLongitude() != 14 {
+		t.Errorf(`ToCoordinatesChecked() = %v, %v; want 50, 14`, c, err)
+	}
+
+	bounds := &gpx_tools.BoundsType{MinlatAttr: 50, MinlonAttr: 14, MaxlatAttr: 51, MaxlonAttr: 15}
+	if minCoords, maxCoords, err := bounds.ToCoordinatesChecked(); err != nil || minCoords.Latitude != 50 || maxCoords.GetLongitude() != 15 {
+		t.Errorf(`ToCoordinatesChecked() = %v, %v, %v; want bounds`, minCoords, maxCoords, err)
+	}
+	for _, invalid := range []*gpx_tools.BoundsType{
+		{MinlatAttr: 52, MinlonAttr: 14, MaxlatAttr: 51, MaxlonAttr: 15},
+		{MinlatAttr: 50, MinlonAttr: 14, MaxlatAttr: 91, MaxlonAttr: 15},
+	} {
+		if _, _, err := invalid.ToCoordinatesChecked(); err == nil {
+			t.Errorf(`ToCoordinatesChecked(%+v) = nil; want error`, invalid)
+		}
+	}
+
+	// Checked functions report invalid points instead of moving them.
+	points := []gpx_tools.CoordConvertible{pt, wpt}
+	if _, err := gpx_tools.TotalLengthChecked(&points, gpx_tools.Haversine); err == nil {
+		t.Errorf(`TotalLengthChecked() with latitude 95 = nil; want error`)
+	}
+	single := points[:1]
+	if length, err := gpx_tools.TotalLengthChecked(&single, gpx_tools.Haversine); err != nil || length != 0 {
+		t.Errorf(`TotalLengthChecked() of one point = %v, %v; want 0`, length, err)
+	}
+}
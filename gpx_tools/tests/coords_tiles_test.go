@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"gpx_tools"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGeohash(t *testing.T) {
+	c := gpx_tools.NewCoordinates(57.64911, 10.40744)
+	if hash := c.Geohash(11); hash != "u4pruydqqvj" {
+		t.Errorf(`Geohash(11) = %s; want u4pruydqqvj`, hash)
+	}
+	if hash := c.Geohash(3); hash != "u4p" {
+		t.Errorf(`Geohash(3) = %s; want u4p`, hash)
+	}
+	if hash := c.Geohash(-1); hash != "" {
+		t.Errorf(`Geohash(-1) = %s; want empty`, hash)
+	}
+
+	decoded, err := gpx_tools.DecodeGeohash("u4pruydqqvj")
+	if err != nil || math.Abs(decoded.Latitude-57.64911) > 1e-5 || math.Abs(decoded.GetLongitude()-10.40744) > 1e-5 {
+		t.Errorf(`DecodeGeohash(u4pruydqqvj) = %v, %v; want 57.64911, 10.40744`, decoded, err)
+	}
+	if _, err := gpx_tools.DecodeGeohash("u4pa"); err == nil {
+		t.Errorf(`DecodeGeohash(u4pa) = nil; want error`)
+	}
+}
+
+func TestGeohashNeighbors(t *testing.T) {
+	neighbors, err := gpx_tools.GeohashNeighbors("gbsuv")
+	expected := [8]string{"gbsvj", "gbsvn", "gbsuy", "gbsuw", "gbsut", "gbsus", "gbsuu", "gbsvh"}
+	if err != nil || neighbors != expected {
+		t.Errorf(`GeohashNeighbors(gbsuv) = %v, %v; want %v`, neighbors, err, expected)
+	}
+
+	// Cells wrap over the antimeridian but not over the poles.
+	if east, err := gpx_tools.GeohashNeighbor("z", gpx_tools.East); err != nil || east != "b" {
+		t.Errorf(`GeohashNeighbor(z, East) = %s, %v; want b`, east, err)
+	}
+	if _, err := gpx_tools.GeohashNeighbor("z", gpx_tools.North); err == nil {
+		t.Errorf(`GeohashNeighbor(z, North) = nil; want error`)
+	}
+}
+
+func TestWebMercator(t *testing.T) {
+	c := gpx_tools.NewCoordinates(48.8582, 2.2945)
+	x, y := c.ToWebMercator()
+	if math.Abs(x-255422.572) > 1e-3 || math.Abs(y-6250835.062) > 1e-3 {
+		t.Errorf(`ToWebMercator() = %v, %v; want 255422.572, 6250835.062`, x, y)
+	}
+	back := gpx_tools.WebMercatorToCoordinates(x, y)
+	if math.Abs(back.Latitude-48.8582) > 1e-9 || math.Abs(back.GetLongitude()-2.2945) > 1e-9 {
+		t.Errorf(`WebMercatorToCoordinates(%v, %v) = %v; want 48.8582, 2.2945`, x, y, back)
+	}
+}
+
+func TestToTile(t *testing.T) {
+	c := gpx_tools.NewCoordinates(48.8582, 2.2945)
+	tile, pixelX, pixelY := c.ToTile(15)
+	if tile != (gpx_tools.Tile{X: 16592, Y: 11272, Zoom: 15}) || math.Abs(pixelX-217.725) > 1e-3 || math.Abs(pixelY-230.748) > 1e-3 {
+		t.Errorf(`ToTile(15) = %v, %v, %v; want {16592 11272 15}, 217.725, 230.748`, tile, pixelX, pixelY)
+	}
+
+	bounds := tile.Bounds()
+	if !(bounds.MinlatAttr < c.Latitude && c.Latitude < bounds.MaxlatAttr &&
+		bounds.MinlonAttr < c.GetLongitude() && c.GetLongitude() < bounds.MaxlonAttr) {
+		t.Errorf(`Bounds() = %v; want containing %v`, bounds, c)
+	}
+	world := gpx_tools.Tile{}.Bounds()
+	if math.Abs(world.MaxlatAttr-gpx_tools.WebMercatorMaxLatitude) > 1e-9 || world.MinlonAttr != -180 || world.MaxlonAttr != 180 {
+		t.Errorf(`Tile{}.Bounds() = %v; want the whole map`, world)
+	}
+}
+
+func TestBoundsTiles(t *testing.T) {
+	bounds := gpx_tools.BoundsType{MinlatAttr: 48.8, MaxlatAttr: 48.9, MinlonAttr: 2.2, MaxlonAttr: 2.5}
+	expected := []gpx_tools.Tile{{X: 518, Y: 352, Zoom: 10}, {X: 519, Y: 352, Zoom: 10}}
+	if tiles := bounds.Tiles(10); !reflect.DeepEqual(tiles, expected) {
+		t.Errorf(`Tiles(10) = %v; want %v`, tiles, expected)
+	}
+
+	antimeridian := gpx_tools.BoundsType{MinlatAttr: -1, MaxlatAttr: 1, MinlonAttr: 179, MaxlonAttr: -179}
+	expected = []gpx_tools.Tile{{X: 3, Y: 1, Zoom: 2}, {X: 0, Y: 1, Zoom: 2}, {X: 3, Y: 2, Zoom: 2}, {X: 0, Y: 2, Zoom: 2}}
+	if tiles := antimeridian.Tiles(2); !reflect.DeepEqual(tiles, expected) {
+		t.Errorf(`Tiles(2) = %v; want %v`, tiles, expected)
+	}
+}
+
+func TestTrkTiles(t *testing.T) {
+	trk := gpx_tools.TrkType{Trkseg: []*gpx_tools.TrksegType{{Trkpt: []*gpx_tools.WptType{
+		{LatAttr: 10, LonAttr: -10},
+		{LatAttr: -10, LonAttr: 30},
+	}}}}
+	expected := []gpx_tools.Tile{{X: 0, Y: 0, Zoom: 1}, {X: 1, Y: 0, Zoom: 1}, {X: 1, Y: 1, Zoom: 1}}
+	if tiles := trk.Tiles(1); !reflect.DeepEqual(tiles, expected) {
+		t.Errorf(`Tiles(1) = %v; want %v`, tiles, expected)
+	}
+
+	// The shorter way crosses the antimeridian.
+	for _, lons := range [][2]float64{{179.9, -179.9}, {-179.9, 179.9}} {
+		antimeridian := gpx_tools.TrkType{Trkseg: []*gpx_tools.TrksegType{{Trkpt: []*gpx_tools.WptType{
+			{LatAttr: 0.1, LonAttr: lons[0]},
+			{LatAttr: 0.1, LonAttr: lons[1]},
+		}}}}
+		expected = []gpx_tools.Tile{{X: 0, Y: 511, Zoom: 10}, {X: 1023, Y: 511, Zoom: 10}}
+		if tiles := antimeridian.Tiles(10); !reflect.DeepEqual(tiles, expected) {
+			t.Errorf(`Tiles(10) from %v to %v = %v; want %v`, lons[0], lons[1], tiles, expected)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"gpx_tools"
+	"math"
+	"testing"
+)
+
+func TestToUTM(t *testing.T) {
+	for _, test := range []struct {
+		lat, lon float64
+		expected string
+		mgrs     string
+	}{
+		{48.8582, 2.2945, "31U 448252 5411933", "31U DQ 48251 11932"},
+		{-33.857, 151.215, "56H 334873 6252266", "56H LH 34873 52266"},
+		{60, 4, "32V 221289 6661953", "32V KM 21288 61953"},
+		{78, 10, "33X 384085 8663320", "33X UG 84085 63320"},
+		{0, 0, "31N 166021 0", "31N AA 66021 00000"},
+	} {
+		c := gpx_tools.NewCoordinates(test.lat, test.lon)
+		utm, err := c.ToUTM()
+		if err != nil {
+			t.Fatalf(`ToUTM(%v, %v) = %v; want nil`, test.lat, test.lon, err)
+		}
+		if utm.String() != test.expected {
+			t.Errorf(`ToUTM(%v, %v) = %s; want %s`, test.lat, test.lon, utm, test.expected)
+		}
+		back, err := utm.ToCoordinates()
+		if err != nil || math.Abs(back.Latitude-test.lat) > 1e-9 || math.Abs(back.GetLongitude()-test.lon) > 1e-9 {
+			t.Errorf(`ToUTM(%v, %v).ToCoordinates() = %v, %v; want the same coordinates`, test.lat, test.lon, back, err)
+		}
+
+		mgrs, err := c.ToMGRS(5)
+		if err != nil || mgrs != test.mgrs {
+			t.Errorf(`ToMGRS(%v, %v) = %s, %v; want %s`, test.lat, test.lon, mgrs, err, test.mgrs)
+		}
+		parsed, err := gpx_tools.ParseMGRS(mgrs)
+		if err != nil || parsed.Zone != utm.Zone || parsed.Band != utm.Band ||
+			math.Abs(parsed.Easting-utm.Easting) > 1 || math.Abs(parsed.Northing-utm.Northing) > 1 {
+			t.Errorf(`ParseMGRS(%s) = %+v, %v; want %+v`, mgrs, parsed, err, utm)
+		}
+	}
+
+	for _, lat := range []float64{-80.5, 84.5} {
+		c := gpx_tools.NewCoordinates(lat, 0)
+		if _, err := c.ToUTM(); err == nil {
+			t.Errorf(`ToUTM(%v, 0) = nil; want error`, lat)
+		}
+	}
+}
+
+func TestParseMGRS(t *testing.T) {
+	c := gpx_tools.NewCoordinates(48.8582, 2.2945)
+	if mgrs, _ := c.ToMGRS(2); mgrs != "31U DQ 48 11" {
+		t.Errorf(`ToMGRS(2) = %s; want 31U DQ 48 11`, mgrs)
+	}
+	if mgrs, _ := c.ToMGRS(0); mgrs != "31U DQ" {
+		t.Errorf(`ToMGRS(0) = %s; want 31U DQ`, mgrs)
+	}
+
+	utm, err := gpx_tools.ParseMGRS("31udq4811")
+	if err != nil || utm.String() != "31U 448000 5411000" {
+		t.Errorf(`ParseMGRS(31udq4811) = %s, %v; want 31U 448000 5411000`, utm, err)
+	}
+	for _, invalid := range []string{"31U DQ 481", "61U DQ 48 11", "31I DQ", "31U IQ 48 11", "31U"} {
+		if _, err := gpx_tools.ParseMGRS(invalid); err == nil {
+			t.Errorf(`ParseMGRS(%s) = nil; want error`, invalid)
+		}
+	}
+
+	utm, err = gpx_tools.ParseUTM("56 H 334873 6252266")
+	if err != nil || utm.Zone != 56 || utm.Band != 'H' || utm.IsNorthern() {
+		t.Errorf(`ParseUTM() = %+v, %v; want zone 56H`, utm, err)
+	}
+	if c, err := utm.ToCoordinates(); err != nil || math.Abs(c.Latitude+33.857) > 1e-5 || math.Abs(c.GetLongitude()-151.215) > 1e-5 {
+		t.Errorf(`ParseUTM().ToCoordinates() = %v, %v; want -33.857, 151.215`, c, err)
+	}
+	if _, err := gpx_tools.ParseUTM("33U 455678"); err == nil {
+		t.Errorf(`ParseUTM(33U 455678) = nil; want error`)
+	}
+}
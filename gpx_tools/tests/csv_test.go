@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"bytes"
+	"gpx_tools"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteCsv(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxFile("sample.gpx")
+	if err != nil {
+		t.Fatalf(`ParseGpxFile("sample.gpx") = %v; want nil`, err)
+	}
+
+	var buffer bytes.Buffer
+	if err := gpx_tools.WriteCsv(&buffer, gpx, gpx_tools.CsvWriteOptions{}); err != nil {
+		t.Fatalf(`WriteCsv() = %v; want nil`, err)
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 5 || lines[0] != "kind,track,segment,lat,lon,ele,time,name,distance,speed" {
+		t.Fatalf(`WriteCsv() = %q; want header and 4 rows`, lines)
+	}
+	if lines[1] != "wpt,,,37.7749,-122.4194,10,2023-11-25T12:00:00Z,Waypoint 1,," {
+		t.Errorf(`WriteCsv() waypoint row = %q`, lines[1])
+	}
+	if !strings.HasPrefix(lines[4], "trkpt,0,0,37.7751,-122.4196,15,2023-11-25T12:15:00Z,,28.") {
+		t.Errorf(`WriteCsv() track point row = %q; want distance 28 m`, lines[4])
+	}
+
+	parsed, err := gpx_tools.ParseCsv(&buffer, gpx_tools.CsvMapping{})
+	if err != nil {
+		t.Fatalf(`ParseCsv(WriteCsv()) = %v; want nil`, err)
+	}
+	if len(parsed.Wpt) != 2 || parsed.Wpt[1].Name != "Waypoint 2" || len(parsed.Trk) != 1 || len(parsed.Trk[0].Trkseg[0].Trkpt) != 2 {
+		t.Errorf(`ParseCsv(WriteCsv()) = %+v; want 2 waypoints and a track`, parsed)
+	}
+	if trkpt := parsed.Trk[0].Trkseg[0].Trkpt[1]; *trkpt.Ele != 15 || trkpt.Time.String() != "2023-11-25T12:15:00Z" {
+		t.Errorf(`ParseCsv(WriteCsv()) track point = %+v`, trkpt)
+	}
+}
+
+func TestParseCsvMapping(t *testing.T) {
+	input := "Trip;Y;X;Height;When;Label;Satellites\n" +
+		"a;50.1;14.1;1000;25.11.2023 12:00;Start;7\n" +
+		"a;50.2;14.2;1100;25.11.2023 12:10;;8\n" +
+		"b;50.3;14.3;;25.11.2023 12:20;Other;\n"
+	gpx, err := gpx_tools.ParseCsv(strings.NewReader(input), gpx_tools.CsvMapping{
+		Columns: map[gpx_tools.CsvColumn]string{
+			gpx_tools.CsvTrack: "Trip",
+			gpx_tools.CsvLat:   "Y",
+			gpx_tools.CsvLon:   "X",
+			gpx_tools.CsvEle:   "Height",
+			gpx_tools.CsvTime:  "When",
+			gpx_tools.CsvName:  "Label",
+			gpx_tools.CsvSat:   "Satellites",
+		},
+		Delimiter:     ';',
+		TimeFormat:    "02.01.2006 15:04",
+		TimeLocation:  time.FixedZone("", 3600),
+		ElevationUnit: gpx_tools.UnitFeet,
+		Kind:          "rtept",
+	})
+	if err != nil {
+		t.Fatalf(`ParseCsv() = %v; want nil`, err)
+	}
+	if len(gpx.Rte) != 2 || len(gpx.Rte[0].Rtept) != 2 || len(gpx.Rte[1].Rtept) != 1 {
+		t.Fatalf(`ParseCsv().Rte = %+v; want routes of 2 and 1 points`, gpx.Rte)
+	}
+	rtept := gpx.Rte[0].Rtept[0]
+	if rtept.LatAttr != 50.1 || rtept.LonAttr != 14.1 || *rtept.Ele != 304.8 || rtept.Name != "Start" || *rtept.Sat != 7 ||
+		rtept.Time.Time.UTC().Format(time.RFC3339) != "2023-11-25T11:00:00Z" {
+		t.Errorf(`ParseCsv() route point = %+v`, rtept)
+	}
+
+	if _, err := gpx_tools.ParseCsv(strings.NewReader("lat,lon\n1,x\n"), gpx_tools.CsvMapping{}); err == nil || !strings.Contains(err.Error(), "Row 2") {
+		t.Errorf(`ParseCsv(invalid longitude) = %v; want error of row 2`, err)
+	}
+}
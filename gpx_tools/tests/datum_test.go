@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"gpx_tools"
+	"math"
+	"testing"
+)
+
+func TestECEF(t *testing.T) {
+	c := gpx_tools.NewCoordinates3D(48.8582, 2.2945, 330)
+	x, y, z := gpx_tools.WGS84Ellipsoid.ToECEF(c)
+	back := gpx_tools.WGS84Ellipsoid.FromECEF(x, y, z)
+	if math.Abs(back.Coordinates.Latitude-48.8582) > 1e-9 || math.Abs(back.Coordinates.GetLongitude()-2.2945) > 1e-9 || math.Abs(back.Altitude-330) > 1e-3 {
+		t.Errorf(`FromECEF(ToECEF(%v)) = %v`, c, back)
+	}
+
+	// Points on the axes.
+	x, y, z = gpx_tools.WGS84Ellipsoid.ToECEF(gpx_tools.NewCoordinates3D(0, 90, 0))
+	if math.Abs(x) > 1e-6 || math.Abs(y-6378137) > 1e-6 || math.Abs(z) > 1e-6 {
+		t.Errorf(`ToECEF(0, 90, 0) = %v, %v, %v; want 0, 6378137, 0`, x, y, z)
+	}
+	pole := gpx_tools.WGS84Ellipsoid.FromECEF(0, 0, 6356752.314245)
+	if math.Abs(pole.Coordinates.Latitude-90) > 1e-9 || math.Abs(pole.Altitude) > 1e-3 {
+		t.Errorf(`FromECEF(0, 0, b) = %v; want the north pole`, pole)
+	}
+}
+
+func TestDatumTransform(t *testing.T) {
+	// Example point of Ordnance Survey guide to coordinate systems.
+	osgb := gpx_tools.NewCoordinates3D(52+39/60.0+27.2531/3600, 1+43/60.0+4.5177/3600, 0)
+	wgs := osgb.ToWGS84(gpx_tools.OSGB36)
+	if math.Abs(wgs.Coordinates.Latitude-52.6580) > 1e-4 || math.Abs(wgs.Coordinates.GetLongitude()-1.7161) > 1e-4 {
+		t.Errorf(`ToWGS84(OSGB36) = %v; want 52.6580, 1.7161`, wgs)
+	}
+
+	for _, datum := range []*gpx_tools.Datum{gpx_tools.OSGB36, gpx_tools.ED50, gpx_tools.NAD27, gpx_tools.SJTSK} {
+		c := gpx_tools.NewCoordinates3D(50, 15, 200)
+		local := c.FromWGS84(datum)
+		if math.Abs(local.Coordinates.Latitude-50)+math.Abs(local.Coordinates.GetLongitude()-15) < 1e-5 {
+			t.Errorf(`FromWGS84(%s) = %v; want shifted coordinates`, datum.Name, local)
+		}
+		back := local.ToWGS84(datum)
+		if math.Abs(back.Coordinates.Latitude-50) > 1e-7 || math.Abs(back.Coordinates.GetLongitude()-15) > 1e-7 || math.Abs(back.Altitude-200) > 1e-6 {
+			t.Errorf(`ToWGS84(FromWGS84(%s)) = %v; want 50, 15, 200`, datum.Name, back)
+		}
+	}
+}
+
+func TestGetDatum(t *testing.T) {
+	if datum, err := gpx_tools.GetDatum("s-jtsk"); err != nil || datum != gpx_tools.SJTSK {
+		t.Errorf(`GetDatum(s-jtsk) = %v, %v; want S-JTSK`, datum, err)
+	}
+	if _, err := gpx_tools.GetDatum("Tokyo"); err == nil {
+		t.Errorf(`GetDatum(Tokyo) = nil; want error`)
+	}
+}
+
+func TestFromDatum(t *testing.T) {
+	gpx := gpx_tools.NewGpx(gpx_tools.DefaultCreator)
+	gpx.Wpt = []*gpx_tools.WptType{{LatAttr: 51.5, LonAttr: -0.1}}
+	gpx.Trk = []*gpx_tools.TrkType{{Trkseg: []*gpx_tools.TrksegType{{Trkpt: []*gpx_tools.WptType{
+		{LatAttr: 51.5, LonAttr: -0.1, Ele: gpx_tools.Optional(20.0)},
+	}}}}}
+	if err := gpx.FromDatum(gpx_tools.OSGB36); err != nil {
+		t.Fatalf(`FromDatum(OSGB36) = %v; want nil`, err)
+	}
+
+	expected := gpx_tools.NewCoordinates3D(51.5, -0.1, 0).ToWGS84(gpx_tools.OSGB36)
+	wpt := gpx.Wpt[0]
+	if wpt.LatAttr != expected.Coordinates.Latitude || wpt.LonAttr != expected.Coordinates.GetLongitude() || wpt.HasElevation() {
+		t.Errorf(`FromDatum(OSGB36) waypoint = %v, %v, %v; want %v`, wpt.LatAttr, wpt.LonAttr, wpt.Ele, expected)
+	}
+	trkpt := gpx.Trk[0].Trkseg[0].Trkpt[0]
+	if *trkpt.Ele != 20 || trkpt.LatAttr != expected.Coordinates.Latitude {
+		t.Errorf(`FromDatum(OSGB36) track point = %v, %v; want %v, elevation 20`, trkpt.LatAttr, *trkpt.Ele, expected.Coordinates.Latitude)
+	}
+	if name, err := trkpt.GetSourceDatum(); err != nil || name != "OSGB36" {
+		t.Errorf(`GetSourceDatum() = %s, %v; want OSGB36`, name, err)
+	}
+	if gpx.Namespaces["datum"] != gpx_tools.DatumNamespace {
+		t.Errorf(`Namespaces = %v; want datum prefix declared`, gpx.Namespaces)
+	}
+
+	// Converting again skips the points already tagged.
+	if err := gpx.FromDatum(gpx_tools.OSGB36); err != nil || wpt.LatAttr != expected.Coordinates.Latitude {
+		t.Errorf(`FromDatum(OSGB36) twice = %v, %v; want nil, %v`, err, wpt.LatAttr, expected.Coordinates.Latitude)
+	}
+
+	invalid := gpx_tools.NewGpx(gpx_tools.DefaultCreator)
+	invalid.Wpt = []*gpx_tools.WptType{{LatAttr: 51.5, LonAttr: -0.1}}
+	invalid.Trk = []*gpx_tools.TrkType{{Trkseg: []*gpx_tools.TrksegType{{Trkpt: []*gpx_tools.WptType{
+		{LatAttr: 95, LonAttr: -0.1},
+	}}}}}
+	if err := invalid.FromDatum(gpx_tools.OSGB36); err == nil {
+		t.Errorf(`FromDatum(OSGB36) of latitude 95 = nil; want error`)
+	}
+	if wpt := invalid.Wpt[0]; wpt.LatAttr != 51.5 || wpt.LonAttr != -0.1 || wpt.Extensions != nil {
+		t.Errorf(`FromDatum(OSGB36) of latitude 95 changed waypoint to %v, %v`, wpt.LatAttr, wpt.LonAttr)
+	}
+}
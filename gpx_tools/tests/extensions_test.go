@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/xml"
+	"gpx_tools"
+	"strings"
+	"testing"
+)
+
+const tpxNamespace = "http://www.garmin.com/xmlschemas/TrackPointExtension/v1"
+
+const extensionsSample = `<gpx version="1.1" creator="ext test" xmlns:gpxtpx="` + tpxNamespace + `">
+    <trk><trkseg>
+        <trkpt lat="1" lon="2">
+            <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>150</gpxtpx:hr></gpxtpx:TrackPointExtension><other xmlns="urn:other">x</other></extensions>
+        </trkpt>
+    </trkseg></trk>
+</gpx>`
+
+func TestExtensionsRoundTrip(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxBytes([]byte(extensionsSample))
+	if err != nil {
+		t.Fatalf(`ParseGpxBytes() = %v; want nil`, err)
+	}
+	ext := gpx.Trk[0].Trkseg[0].Trkpt[0].Extensions
+
+	element, err := ext.GetElement(tpxNamespace, "TrackPointExtension")
+	if err != nil || element == nil {
+		t.Fatalf(`GetElement() = %v, %v; want element`, element, err)
+	}
+	if hr := element.GetChild(tpxNamespace, "hr"); hr == nil || hr.Text != "150" {
+		t.Errorf(`hr = %v; want 150`, hr)
+	}
+
+	bytes, err := xml.Marshal(gpx)
+	if err != nil {
+		t.Fatalf(`Marshal() = %v; want nil`, err)
+	}
+	output := string(bytes)
+	if !strings.Contains(output, `xmlns:gpxtpx="`+tpxNamespace+`"`) {
+		t.Errorf(`output does not declare gpxtpx namespace: %s`, output)
+	}
+	if !strings.Contains(output, `<gpxtpx:hr>150</gpxtpx:hr>`) {
+		t.Errorf(`output lost extension content: %s`, output)
+	}
+
+	again, err := gpx_tools.ParseGpxBytes(bytes)
+	if err != nil {
+		t.Fatalf(`ParseGpxBytes(output) = %v; want nil`, err)
+	}
+	element, _ = again.Trk[0].Trkseg[0].Trkpt[0].Extensions.GetElement(tpxNamespace, "TrackPointExtension")
+	if element == nil {
+		t.Errorf(`extension lost after round trip`)
+	}
+}
+
+func TestExtensionsAddRemove(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxBytes([]byte(extensionsSample))
+	if err != nil {
+		t.Fatalf(`ParseGpxBytes() = %v; want nil`, err)
+	}
+	ext := gpx.Trk[0].Trkseg[0].Trkpt[0].Extensions
+
+	removed, err := ext.RemoveElements("urn:other", "other")
+	if err != nil || removed != 1 {
+		t.Errorf(`RemoveElements() = %d, %v; want 1, nil`, removed, err)
+	}
+
+	added := gpx_tools.NewExtensionElement(tpxNamespace, "TrackPointExtension", "")
+	added.AddChild(gpx_tools.NewExtensionElement(tpxNamespace, "cad", "90"))
+	if err := ext.AddElement(added); err != nil {
+		t.Fatalf(`AddElement() = %v; want nil`, err)
+	}
+	if !strings.Contains(ext.InnerXML, "<gpxtpx:TrackPointExtension><gpxtpx:cad>90</gpxtpx:cad></gpxtpx:TrackPointExtension>") {
+		t.Errorf(`AddElement() did not use known prefix: %s`, ext.InnerXML)
+	}
+
+	elements, err := ext.Elements()
+	if err != nil || len(elements) != 2 {
+		t.Errorf(`Elements() = %d, %v; want 2, nil`, len(elements), err)
+	}
+
+	unknown := &gpx_tools.ExtensionsType{}
+	if err := unknown.AddElement(gpx_tools.NewExtensionElement("urn:new", "value", "1")); err != nil {
+		t.Fatalf(`AddElement() = %v; want nil`, err)
+	}
+	if unknown.InnerXML != `<value xmlns="urn:new">1</value>` {
+		t.Errorf(`AddElement() = %s; want inline namespace declaration`, unknown.InnerXML)
+	}
+}
+
+func TestExtensionsEnclosingNamespaces(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxBytes([]byte(`<gpx version="1.1" creator="ext test">
+    <wpt lat="1" lon="2"><extensions xmlns:a="urn:a"><a:foo>1</a:foo></extensions></wpt>
+    <trk><trkseg>
+        <trkpt lat="3" lon="4" xmlns:b="urn:b"><extensions><b:bar b:unit="m">2</b:bar></extensions></trkpt>
+    </trkseg></trk>
+</gpx>`))
+	if err != nil {
+		t.Fatalf(`ParseGpxBytes() = %v; want nil`, err)
+	}
+	if foo, err := gpx.Wpt[0].Extensions.GetElement("urn:a", "foo"); err != nil || foo == nil || foo.Text != "1" {
+		t.Errorf(`GetElement(urn:a, foo) = %v, %v; want 1`, foo, err)
+	}
+	bar, err := gpx.Trk[0].Trkseg[0].Trkpt[0].Extensions.GetElement("urn:b", "bar")
+	if err != nil || bar == nil || bar.Text != "2" {
+		t.Fatalf(`GetElement(urn:b, bar) = %v, %v; want 2`, bar, err)
+	}
+	if unit, ok := bar.GetAttr("urn:b", "unit"); !ok || unit != "m" {
+		t.Errorf(`GetAttr(urn:b, unit) = %s, %v; want m`, unit, ok)
+	}
+
+	var buffer bytes.Buffer
+	if err := gpx_tools.WriteGpx(&buffer, gpx, gpx_tools.WriteOptions{}); err != nil {
+		t.Fatalf(`WriteGpx() = %v; want nil`, err)
+	}
+	output := buffer.String()
+	for _, expected := range []string{
+		`<extensions xmlns:a="urn:a"><a:foo>1</a:foo></extensions>`,
+		`<extensions xmlns:b="urn:b"><b:bar b:unit="m">2</b:bar></extensions>`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf(`WriteGpx() = %s; want %s`, output, expected)
+		}
+	}
+	again, err := gpx_tools.ParseGpxBytes(buffer.Bytes())
+	if err != nil {
+		t.Fatalf(`ParseGpxBytes(output) = %v; want nil`, err)
+	}
+	if bar, _ := again.Trk[0].Trkseg[0].Trkpt[0].Extensions.GetElement("urn:b", "bar"); bar == nil {
+		t.Errorf(`extension lost after round trip: %s`, output)
+	}
+
+	// Removed elements leave their prefixes undeclared.
+	if _, err := gpx.Wpt[0].Extensions.RemoveElements("urn:a", "foo"); err != nil {
+		t.Fatalf(`RemoveElements() = %v; want nil`, err)
+	}
+	buffer.Reset()
+	if err := gpx_tools.WriteGpx(&buffer, gpx, gpx_tools.WriteOptions{}); err != nil {
+		t.Fatalf(`WriteGpx() = %v; want nil`, err)
+	}
+	if strings.Contains(buffer.String(), `urn:a`) {
+		t.Errorf(`WriteGpx() = %s; want no declaration of unused prefix`, buffer.String())
+	}
+}
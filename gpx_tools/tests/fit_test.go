@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"gpx_tools"
+	"math"
+	"testing"
+)
+
+// Builds FIT file of records with header and CRC.
+type fitBuilder struct {
+	records bytes.Buffer
+}
+
+func (b *fitBuilder) definition(local byte, global uint16, fields ...byte) {
+	b.records.Write([]byte{0x40 | local, 0, 0})
+	binary.Write(&b.records, binary.LittleEndian, global)
+	b.records.WriteByte(byte(len(fields) / 3))
+	b.records.Write(fields)
+}
+
+func (b *fitBuilder) data(header byte, values ...any) {
+	b.records.WriteByte(header)
+	for _, value := range values {
+		binary.Write(&b.records, binary.LittleEndian, value)
+	}
+}
+
+func (b *fitBuilder) bytes() []byte {
+	var file bytes.Buffer
+	file.Write([]byte{14, 0x10})
+	binary.Write(&file, binary.LittleEndian, uint16(2132))
+	binary.Write(&file, binary.LittleEndian, uint32(b.records.Len()))
+	file.WriteString(".FIT")
+	binary.Write(&file, binary.LittleEndian, fitCrc(file.Bytes()))
+	file.Write(b.records.Bytes())
+	binary.Write(&file, binary.LittleEndian, fitCrc(file.Bytes()))
+	return file.Bytes()
+}
+
+func fitCrc(data []byte) uint16 {
+	table := [16]uint16{
+		0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+		0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+	}
+	var crc uint16
+	for _, b := range data {
+		for _, nibble := range []byte{b & 0xF, b >> 4} {
+			tmp := table[crc&0xF]
+			crc = (crc>>4)&0x0FFF ^ tmp ^ table[nibble]
+		}
+	}
+	return crc
+}
+
+func semicircles(degrees float64) int32 {
+	return int32(math.Round(degrees * math.Pow(2, 31) / 180))
+}
+
+func sampleFit() []byte {
+	var b fitBuilder
+	// file_id: type, time_created
+	b.definition(0, 0, 0, 1, 0x00, 4, 4, 0x86)
+	b.data(0, uint8(4), uint32(1000000000))
+	// record: timestamp, position_lat, position_long, altitude, heart_rate, distance, speed
+	b.definition(1, 20, 253, 4, 0x86, 0, 4, 0x85, 1, 4, 0x85, 2, 2, 0x84, 3, 1, 0x02, 5, 4, 0x86, 6, 2, 0x84)
+	b.data(1, uint32(1000000010), semicircles(50), semicircles(14), uint16((200+500)*5), uint8(120), uint32(0), uint16(2500))
+	b.data(1, uint32(1000000015), semicircles(50.001), semicircles(14.001), uint16(0xFFFF), uint8(0xFF), uint32(1500), uint16(3000))
+	// lap: timestamp
+	b.definition(2, 19, 253, 4, 0x86)
+	b.data(2, uint32(1000000015))
+	// record with compressed timestamp: position_lat, position_long
+	b.definition(3, 20, 0, 4, 0x85, 1, 4, 0x85)
+	b.data(0x80|3<<5|(1000000020&0x1F), semicircles(50.002), semicircles(14.002))
+	// session: sport
+	b.definition(2, 18, 5, 1, 0x00)
+	b.data(2, uint8(1))
+	return b.bytes()
+}
+
+func TestParseFit(t *testing.T) {
+	gpx, err := gpx_tools.ParseFitBytes(sampleFit())
+	if err != nil {
+		t.Fatalf(`ParseFitBytes() = %v; want nil`, err)
+	}
+	if gpx.Metadata == nil || gpx.Metadata.Time.String() != "2021-09-08T01:46:40Z" {
+		t.Errorf(`ParseFitBytes().Metadata = %+v; want time of creation`, gpx.Metadata)
+	}
+	if len(gpx.Trk) != 1 || gpx.Trk[0].Type != "running" || len(gpx.Trk[0].Trkseg) != 2 {
+		t.Fatalf(`ParseFitBytes().Trk = %+v; want running track with 2 segments`, gpx.Trk)
+	}
+
+	first := gpx.Trk[0].Trkseg[0].Trkpt[0]
+	if math.Abs(first.LatAttr-50) > 1e-6 || math.Abs(first.LonAttr-14) > 1e-6 || *first.Ele != 200 || first.Time.String() != "2021-09-08T01:46:50Z" {
+		t.Errorf(`ParseFitBytes() first point = %+v; want 50, 14, 200 m`, first)
+	}
+	data, _ := first.GetSensorData()
+	if *data.HeartRate != 120 || *data.Speed != 2.5 || *data.Distance != 0 {
+		t.Errorf(`GetSensorData() = %+v; want hr 120, speed 2.5, distance 0`, data)
+	}
+
+	second := gpx.Trk[0].Trkseg[0].Trkpt[1]
+	data, _ = second.GetSensorData()
+	if second.HasElevation() || data.HeartRate != nil || *data.Distance != 15 {
+		t.Errorf(`ParseFitBytes() second point = %+v, %+v; want invalid values missing`, second, data)
+	}
+
+	third := gpx.Trk[0].Trkseg[1].Trkpt[0]
+	if third.Time.String() != "2021-09-08T01:47:00Z" {
+		t.Errorf(`ParseFitBytes() compressed timestamp = %s; want 2021-09-08T01:47:00Z`, third.Time)
+	}
+}
+
+func TestParseFitCrc(t *testing.T) {
+	data := sampleFit()
+	data[20] ^= 0xFF
+	if _, err := gpx_tools.ParseFitBytes(data); err == nil {
+		t.Errorf(`ParseFitBytes(corrupted) = nil; want CRC error`)
+	}
+	if _, err := gpx_tools.ParseFitBytes([]byte("not a fit file")); err == nil {
+		t.Errorf(`ParseFitBytes(text) = nil; want error`)
+	}
+}
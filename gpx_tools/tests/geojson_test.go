@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"bytes"
+	"gpx_tools"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestGeoJsonRoundTrip(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxFile("sample.gpx")
+	if err != nil {
+		t.Fatalf(`ParseGpxFile("sample.gpx") = %v; want nil`, err)
+	}
+	gpx.Rte = append(gpx.Rte, &gpx_tools.RteType{Name: "Route", Rtept: []*gpx_tools.WptType{{LatAttr: 1, LonAttr: 2}, {LatAttr: 3, LonAttr: 4}}})
+
+	var buffer bytes.Buffer
+	if err := gpx_tools.WriteGeoJson(&buffer, gpx, ""); err != nil {
+		t.Fatalf(`WriteGeoJson() = %v; want nil`, err)
+	}
+	output := buffer.String()
+	for _, want := range []string{
+		`"type":"FeatureCollection"`,
+		`{"type":"Point","coordinates":[-122.4194,37.7749,10]}`,
+		`{"type":"LineString","coordinates":[[2,1],[4,3]]}`,
+		`"type":"MultiLineString"`,
+		`"coordinateProperties":{"times":[["2023-11-25T12:00:00Z","2023-11-25T12:15:00Z"]]}`,
+		`"_gpxType":"rte"`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf(`WriteGeoJson() = %s; want %s`, output, want)
+		}
+	}
+
+	parsed, err := gpx_tools.ParseGeoJson(&buffer)
+	if err != nil {
+		t.Fatalf(`ParseGeoJson() = %v; want nil`, err)
+	}
+	if len(parsed.Wpt) != 2 || parsed.Wpt[0].Name != "Waypoint 1" || *parsed.Wpt[0].Ele != 10 || parsed.Wpt[0].Time.String() != "2023-11-25T12:00:00Z" {
+		t.Errorf(`ParseGeoJson().Wpt = %+v; want 2 waypoints`, parsed.Wpt)
+	}
+	if len(parsed.Rte) != 1 || len(parsed.Rte[0].Rtept) != 2 || parsed.Rte[0].Rtept[1].LatAttr != 3 {
+		t.Errorf(`ParseGeoJson().Rte = %+v; want route of 2 points`, parsed.Rte)
+	}
+	if len(parsed.Trk) != 1 || len(parsed.Trk[0].Trkseg) != 1 || len(parsed.Trk[0].Trkseg[0].Trkpt) != 2 {
+		t.Fatalf(`ParseGeoJson().Trk = %+v; want 1 track with 2 points`, parsed.Trk)
+	}
+	if trkpt := parsed.Trk[0].Trkseg[0].Trkpt[1]; trkpt.Time.String() != "2023-11-25T12:15:00Z" || *trkpt.Ele != 15 {
+		t.Errorf(`ParseGeoJson() track point = %+v; want the written one`, trkpt)
+	}
+}
+
+func TestParseGeoJsonGeometry(t *testing.T) {
+	gpx, err := gpx_tools.ParseGeoJsonBytes([]byte(`{"type": "LineString", "coordinates": [[14.4, 50.1], [14.5, 50.2, 300]]}`))
+	if err != nil {
+		t.Fatalf(`ParseGeoJsonBytes(LineString) = %v; want nil`, err)
+	}
+	if len(gpx.Trk) != 1 || len(gpx.Trk[0].Trkseg[0].Trkpt) != 2 || gpx.Trk[0].Trkseg[0].Trkpt[0].HasElevation() {
+		t.Errorf(`ParseGeoJsonBytes(LineString).Trk = %+v; want 1 track with 2 points`, gpx.Trk)
+	}
+
+	if _, err := gpx_tools.ParseGeoJsonBytes([]byte(`{"type": "Point", "coordinates": [14.4]}`)); err == nil {
+		t.Errorf(`ParseGeoJsonBytes(short position) = nil; want error`)
+	}
+	if _, err := gpx_tools.ParseGeoJsonBytes([]byte(`{"type": "Point", "coordinates": [10, 95]}`)); err == nil {
+		t.Errorf(`ParseGeoJsonBytes(latitude 95) = nil; want error`)
+	}
+
+	// Null members of GeometryCollection are skipped.
+	gpx, err = gpx_tools.ParseGeoJsonBytes([]byte(`{"type": "Feature", "geometry": {"type": "GeometryCollection",
+		"geometries": [null, {"type": "Point", "coordinates": [14.4, 50.1]}]}}`))
+	if err != nil || len(gpx.Wpt) != 1 {
+		t.Errorf(`ParseGeoJsonBytes(GeometryCollection with null) = %v, %v; want 1 waypoint`, gpx, err)
+	}
+}
+
+func TestWriteGeoJsonInvalid(t *testing.T) {
+	gpx := &gpx_tools.GpxType{Wpt: []*gpx_tools.WptType{{LatAttr: math.NaN(), LonAttr: 10}}}
+	var buffer bytes.Buffer
+	if err := gpx_tools.WriteGeoJson(&buffer, gpx, ""); err == nil {
+		t.Errorf(`WriteGeoJson(NaN latitude) = nil; want error`)
+	}
+}
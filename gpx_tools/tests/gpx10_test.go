@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"encoding/xml"
+	"gpx_tools"
+	"strings"
+	"testing"
+)
+
+const gpx10Sample = `<?xml version="1.0"?>
+<gpx version="1.0" creator="old device" xmlns="http://www.topografix.com/GPX/1/0">
+  <name>Legacy</name>
+  <author>Jane</author>
+  <email>jane@example.com</email>
+  <url>http://example.com</url>
+  <urlname>Example</urlname>
+  <time>2004-05-01T10:00:00Z</time>
+  <trk>
+    <name>Old track</name>
+    <trkseg>
+      <trkpt lat="1" lon="2"><ele>5</ele><course>90.5</course><speed>3.2</speed><sat>6</sat></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestDetectGpxVersion(t *testing.T) {
+	version, err := gpx_tools.DetectGpxVersion([]byte(gpx10Sample))
+	if err != nil || version != "1.0" {
+		t.Errorf(`DetectGpxVersion() = %q, %v; want "1.0", nil`, version, err)
+	}
+	version, err = gpx_tools.DetectGpxVersion([]byte(`<gpx version="1.1" creator="x"/>`))
+	if err != nil || version != "1.1" {
+		t.Errorf(`DetectGpxVersion() = %q, %v; want "1.1", nil`, version, err)
+	}
+}
+
+func TestParseGpx10(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxBytes([]byte(gpx10Sample))
+	if err != nil {
+		t.Fatalf(`ParseGpxBytes() = %v; want nil`, err)
+	}
+	if gpx.VersionAttr != "1.1" || gpx.Metadata == nil || gpx.Metadata.Name != "Legacy" {
+		t.Fatalf(`ParseGpxBytes() = %+v; want upgraded document with metadata`, gpx)
+	}
+	if gpx.Metadata.Author.Email.GetEmailStr() != "jane@example.com" {
+		t.Errorf(`email = %q; want "jane@example.com"`, gpx.Metadata.Author.Email.GetEmailStr())
+	}
+	if len(gpx.Metadata.Link) != 1 || gpx.Metadata.Link[0].Text != "Example" {
+		t.Errorf(`links = %v; want one link "Example"`, gpx.Metadata.Link)
+	}
+
+	trkpt := gpx.Trk[0].Trkseg[0].Trkpt[0]
+	course, err := trkpt.Extensions.GetElement(gpx_tools.Gpx10Namespace, "course")
+	if err != nil || course == nil || course.Text != "90.5" {
+		t.Errorf(`course extension = %v, %v; want 90.5`, course, err)
+	}
+
+	bytes, err := xml.Marshal(gpx_tools.ToGpx10(gpx))
+	if err != nil {
+		t.Fatalf(`Marshal(ToGpx10()) = %v; want nil`, err)
+	}
+	output := string(bytes)
+	for _, expected := range []string{
+		`xmlns="http://www.topografix.com/GPX/1/0"`,
+		`<course>90.5</course><speed>3.2</speed>`,
+		`<email>jane@example.com</email>`,
+		`<urlname>Example</urlname>`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf(`GPX 1.0 output does not contain %s: %s`, expected, output)
+		}
+	}
+	if strings.Contains(output, "extensions") {
+		t.Errorf(`GPX 1.0 output contains extensions: %s`, output)
+	}
+}
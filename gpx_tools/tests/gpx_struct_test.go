@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"encoding/xml"
+	"gpx_tools"
+	"strings"
+	"testing"
+)
+
+func TestPointElements(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxBytes([]byte(`<gpx version="1.1" creator="test">
+    <wpt lat="1" lon="2"/>
+    <rte><rtept lat="3" lon="4"/></rte>
+    <trk><trkseg><trkpt lat="5" lon="6"/><trkpt lat="7" lon="8"/></trkseg></trk>
+</gpx>`))
+	if err != nil {
+		t.Fatalf(`ParseGpxBytes() = %v; want nil`, err)
+	}
+	if len(gpx.Rte) != 1 || len(gpx.Rte[0].Rtept) != 1 || gpx.Rte[0].Rtept[0].LatAttr != 3 {
+		t.Errorf(`ParseGpxBytes() route = %v; want one rtept`, gpx.Rte)
+	}
+	if len(gpx.Trk) != 1 || len(gpx.Trk[0].Trkseg) != 1 || len(gpx.Trk[0].Trkseg[0].Trkpt) != 2 {
+		t.Fatalf(`ParseGpxBytes() track = %v; want two trkpt`, gpx.Trk)
+	}
+
+	// Element names follow the field, not the shared WptType.
+	bytes, err := xml.Marshal(gpx)
+	if err != nil {
+		t.Fatalf(`xml.Marshal() = %v; want nil`, err)
+	}
+	for _, element := range []string{`<wpt lat="1"`, `<rtept lat="3"`, `<trkpt lat="5"`, `<trkpt lat="7"`} {
+		if !strings.Contains(string(bytes), element) {
+			t.Errorf(`xml.Marshal() = %s; want %s`, bytes, element)
+		}
+	}
+}
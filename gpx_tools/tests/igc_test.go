@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"bytes"
+	"gpx_tools"
+	"strings"
+	"testing"
+)
+
+const igcLog = "AXXXABC FLIGHT:1\r\n" +
+	"HFDTEDATE:311223,01\r\n" +
+	"HFPLTPILOTINCHARGE: Jane Doe\r\n" +
+	"HFGTYGLIDERTYPE:Discus 2\r\n" +
+	"HFGIDGLIDERID:D-1234\r\n" +
+	"HFCIDCOMPETITIONID:XY\r\n" +
+	"I023638FXA3940SIU\r\n" +
+	"B2359585206343N00006198WA005870061202550\r\n" +
+	"LXXXsome comment\r\n" +
+	"B0000035206350S00006210EV-00120000003008\r\n" +
+	"GREFDJ0123\r\n"
+
+func TestParseIgc(t *testing.T) {
+	gpx, err := gpx_tools.ParseIgc(strings.NewReader(igcLog))
+	if err != nil {
+		t.Fatalf(`ParseIgc() = %v; want nil`, err)
+	}
+	if gpx.Metadata == nil || gpx.Metadata.Author.Name != "Jane Doe" || gpx.Metadata.Time.String() != "2023-12-31T23:59:58Z" {
+		t.Errorf(`ParseIgc().Metadata = %+v; want pilot and time of the first fix`, gpx.Metadata)
+	}
+	if len(gpx.Trk) != 1 || gpx.Trk[0].Name != "Discus 2" || gpx.Trk[0].Cmt != "D-1234" || len(gpx.Trk[0].Trkseg[0].Trkpt) != 2 {
+		t.Fatalf(`ParseIgc().Trk = %+v; want a track of glider with 2 points`, gpx.Trk)
+	}
+	if element, _ := gpx.Trk[0].Extensions.GetElement(gpx_tools.IgcNamespace, "competitionId"); element == nil || element.Text != "XY" {
+		t.Errorf(`ParseIgc() competition ID = %+v; want XY`, element)
+	}
+
+	first := gpx.Trk[0].Trkseg[0].Trkpt[0]
+	if first.LatAttr != 52+6.343/60 || first.LonAttr != -6.198/60 || first.Fix != "3d" || *first.Ele != 612 || *first.Sat != 50 {
+		t.Errorf(`ParseIgc() first point = %+v`, first)
+	}
+	altitudes, err := first.GetIgcAltitudes()
+	if err != nil || *altitudes.Pressure != 587 || *altitudes.Gnss != 612 {
+		t.Errorf(`GetIgcAltitudes() = %+v, %v; want 587 and 612`, altitudes, err)
+	}
+	if element, _ := first.Extensions.GetElement(gpx_tools.IgcNamespace, "FXA"); element == nil || element.Text != "025" {
+		t.Errorf(`ParseIgc() FXA extension = %+v; want 025`, element)
+	}
+
+	second := gpx.Trk[0].Trkseg[0].Trkpt[1]
+	if second.Time.String() != "2024-01-01T00:00:03Z" || second.LatAttr != -(52+6.35/60) || second.Fix != "2d" || *second.Ele != -12 {
+		t.Errorf(`ParseIgc() second point = %+v; want next day and pressure altitude`, second)
+	}
+	if altitudes, _ := second.GetIgcAltitudes(); altitudes.Gnss != nil {
+		t.Errorf(`GetIgcAltitudes() = %+v; want no GNSS altitude of invalid fix`, altitudes)
+	}
+
+	var buffer bytes.Buffer
+	if err := gpx_tools.WriteGpx(&buffer, gpx, gpx_tools.WriteOptions{}); err != nil {
+		t.Fatalf(`WriteGpx(ParseIgc()) = %v; want nil`, err)
+	}
+	if !strings.Contains(buffer.String(), `xmlns:igc="urn:gpx-tools:igc"`) || !strings.Contains(buffer.String(), "<igc:gnssAltitude>612</igc:gnssAltitude>") {
+		t.Errorf(`WriteGpx(ParseIgc()) = %s; want IGC extensions`, buffer.String())
+	}
+
+	if _, err := gpx_tools.ParseIgc(strings.NewReader("B2359585206343N00006198WA0058700612\n")); err == nil || !strings.Contains(err.Error(), "Line 1") {
+		t.Errorf(`ParseIgc(no date) = %v; want error of line 1`, err)
+	}
+}
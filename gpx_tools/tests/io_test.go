@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"bytes"
+	"gpx_tools"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteAndParseCompressed(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxFile("sample.gpx")
+	if err != nil {
+		t.Fatalf(`ParseGpxFile("sample.gpx") = %v; want nil`, err)
+	}
+
+	for _, compression := range []gpx_tools.Compression{gpx_tools.NoCompression, gpx_tools.GzipCompression, gpx_tools.ZipCompression} {
+		var buffer bytes.Buffer
+		if err := gpx_tools.WriteGpx(&buffer, gpx, gpx_tools.WriteOptions{Compression: compression}); err != nil {
+			t.Fatalf(`WriteGpx(%d) = %v; want nil`, compression, err)
+		}
+		parsed, err := gpx_tools.ParseGpx(&buffer)
+		if err != nil {
+			t.Fatalf(`ParseGpx(%d) = %v; want nil`, compression, err)
+		}
+		if parsed.CreatorAttr != gpx.CreatorAttr || len(parsed.Trk[0].Trkseg[0].Trkpt) != 2 {
+			t.Errorf(`ParseGpx(%d) = %+v; want the written document`, compression, parsed)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "sample.gpx.gz")
+	if err := gpx_tools.WriteGpxFile(gpx, path); err != nil {
+		t.Fatalf(`WriteGpxFile(%q) = %v; want nil`, path, err)
+	}
+	parsed, err := gpx_tools.ParseGpxFile(path)
+	if err != nil || len(parsed.Wpt) != 2 {
+		t.Errorf(`ParseGpxFile(%q) = %v; want 2 waypoints`, path, err)
+	}
+}
+
+func TestWriteGpx10(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxFile("sample.gpx")
+	if err != nil {
+		t.Fatalf(`ParseGpxFile("sample.gpx") = %v; want nil`, err)
+	}
+	var buffer bytes.Buffer
+	if err := gpx_tools.WriteGpx(&buffer, gpx, gpx_tools.WriteOptions{Version: "1.0"}); err != nil {
+		t.Fatalf(`WriteGpx() = %v; want nil`, err)
+	}
+	if !strings.Contains(buffer.String(), `version="1.0"`) {
+		t.Errorf(`WriteGpx() = %s; want GPX 1.0`, buffer.String())
+	}
+}
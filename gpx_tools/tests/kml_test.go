@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"bytes"
+	"gpx_tools"
+	"strings"
+	"testing"
+)
+
+func TestKmlRoundTrip(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxFile("sample.gpx")
+	if err != nil {
+		t.Fatalf(`ParseGpxFile("sample.gpx") = %v; want nil`, err)
+	}
+	gpx.Rte = append(gpx.Rte, &gpx_tools.RteType{Name: "Route", Rtept: []*gpx_tools.WptType{{LatAttr: 1, LonAttr: 2}, {LatAttr: 3, LonAttr: 4}}})
+
+	for _, opts := range []gpx_tools.KmlOptions{{Indent: "  "}, {Kmz: true}} {
+		var buffer bytes.Buffer
+		if err := gpx_tools.WriteKml(&buffer, gpx, opts); err != nil {
+			t.Fatalf(`WriteKml(%+v) = %v; want nil`, opts, err)
+		}
+		if !opts.Kmz {
+			output := buffer.String()
+			for _, want := range []string{`xmlns="http://www.opengis.net/kml/2.2"`, `<gx:Track>`, `<gx:coord>-122.4194 37.7749 10</gx:coord>`, `<when>2023-11-25T12:00:00Z</when>`, `<coordinates>2,1 4,3</coordinates>`} {
+				if !strings.Contains(output, want) {
+					t.Errorf(`WriteKml() = %s; want %s`, output, want)
+				}
+			}
+		}
+
+		parsed, err := gpx_tools.ParseKml(&buffer)
+		if err != nil {
+			t.Fatalf(`ParseKml(%+v) = %v; want nil`, opts, err)
+		}
+		if parsed.Metadata == nil || parsed.Metadata.Name != "Sample GPX File" {
+			t.Errorf(`ParseKml().Metadata = %+v; want name "Sample GPX File"`, parsed.Metadata)
+		}
+		if len(parsed.Wpt) != 2 || parsed.Wpt[1].Name != "Waypoint 2" || *parsed.Wpt[1].Ele != 15 || parsed.Wpt[1].Time.String() != "2023-11-25T12:15:00Z" {
+			t.Errorf(`ParseKml().Wpt = %+v; want 2 waypoints`, parsed.Wpt)
+		}
+		if len(parsed.Rte) != 1 || len(parsed.Rte[0].Rtept) != 2 || parsed.Rte[0].Rtept[1].LonAttr != 4 || parsed.Rte[0].Rtept[1].HasElevation() {
+			t.Errorf(`ParseKml().Rte = %+v; want route of 2 points`, parsed.Rte)
+		}
+		if len(parsed.Trk) != 1 || len(parsed.Trk[0].Trkseg) != 1 {
+			t.Fatalf(`ParseKml().Trk = %+v; want 1 track with 1 segment`, parsed.Trk)
+		}
+		trkpt := parsed.Trk[0].Trkseg[0].Trkpt[1]
+		if trkpt.LatAttr != 37.7751 || trkpt.LonAttr != -122.4196 || *trkpt.Ele != 15 || trkpt.Time.String() != "2023-11-25T12:15:00Z" {
+			t.Errorf(`ParseKml() track point = %+v; want the written one`, trkpt)
+		}
+	}
+}
+
+func TestParseKmlLineStrings(t *testing.T) {
+	input := `<?xml version="1.0"?>
+	<kml xmlns="http://www.opengis.net/kml/2.2">
+		<Folder>
+			<name>Trip</name>
+			<Placemark>
+				<name>Walk</name>
+				<MultiGeometry>
+					<LineString><coordinates>14.1,50.1 14.2,50.2</coordinates></LineString>
+					<LineString><coordinates>
+						14.3,50.3,200 14.4,50.4,210
+					</coordinates></LineString>
+				</MultiGeometry>
+			</Placemark>
+		</Folder>
+	</kml>`
+	gpx, err := gpx_tools.ParseKmlBytes([]byte(input))
+	if err != nil {
+		t.Fatalf(`ParseKmlBytes() = %v; want nil`, err)
+	}
+	if len(gpx.Trk) != 1 || len(gpx.Trk[0].Trkseg) != 2 || gpx.Trk[0].Name != "Walk" {
+		t.Fatalf(`ParseKmlBytes().Trk = %+v; want 1 track with 2 segments`, gpx.Trk)
+	}
+	if trkpt := gpx.Trk[0].Trkseg[1].Trkpt[1]; trkpt.LatAttr != 50.4 || trkpt.LonAttr != 14.4 || *trkpt.Ele != 210 {
+		t.Errorf(`ParseKmlBytes() track point = %+v; want 50.4, 14.4, 210`, trkpt)
+	}
+
+	if _, err := gpx_tools.ParseKmlBytes([]byte(`<kml><Placemark><Point><coordinates>x,1</coordinates></Point></Placemark></kml>`)); err == nil {
+		t.Errorf(`ParseKmlBytes(invalid coordinates) = nil; want error`)
+	}
+	_, err = gpx_tools.ParseKmlBytes([]byte(`<kml><Placemark><name>North</name><Point><coordinates>10,95,0</coordinates></Point></Placemark></kml>`))
+	if err == nil || !strings.Contains(err.Error(), `Placemark "North"`) || !strings.Contains(err.Error(), "Latitude 95") {
+		t.Errorf(`ParseKmlBytes(latitude 95) = %v; want placemark and latitude error`, err)
+	}
+}
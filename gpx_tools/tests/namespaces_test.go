@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"bytes"
+	"gpx_tools"
+	"strings"
+	"testing"
+)
+
+func TestWriteNamespaces(t *testing.T) {
+	input := `<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1"
+		xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+		xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v1"
+		xsi:schemaLocation="http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd http://www.garmin.com/xmlschemas/TrackPointExtension/v1 http://www.garmin.com/xmlschemas/TrackPointExtensionv1.xsd">
+		<wpt lat="1" lon="2"/>
+	</gpx>`
+	gpx, err := gpx_tools.ParseGpxBytes([]byte(input))
+	if err != nil {
+		t.Fatalf(`ParseGpxBytes() = %v; want nil`, err)
+	}
+
+	var buffer bytes.Buffer
+	if err := gpx_tools.WriteGpx(&buffer, gpx, gpx_tools.WriteOptions{Indent: "  ", Creator: "writer"}); err != nil {
+		t.Fatalf(`WriteGpx() = %v; want nil`, err)
+	}
+	output := buffer.String()
+	for _, want := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`xmlns="http://www.topografix.com/GPX/1/1"`,
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"`,
+		`xsi:schemaLocation="http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd http://www.garmin.com/xmlschemas/TrackPointExtension/v1 http://www.garmin.com/xmlschemas/TrackPointExtensionv1.xsd"`,
+		`xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v1"`,
+		`version="1.1"`,
+		`creator="writer"`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf(`WriteGpx() = %s; want %s`, output, want)
+		}
+	}
+	if strings.Count(output, "xmlns:xsi") != 1 {
+		t.Errorf(`WriteGpx() = %s; want xsi declared once`, output)
+	}
+	if gpx.CreatorAttr != "test" {
+		t.Errorf(`CreatorAttr = %q after WriteGpx; want "test"`, gpx.CreatorAttr)
+	}
+	if errs := gpx_tools.ValidateBytes(buffer.Bytes()); len(errs) != 0 {
+		t.Errorf(`ValidateBytes(WriteGpx()) = %v; want no errors`, errs)
+	}
+}
+
+func TestNewGpx(t *testing.T) {
+	gpx := gpx_tools.NewGpx("")
+	gpx.SetNamespace("gpxx", "http://www.garmin.com/xmlschemas/GpxExtensions/v3")
+
+	var buffer bytes.Buffer
+	if err := gpx_tools.WriteGpx(&buffer, gpx, gpx_tools.WriteOptions{}); err != nil {
+		t.Fatalf(`WriteGpx() = %v; want nil`, err)
+	}
+	output := buffer.String()
+	for _, want := range []string{`creator="` + gpx_tools.DefaultCreator + `"`, `xmlns:gpxx=`} {
+		if !strings.Contains(output, want) {
+			t.Errorf(`WriteGpx() = %s; want %s`, output, want)
+		}
+	}
+
+	buffer.Reset()
+	if err := gpx_tools.WriteGpx(&buffer, gpx, gpx_tools.WriteOptions{Version: "1.0"}); err != nil {
+		t.Fatalf(`WriteGpx(1.0) = %v; want nil`, err)
+	}
+	if output := buffer.String(); !strings.Contains(output, `xmlns="http://www.topografix.com/GPX/1/0"`) || !strings.Contains(output, `version="1.0"`) {
+		t.Errorf(`WriteGpx(1.0) = %s; want GPX 1.0 namespace and version`, output)
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"gpx_tools"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const nmeaLog = `$GPGGA,235958.00,5000.0000,N,01400.0000,E,2,08,0.9,245.5,M,45.0,M,1.5,0123*43
+$GPGSA,A,3,01,02,03,04,05,06,07,08,,,,,1.8,0.9,1.5*3E
+$GPRMC,235958.00,A,5000.0000,N,01400.0000,E,10.0,90.0,311223,3.5,W,A*29
+$GPVTG,90.0,T,,M,10.0,N,18.52,K,A*0B
+$GPGGA,000003.00,5000.0060,N,01400.0060,E,1,07,1.1,246.0,M,45.0,M,,*69
+$GPGSA,A,2,01,02,03,,,,,,,,,,2.0,1.1,1.6*36
+$GPGGA,000004.00,5000.0060,N,01400.0060,E,1,07,1.1,246.0,M,45.0,M,,*6E
+$GPGLL,5000.0120,N,01400.0120,E,000010.00,A,A*68
+$GPGGA,000020.00,,,,,0,00,,,,,,,*4A
+`
+
+func TestParseNmea(t *testing.T) {
+	gpx, err := gpx_tools.ParseNmea(strings.NewReader(nmeaLog), gpx_tools.NmeaOptions{SegmentGap: 5 * time.Second, Strict: true})
+	if err != nil {
+		t.Fatalf(`ParseNmea() = %v; want nil`, err)
+	}
+	if len(gpx.Trk) != 1 || len(gpx.Trk[0].Trkseg) != 2 {
+		t.Fatalf(`ParseNmea().Trk = %+v; want 1 track with 2 segments`, gpx.Trk)
+	}
+
+	first := gpx.Trk[0].Trkseg[0].Trkpt[0]
+	if first.LatAttr != 50 || first.LonAttr != 14 || *first.Ele != 245.5 || first.Time.String() != "2023-12-31T23:59:58Z" {
+		t.Errorf(`ParseNmea() first point = %+v; want 50, 14, 245.5 m at 2023-12-31T23:59:58Z`, first)
+	}
+	if first.Fix != "dgps" || *first.Sat != 8 || *first.Hdop != 0.9 || *first.Pdop != 1.8 || *first.Vdop != 1.5 ||
+		*first.Geoidheight != 45 || *first.Ageofdgpsdata != 1.5 || *first.Dgpsid != 123 || *first.Magvar != 356.5 {
+		t.Errorf(`ParseNmea() first point = %+v; want fields of GGA, GSA and RMC`, first)
+	}
+	data, _ := first.GetSensorData()
+	if *data.Course != 90 || math.Abs(*data.Speed-18.52/3.6) > 1e-9 {
+		t.Errorf(`GetSensorData() = %v, %v; want course 90 and speed of VTG`, *data.Course, *data.Speed)
+	}
+
+	points := gpx.Trk[0].Trkseg[0].Trkpt
+	if len(points) != 3 || points[1].Time.String() != "2024-01-01T00:00:03Z" || points[1].Fix != "2d" || points[1].HasDgpsid() {
+		t.Errorf(`ParseNmea() second point = %+v; want 2d fix after midnight`, points[1])
+	}
+
+	// Point 6 seconds after the previous one starts a new segment,
+	// point without fix is skipped.
+	if points := gpx.Trk[0].Trkseg[1].Trkpt; len(points) != 1 || points[0].Time.String() != "2024-01-01T00:00:10Z" || math.Abs(points[0].LatAttr-50.0002) > 1e-9 {
+		t.Errorf(`ParseNmea() second segment = %+v; want GLL point`, *points[0])
+	}
+}
+
+func TestParseNmeaChecksum(t *testing.T) {
+	log := "$GPGGA,120000.00,5000.0000,N,01400.0000,E,1,08,0.9,245.5,M,45.0,M,,*00\n"
+	gpx, err := gpx_tools.ParseNmea(strings.NewReader(log), gpx_tools.NmeaOptions{})
+	if err != nil || len(gpx.Trk) != 0 {
+		t.Errorf(`ParseNmea(invalid checksum) = %v, %+v; want sentence skipped`, err, gpx.Trk)
+	}
+	if _, err := gpx_tools.ParseNmea(strings.NewReader(log), gpx_tools.NmeaOptions{Strict: true}); err == nil {
+		t.Errorf(`ParseNmea(invalid checksum, strict) = nil; want error`)
+	}
+}
+
+func TestParseNmeaDateFromLaterRmc(t *testing.T) {
+	log := strings.Join(strings.Split(nmeaLog, "\n")[4:8], "\n") + "\n$GPRMC,000010.00,A,5000.0120,N,01400.0120,E,,,010124,,*34\n"
+	gpx, err := gpx_tools.ParseNmea(strings.NewReader(log), gpx_tools.NmeaOptions{Strict: true})
+	if err != nil || len(gpx.Trk) != 1 || gpx.Trk[0].Trkseg[0].Trkpt[0].Time.String() != "2024-01-01T00:00:03Z" {
+		t.Errorf(`ParseNmea(RMC at the end) = %v; want date of points from RMC`, err)
+	}
+
+	gpx, err = gpx_tools.ParseNmea(strings.NewReader(nmeaLog[:strings.Index(nmeaLog, "$GPRMC")]), gpx_tools.NmeaOptions{})
+	if err != nil || len(gpx.Trk) != 1 || gpx.Trk[0].Trkseg[0].Trkpt[0].Time != nil {
+		t.Errorf(`ParseNmea(no RMC) = %v; want points without time`, err)
+	}
+}
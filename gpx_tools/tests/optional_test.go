@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"encoding/xml"
+	"gpx_tools"
+	"strings"
+	"testing"
+)
+
+func TestOptionalFields(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxBytes([]byte(`<gpx version="1.1" creator="opt">
+		<wpt lat="1" lon="2"><ele>0</ele></wpt>
+		<wpt lat="3" lon="4"><name>No elevation</name></wpt>
+	</gpx>`))
+	if err != nil {
+		t.Fatalf(`ParseGpxBytes() = %v; want nil`, err)
+	}
+	if !gpx.Wpt[0].HasElevation() {
+		t.Errorf(`HasElevation() = false; want true for <ele>0</ele>`)
+	}
+	if gpx.Wpt[1].HasElevation() || gpx.Wpt[1].HasSat() {
+		t.Errorf(`HasElevation() or HasSat() = true; want false for missing elements`)
+	}
+
+	bytes, err := xml.Marshal(gpx)
+	if err != nil {
+		t.Fatalf(`Marshal() = %v; want nil`, err)
+	}
+	output := string(bytes)
+	if strings.Count(output, "<ele>") != 1 {
+		t.Errorf(`output has %d <ele>; want 1: %s`, strings.Count(output, "<ele>"), output)
+	}
+	for _, element := range []string{"<sat>", "<magvar>", "<hdop>", "<dgpsid>", "<cmt>"} {
+		if strings.Contains(output, element) {
+			t.Errorf(`output contains missing element %s: %s`, element, output)
+		}
+	}
+}
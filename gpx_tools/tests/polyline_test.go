@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"gpx_tools"
+	"math"
+	"testing"
+)
+
+const googlePolyline = "_p~iF~ps|U_ulLnnqC_mqNvxq`@"
+
+func TestEncodePolyline(t *testing.T) {
+	points := []gpx_tools.CoordConvertible{
+		&gpx_tools.WptType{LatAttr: 38.5, LonAttr: -120.2},
+		&gpx_tools.WptType{LatAttr: 40.7, LonAttr: -120.95},
+		&gpx_tools.PtType{LatAttr: 43.252, LonAttr: -126.453},
+	}
+	if polyline := gpx_tools.EncodePolyline(&points, gpx_tools.PolylineOptions{}); polyline != googlePolyline {
+		t.Errorf(`EncodePolyline() = %q; want %q`, polyline, googlePolyline)
+	}
+
+	trk := &gpx_tools.TrkType{Trkseg: []*gpx_tools.TrksegType{
+		{Trkpt: []*gpx_tools.WptType{{LatAttr: 50.0123456, LonAttr: 14.5, Ele: gpx_tools.Optional(245.67)}}},
+		{Trkpt: []*gpx_tools.WptType{{LatAttr: 50.0133456, LonAttr: 14.4999, Ele: gpx_tools.Optional(240.1)}, {LatAttr: -0.5, LonAttr: 0}}},
+	}}
+	opts := gpx_tools.PolylineOptions{Precision: 6, Elevation: true}
+	decoded, err := gpx_tools.PolylineToTrk(trk.EncodePolyline(opts), opts)
+	if err != nil {
+		t.Fatalf(`PolylineToTrk() = %v; want nil`, err)
+	}
+	points2 := decoded.Trkseg[0].Trkpt
+	if len(points2) != 3 || points2[0].LatAttr != 50.012346 || points2[1].LonAttr != 14.4999 ||
+		math.Abs(*points2[0].Ele-245.67) > 1e-9 || math.Abs(*points2[1].Ele-240.1) > 1e-9 || *points2[2].Ele != 0 || points2[2].LatAttr != -0.5 {
+		t.Errorf(`PolylineToTrk(EncodePolyline()) = %+v`, points2)
+	}
+}
+
+func TestDecodePolyline(t *testing.T) {
+	coordinates, err := gpx_tools.DecodePolyline(googlePolyline, gpx_tools.PolylineOptions{})
+	if err != nil {
+		t.Fatalf(`DecodePolyline() = %v; want nil`, err)
+	}
+	if len(coordinates) != 3 || coordinates[2].Coordinates.Latitude != 43.252 || coordinates[2].Coordinates.GetLongitude() != -126.453 {
+		t.Errorf(`DecodePolyline() = %+v; want 3 points ending at 43.252, -126.453`, coordinates)
+	}
+
+	rte, err := gpx_tools.PolylineToRte(googlePolyline, gpx_tools.PolylineOptions{})
+	if err != nil || len(rte.Rtept) != 3 || rte.Rtept[0].LatAttr != 38.5 || rte.Rtept[0].Ele != nil {
+		t.Errorf(`PolylineToRte() = %+v, %v; want 3 points without elevation`, rte, err)
+	}
+	if polyline := rte.EncodePolyline(gpx_tools.PolylineOptions{}); polyline != googlePolyline {
+		t.Errorf(`EncodePolyline(PolylineToRte()) = %q; want %q`, polyline, googlePolyline)
+	}
+
+	for _, invalid := range []string{"_p~iF~ps|U_", "_p~iF", "_p~iF ps|U"} {
+		if _, err := gpx_tools.DecodePolyline(invalid, gpx_tools.PolylineOptions{}); err == nil {
+			t.Errorf(`DecodePolyline(%q) = nil; want error`, invalid)
+		}
+	}
+
+	// Polyline of precision 6 decoded with the default precision 5.
+	munich := []gpx_tools.CoordConvertible{&gpx_tools.WptType{LatAttr: 48.1, LonAttr: 11.5}}
+	polyline := gpx_tools.EncodePolyline(&munich, gpx_tools.PolylineOptions{Precision: 6})
+	if coordinates, err := gpx_tools.DecodePolyline(polyline, gpx_tools.PolylineOptions{}); err == nil {
+		t.Errorf(`DecodePolyline() of other precision = %v; want error`, coordinates)
+	}
+}
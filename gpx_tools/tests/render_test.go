@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/xml"
+	"gpx_tools"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func renderGpx() gpx_tools.Gpx {
+	start := time.Date(2023, 11, 25, 12, 0, 0, 0, time.UTC)
+	return &gpx_tools.GpxType{
+		Wpt: []*gpx_tools.WptType{{LatAttr: 0.5, LonAttr: 1, Name: "A & B"}},
+		Trk: []*gpx_tools.TrkType{{Trkseg: []*gpx_tools.TrksegType{{Trkpt: []*gpx_tools.WptType{
+			{LatAttr: 0, LonAttr: 0, Ele: gpx_tools.Optional(100.0), Time: gpx_tools.NewGpxTime(start)},
+			{LatAttr: 0, LonAttr: 1, Ele: gpx_tools.Optional(200.0), Time: gpx_tools.NewGpxTime(start.Add(100 * time.Second))},
+			{LatAttr: 0, LonAttr: 2, Ele: gpx_tools.Optional(300.0), Time: gpx_tools.NewGpxTime(start.Add(300 * time.Second))},
+		}}}}},
+	}
+}
+
+func TestWriteSvg(t *testing.T) {
+	var buffer bytes.Buffer
+	opts := gpx_tools.RenderOptions{Width: 400, Height: 300, Background: color.White}
+	if err := gpx_tools.WriteSvg(&buffer, renderGpx(), opts); err != nil {
+		t.Fatalf(`WriteSvg() = %v; want nil`, err)
+	}
+	svg := buffer.String()
+	if err := xml.Unmarshal(buffer.Bytes(), new(struct{})); err != nil {
+		t.Fatalf(`WriteSvg() = %s; want valid XML, got %v`, svg, err)
+	}
+	for _, expected := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="400" height="300"`,
+		`<rect width="100%" height="100%" fill="#ffffff"/>`,
+		`<path d="M20 195L200 195L380 195" fill="none" stroke="#e41a1c" stroke-width="3"`,
+		`<circle cx="200" cy="105" r="4" fill="#cc0000"`,
+		`>A &amp; B</text>`,
+		`<path d="M20 286V290H100.94V286"`,
+		`>50 km</text>`,
+	} {
+		if !strings.Contains(svg, expected) {
+			t.Errorf(`WriteSvg() = %s; want to contain %s`, svg, expected)
+		}
+	}
+
+	buffer.Reset()
+	opts = gpx_tools.RenderOptions{Width: 400, Height: 300, ColorBy: gpx_tools.ColorBySpeed,
+		HideLabels: true, HideScaleBar: true, Projection: gpx_tools.EquirectangularProjection}
+	if err := gpx_tools.WriteSvg(&buffer, renderGpx(), opts); err != nil {
+		t.Fatalf(`WriteSvg(ColorBySpeed) = %v; want nil`, err)
+	}
+	svg = buffer.String()
+	if !strings.Contains(svg, `<line x1="20" y1="195" x2="200" y2="195" stroke="#d7191c"/>`) ||
+		!strings.Contains(svg, `<line x1="200" y1="195" x2="380" y2="195" stroke="#1a9641"/>`) ||
+		strings.Contains(svg, "<text") {
+		t.Errorf(`WriteSvg(ColorBySpeed) = %s; want faster part red, slower green and no text`, svg)
+	}
+
+	// Empty styles and gradient fall back to the defaults.
+	buffer.Reset()
+	opts = gpx_tools.RenderOptions{TrackStyles: []gpx_tools.RenderStyle{}, RouteStyles: []gpx_tools.RenderStyle{},
+		Gradient: []color.Color{}, ColorBy: gpx_tools.ColorByElevation}
+	if err := gpx_tools.WriteSvg(&buffer, renderGpx(), opts); err != nil {
+		t.Errorf(`WriteSvg(empty styles) = %v; want nil`, err)
+	}
+
+	if err := gpx_tools.WriteSvg(&buffer, &gpx_tools.GpxType{}, gpx_tools.RenderOptions{}); err == nil {
+		t.Errorf(`WriteSvg(empty) = nil; want error`)
+	}
+	invalid := &gpx_tools.GpxType{Wpt: []*gpx_tools.WptType{{LatAttr: 95, LonAttr: 10}}}
+	if err := gpx_tools.WriteSvg(&buffer, invalid, gpx_tools.RenderOptions{}); err == nil {
+		t.Errorf(`WriteSvg(latitude 95) = nil; want error`)
+	}
+}
+
+func TestRenderImage(t *testing.T) {
+	opts := gpx_tools.RenderOptions{Width: 200, Height: 100, Background: color.White, StartFinish: true}
+	var buffer bytes.Buffer
+	if err := gpx_tools.WritePng(&buffer, renderGpx(), opts); err != nil {
+		t.Fatalf(`WritePng() = %v; want nil`, err)
+	}
+	img, err := png.Decode(&buffer)
+	if err != nil {
+		t.Fatalf(`png.Decode(WritePng()) = %v; want nil`, err)
+	}
+	if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 100 {
+		t.Errorf(`WritePng() size = %v; want 200×100`, img.Bounds())
+	}
+	for _, pixel := range []struct {
+		x, y     int
+		expected color.Color
+	}{
+		{5, 5, color.White},
+		{100, 70, gpx_tools.DefaultTrackStyles[0].Color},
+		{100, 72, color.White},
+		{20, 70, gpx_tools.StartStyle.Color},
+		{180, 70, gpx_tools.FinishStyle.Color},
+		{100, 30, gpx_tools.DefaultWaypointStyle.Color},
+	} {
+		if !sameColor(img.At(pixel.x, pixel.y), pixel.expected) {
+			t.Errorf(`WritePng() pixel %d, %d = %v; want %v`, pixel.x, pixel.y, img.At(pixel.x, pixel.y), pixel.expected)
+		}
+	}
+	// Edge of anti-aliased line is blended with background.
+	if edge := color.RGBAModel.Convert(img.At(100, 71)).(color.RGBA); edge.G <= 0x1a || edge.G >= 0xff {
+		t.Errorf(`WritePng() pixel 100, 71 = %v; want blended colour`, edge)
+	}
+
+	transparent, err := gpx_tools.RenderImage(renderGpx(), gpx_tools.RenderOptions{Width: 200, Height: 100, ColorBy: gpx_tools.ColorByElevation})
+	if err != nil {
+		t.Fatalf(`RenderImage() = %v; want nil`, err)
+	}
+	if _, _, _, a := transparent.At(5, 5).RGBA(); a != 0 {
+		t.Errorf(`RenderImage() background alpha = %d; want transparent`, a)
+	}
+	if !sameColor(transparent.At(60, 70), color.RGBA{0x1a, 0x96, 0x41, 0xff}) || !sameColor(transparent.At(140, 70), color.RGBA{0xd7, 0x19, 0x1c, 0xff}) {
+		t.Errorf(`RenderImage(ColorByElevation) = %v, %v; want lower part green and higher red`, transparent.At(60, 70), transparent.At(140, 70))
+	}
+}
+
+func sameColor(c1, c2 color.Color) bool {
+	r1, g1, b1, a1 := c1.RGBA()
+	r2, g2, b2, a2 := c2.RGBA()
+	return r1>>8 == r2>>8 && g1>>8 == g2>>8 && b1>>8 == b2>>8 && a1>>8 == a2>>8
+}
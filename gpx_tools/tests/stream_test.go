@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"gpx_tools"
+	"strings"
+	"testing"
+)
+
+const streamSample = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="stream test">
+    <metadata><name>Streamed</name></metadata>
+    <wpt lat="1" lon="2"><name>A</name></wpt>
+    <rte>
+        <name>Route</name>
+        <rtept lat="3" lon="4"/>
+        <rtept lat="5" lon="6"/>
+    </rte>
+    <trk>
+        <name>Track</name>
+        <trkseg><trkpt lat="7" lon="8"/></trkseg>
+        <trkseg><trkpt lat="9" lon="10"/><trkpt lat="11" lon="12"/></trkseg>
+    </trk>
+</gpx>`
+
+func TestStreamGpx(t *testing.T) {
+	var points []gpx_tools.StreamPoint
+	var metadataName, trackName string
+	err := gpx_tools.StreamGpx(strings.NewReader(streamSample), gpx_tools.StreamHandler{
+		OnMetadata: func(metadata *gpx_tools.MetadataType) error {
+			metadataName = metadata.Name
+			return nil
+		},
+		OnPoint: func(point gpx_tools.StreamPoint) error {
+			points = append(points, point)
+			return nil
+		},
+		OnTrack: func(index int, trk *gpx_tools.TrkType) error {
+			trackName = trk.Name
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf(`StreamGpx() = %v; want nil`, err)
+	}
+	if metadataName != "Streamed" {
+		t.Errorf(`metadata name = %q; want "Streamed"`, metadataName)
+	}
+	if trackName != "Track" {
+		t.Errorf(`track name = %q; want "Track"`, trackName)
+	}
+	if len(points) != 6 {
+		t.Fatalf(`StreamGpx() emitted %d points; want 6`, len(points))
+	}
+
+	last := points[5]
+	if last.Kind != gpx_tools.TrackPointKind || last.Trk != 0 || last.Trkseg != 1 || last.Index != 1 {
+		t.Errorf(`last point = %+v; want track point 1 of segment 1`, last)
+	}
+	if last.Point.LatAttr != 11 || last.Point.LonAttr != 12 {
+		t.Errorf(`last point at %f, %f; want 11, 12`, last.Point.LatAttr, last.Point.LonAttr)
+	}
+	if points[2].Kind != gpx_tools.RoutePointKind || points[2].Rte != 0 || points[2].Index != 1 {
+		t.Errorf(`third point = %+v; want route point 1`, points[2])
+	}
+}
+
+func TestStreamGpxChannel(t *testing.T) {
+	points, errs := gpx_tools.StreamGpxChannel(strings.NewReader(streamSample))
+	count := 0
+	for range points {
+		count++
+	}
+	if err := <-errs; err != nil {
+		t.Errorf(`StreamGpxChannel() = %v; want nil`, err)
+	}
+	if count != 6 {
+		t.Errorf(`StreamGpxChannel() emitted %d points; want 6`, count)
+	}
+}
+
+func TestParseGpxRouteAndTrackPoints(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxBytes([]byte(streamSample))
+	if err != nil {
+		t.Fatalf(`ParseGpxBytes() = %v; want nil`, err)
+	}
+	if len(gpx.Rte[0].Rtept) != 2 {
+		t.Errorf(`len(Rtept) = %d; want 2`, len(gpx.Rte[0].Rtept))
+	}
+	if len(gpx.Trk[0].Trkseg[1].Trkpt) != 2 {
+		t.Errorf(`len(Trkpt) = %d; want 2`, len(gpx.Trk[0].Trkseg[1].Trkpt))
+	}
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"gpx_tools"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const tcxActivity = `
+<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2" xmlns:ns3="http://www.garmin.com/xmlschemas/ActivityExtension/v2">
+  <Activities>
+    <Activity Sport="Running">
+      <Id>2023-11-25T12:00:00Z</Id>
+      <Lap StartTime="2023-11-25T12:00:00Z">
+        <TotalTimeSeconds>20</TotalTimeSeconds>
+        <DistanceMeters>50</DistanceMeters>
+        <Calories>3</Calories>
+        <Intensity>Active</Intensity>
+        <TriggerMethod>Manual</TriggerMethod>
+        <Track>
+          <Trackpoint>
+            <Time>2023-11-25T12:00:00Z</Time>
+            <Position><LatitudeDegrees>50.0</LatitudeDegrees><LongitudeDegrees>14.0</LongitudeDegrees></Position>
+            <AltitudeMeters>200.5</AltitudeMeters>
+            <DistanceMeters>0</DistanceMeters>
+            <HeartRateBpm><Value>120</Value></HeartRateBpm>
+            <Extensions><ns3:TPX><ns3:Speed>2.5</ns3:Speed><ns3:RunCadence>85</ns3:RunCadence></ns3:TPX></Extensions>
+          </Trackpoint>
+          <Trackpoint>
+            <Time>2023-11-25T12:00:10Z</Time>
+            <HeartRateBpm><Value>125</Value></HeartRateBpm>
+          </Trackpoint>
+          <Trackpoint>
+            <Time>2023-11-25T12:00:20Z</Time>
+            <Position><LatitudeDegrees>50.0004</LatitudeDegrees><LongitudeDegrees>14.0</LongitudeDegrees></Position>
+            <DistanceMeters>50</DistanceMeters>
+            <HeartRateBpm><Value>130</Value></HeartRateBpm>
+          </Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+
+func TestParseTcx(t *testing.T) {
+	gpx, err := gpx_tools.ParseTcxBytes([]byte(tcxActivity))
+	if err != nil {
+		t.Fatalf(`ParseTcxBytes() = %v; want nil`, err)
+	}
+	if len(gpx.Trk) != 1 || gpx.Trk[0].Type != "Running" || len(gpx.Trk[0].Trkseg) != 1 {
+		t.Fatalf(`ParseTcxBytes().Trk = %+v; want 1 running track`, gpx.Trk)
+	}
+	points := gpx.Trk[0].Trkseg[0].Trkpt
+	if len(points) != 2 {
+		t.Fatalf(`ParseTcxBytes() points = %d; want 2 points with position`, len(points))
+	}
+	if *points[0].Ele != 200.5 || points[0].Time.String() != "2023-11-25T12:00:00Z" {
+		t.Errorf(`ParseTcxBytes() point = %+v; want elevation and time`, points[0])
+	}
+	data, err := points[0].GetSensorData()
+	if err != nil {
+		t.Fatalf(`GetSensorData() = %v; want nil`, err)
+	}
+	if *data.HeartRate != 120 || *data.Cadence != 85 || *data.Speed != 2.5 || *data.Distance != 0 {
+		t.Errorf(`GetSensorData() = %+v; want hr 120, cadence 85, speed 2.5, distance 0`, data)
+	}
+}
+
+func TestWriteTcx(t *testing.T) {
+	gpx, err := gpx_tools.ParseTcxBytes([]byte(tcxActivity))
+	if err != nil {
+		t.Fatalf(`ParseTcxBytes() = %v; want nil`, err)
+	}
+
+	var buffer bytes.Buffer
+	if err := gpx_tools.WriteTcx(&buffer, gpx, gpx_tools.TcxOptions{}); err != nil {
+		t.Fatalf(`WriteTcx() = %v; want nil`, err)
+	}
+	output := buffer.String()
+	for _, want := range []string{
+		`<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">`,
+		`<Activity Sport="Running"><Id>2023-11-25T12:00:00Z</Id>`,
+		`<TotalTimeSeconds>20</TotalTimeSeconds>`,
+		`<AverageHeartRateBpm><Value>125</Value></AverageHeartRateBpm>`,
+		`<DistanceMeters>50</DistanceMeters><HeartRateBpm><Value>130</Value></HeartRateBpm>`,
+		`<TPX xmlns="http://www.garmin.com/xmlschemas/ActivityExtension/v2"><Speed>2.5</Speed></TPX>`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf(`WriteTcx() = %s; want %s`, output, want)
+		}
+	}
+
+	parsed, err := gpx_tools.ParseTcx(&buffer)
+	if err != nil || len(parsed.Trk) != 1 || len(parsed.Trk[0].Trkseg[0].Trkpt) != 2 {
+		t.Errorf(`ParseTcx(WriteTcx()) = %v; want the written track`, err)
+	}
+
+	buffer.Reset()
+	if err := gpx_tools.WriteTcx(&buffer, gpx, gpx_tools.TcxOptions{Course: true}); err != nil {
+		t.Fatalf(`WriteTcx(course) = %v; want nil`, err)
+	}
+	if output := buffer.String(); !strings.Contains(output, `<Courses><Course><Name>2023-11-25T12:0</Name>`) || !strings.Contains(output, `<BeginPosition>`) {
+		t.Errorf(`WriteTcx(course) = %s; want course`, output)
+	}
+
+	gpx.Trk[0].Trkseg[0].Trkpt[1].Time = nil
+	if err := gpx_tools.WriteTcx(&buffer, gpx, gpx_tools.TcxOptions{}); err == nil {
+		t.Errorf(`WriteTcx(point without time) = nil; want error`)
+	}
+}
+
+func TestTcxLapDistances(t *testing.T) {
+	start := time.Date(2023, 11, 25, 12, 0, 0, 0, time.UTC)
+	point := func(lat float64, seconds int) *gpx_tools.WptType {
+		return &gpx_tools.WptType{LatAttr: lat, LonAttr: 14, Time: gpx_tools.NewGpxTime(start.Add(time.Duration(seconds) * time.Second))}
+	}
+	gpx := gpx_tools.NewGpx(gpx_tools.DefaultCreator)
+	gpx.Trk = []*gpx_tools.TrkType{{Trkseg: []*gpx_tools.TrksegType{
+		{Trkpt: []*gpx_tools.WptType{point(50, 0), point(50.001, 10)}},
+		{Trkpt: []*gpx_tools.WptType{point(50.1, 20), point(50.101, 30)}},
+	}}}
+
+	tcx, err := gpx_tools.ToTcx(gpx, gpx_tools.TcxOptions{})
+	if err != nil {
+		t.Fatalf(`ToTcx() = %v; want nil`, err)
+	}
+	laps := tcx.Activities.Activity[0].Lap
+	total := 0.0
+	for _, lap := range laps {
+		total += lap.DistanceMeters
+	}
+	trackpoints := laps[1].Track[0].Trackpoint
+	last := *trackpoints[len(trackpoints)-1].DistanceMeters
+	if len(laps) != 2 || math.Abs(last-total) > 1e-6 {
+		t.Errorf(`ToTcx() last DistanceMeters = %v; want sum of laps %v`, last, total)
+	}
+}
+
+func TestSensorData(t *testing.T) {
+	gpx := gpx_tools.NewGpx("")
+	wpt := &gpx_tools.WptType{LatAttr: 1, LonAttr: 2}
+	data := gpx_tools.SensorData{HeartRate: gpx_tools.Optional(140), Power: gpx_tools.Optional(250), Distance: gpx_tools.Optional(12.5)}
+	if err := gpx.SetSensorData(wpt, data); err != nil {
+		t.Fatalf(`SetSensorData() = %v; want nil`, err)
+	}
+	if gpx.Namespaces["gpxtpx"] != gpx_tools.TrackPointExtensionNamespace || gpx.Namespaces["gpxpx"] != gpx_tools.PowerExtensionNamespace {
+		t.Errorf(`Namespaces = %v; want gpxtpx and gpxpx`, gpx.Namespaces)
+	}
+	if want := `<gpxtpx:TrackPointExtension><gpxtpx:hr>140</gpxtpx:hr></gpxtpx:TrackPointExtension>`; !strings.Contains(wpt.Extensions.InnerXML, want) {
+		t.Errorf(`InnerXML = %s; want %s`, wpt.Extensions.InnerXML, want)
+	}
+	read, err := wpt.GetSensorData()
+	if err != nil || *read.HeartRate != 140 || *read.Power != 250 || *read.Distance != 12.5 || read.Cadence != nil {
+		t.Errorf(`GetSensorData() = %+v, %v; want the set data`, read, err)
+	}
+
+	if err := gpx.SetSensorData(wpt, gpx_tools.SensorData{}); err != nil || wpt.Extensions != nil {
+		t.Errorf(`SetSensorData(empty) = %v; want extensions removed`, err)
+	}
+}
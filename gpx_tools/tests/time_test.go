@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"encoding/xml"
+	"gpx_tools"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGpxTimeVariants(t *testing.T) {
+	expected := time.Date(2023, 11, 25, 12, 0, 0, 0, time.UTC)
+	variants := map[string]time.Time{
+		"2023-11-25T12:00:00Z":           expected,
+		"2023-11-25T12:00:00.123Z":       expected.Add(123 * time.Millisecond),
+		"2023-11-25T14:00:00+02:00":      expected,
+		"2023-11-25T14:00:00+0200":       expected,
+		"2023-11-25T07:30:00-04:30":      expected,
+		"2023-11-25T12:00:00":            expected,
+		"2023-11-24T24:00:00Z":           time.Date(2023, 11, 25, 0, 0, 0, 0, time.UTC),
+		"2023-11-25T12:00:00.000000001Z": expected.Add(time.Nanosecond),
+	}
+	for timeStr, want := range variants {
+		got, err := gpx_tools.ParseGpxTimeStr(timeStr)
+		if err != nil || !got.Equal(want) {
+			t.Errorf(`ParseGpxTimeStr(%q) = %v, %v; want %v`, timeStr, got, err, want)
+		}
+	}
+
+	for _, invalid := range []string{"", "yesterday", "2023-13-01T00:00:00Z", "2023-02-30T00:00:00Z", "2023-11-25T24:00:01Z"} {
+		if _, err := gpx_tools.ParseGpxTimeStr(invalid); err == nil {
+			t.Errorf(`ParseGpxTimeStr(%q) = nil error; want error`, invalid)
+		}
+	}
+}
+
+func TestParseGpxTimeDefaultLocation(t *testing.T) {
+	zone := time.FixedZone("", 3600)
+	got, err := gpx_tools.ParseGpxTimeStrIn("2023-11-25T13:00:00", zone)
+	if err != nil || !got.Equal(time.Date(2023, 11, 25, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf(`ParseGpxTimeStrIn() = %v, %v; want 12:00 UTC`, got, err)
+	}
+}
+
+func TestParseGpxTimeLocationOption(t *testing.T) {
+	doc := `<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+<metadata><time>2023-11-25T13:00:00</time></metadata>
+<wpt lat="1" lon="2"><time>2023-11-25T13:00:00</time></wpt>
+<trk><trkseg><trkpt lat="1" lon="2"><time>2023-11-25T13:00:00+02:00</time></trkpt></trkseg></trk>
+</gpx>`
+	opts := gpx_tools.ParseOptions{TimeLocation: time.FixedZone("", 3600)}
+	expected := time.Date(2023, 11, 25, 12, 0, 0, 0, time.UTC)
+
+	gpx, err := gpx_tools.ParseGpxBytesWithOptions([]byte(doc), opts)
+	if err != nil {
+		t.Fatalf(`ParseGpxBytesWithOptions() = %v; want nil`, err)
+	}
+	if got, err := gpx.Metadata.Time.Get(); err != nil || !got.Equal(expected) {
+		t.Errorf(`metadata time = %v, %v; want %v`, got, err, expected)
+	}
+	if got, err := gpx.Wpt[0].Time.Get(); err != nil || !got.Equal(expected) {
+		t.Errorf(`waypoint time = %v, %v; want %v`, got, err, expected)
+	}
+	// Timestamps with zone are not affected.
+	if got, err := gpx.Trk[0].Trkseg[0].Trkpt[0].Time.Get(); err != nil || !got.Equal(expected.Add(-time.Hour)) {
+		t.Errorf(`track point time = %v, %v; want %v`, got, err, expected.Add(-time.Hour))
+	}
+	if gpx.Wpt[0].Time.String() != "2023-11-25T13:00:00" {
+		t.Errorf(`waypoint time String() = %s; want the original form`, gpx.Wpt[0].Time.String())
+	}
+
+	var streamed []time.Time
+	err = gpx_tools.StreamGpxWithOptions(strings.NewReader(doc), gpx_tools.StreamHandler{
+		OnPoint: func(point gpx_tools.StreamPoint) error {
+			got, err := point.Point.Time.Get()
+			streamed = append(streamed, got)
+			return err
+		},
+	}, opts)
+	if err != nil || len(streamed) != 2 || !streamed[0].Equal(expected) {
+		t.Errorf(`StreamGpxWithOptions() = %v, %v; want %v first`, streamed, err, expected)
+	}
+}
+
+func TestGpxTimeRoundTrip(t *testing.T) {
+	gpx, err := gpx_tools.ParseGpxBytes([]byte(`<gpx version="1.1" creator="time">
+		<wpt lat="1" lon="2"><time>2023-11-25T14:00:00.500+02:00</time></wpt>
+		<wpt lat="1" lon="2"><time>2023-11-25T12:00:00Z</time></wpt>
+		<wpt lat="1" lon="2"><time>broken</time></wpt>
+	</gpx>`))
+	if err != nil {
+		t.Fatalf(`ParseGpxBytes() = %v; want nil`, err)
+	}
+	if _, err := gpx.Wpt[2].Time.Get(); err == nil {
+		t.Errorf(`Get() of invalid time = nil error; want error`)
+	}
+	gpx.Wpt[1].Time.Time = gpx.Wpt[1].Time.Time.Add(time.Hour)
+
+	bytes, err := xml.Marshal(gpx)
+	if err != nil {
+		t.Fatalf(`Marshal() = %v; want nil`, err)
+	}
+	output := string(bytes)
+	for _, expected := range []string{
+		"<time>2023-11-25T14:00:00.500+02:00</time>",
+		"<time>2023-11-25T13:00:00Z</time>",
+		"<time>broken</time>",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf(`output does not contain %s: %s`, expected, output)
+		}
+	}
+}
+
+func TestSortByTime(t *testing.T) {
+	points := []gpx_tools.CoordConvertible{
+		&gpx_tools.WptType{LatAttr: 2, Time: gpx_tools.NewGpxTimeStr("2023-11-25T12:00:02.5Z")},
+		&gpx_tools.WptType{LatAttr: 1, Time: gpx_tools.NewGpxTimeStr("2023-11-25T14:00:01+02:00")},
+		&gpx_tools.WptType{LatAttr: 3, Time: gpx_tools.NewGpxTimeStr("2023-11-25T12:00:03Z")},
+	}
+	if err := gpx_tools.SortByTime(&points); err != nil {
+		t.Fatalf(`SortByTime() = %v; want nil`, err)
+	}
+	for i, point := range points {
+		if point.ToCoordinates().Latitude != float64(i+1) {
+			t.Errorf(`point %d has latitude %f; want %d`, i, point.ToCoordinates().Latitude, i+1)
+		}
+	}
+
+	total, err := gpx_tools.TotalTime(&points)
+	if err != nil || total != 2*time.Second {
+		t.Errorf(`TotalTime() = %v, %v; want 2s`, total, err)
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"gpx_tools"
+	"strings"
+	"testing"
+)
+
+func TestValidateBytesValid(t *testing.T) {
+	document := `<?xml version="1.0"?>
+<gpx xmlns="http://www.topografix.com/GPX/1/1" version="1.1" creator="validator">
+  <metadata>
+    <copyright author="me"><year>2023</year></copyright>
+    <time>2023-11-25T12:00:00.5+02:00</time>
+  </metadata>
+  <wpt lat="-90" lon="179.9"><ele>1</ele><fix>3d</fix><dgpsid>1023</dgpsid></wpt>
+  <trk><trkseg><trkpt lat="1" lon="2"/></trkseg></trk>
+</gpx>`
+	if violations := gpx_tools.ValidateBytes([]byte(document)); len(violations) != 0 {
+		t.Errorf(`ValidateBytes() = %v; want no violations`, violations)
+	}
+}
+
+func TestValidateBytesViolations(t *testing.T) {
+	document := `<gpx xmlns="http://www.topografix.com/GPX/1/1" version="1.0">
+  <wpt lat="95" lon="180">
+    <name>Bad</name>
+    <ele>1</ele>
+    <fix>4d</fix>
+    <dgpsid>2000</dgpsid>
+  </wpt>
+  <metadata/>
+  <trk><trkseg><trkpt lon="2"><time>yesterday</time></trkpt></trkseg></trk>
+</gpx>`
+	violations := gpx_tools.ValidateBytes([]byte(document))
+
+	expected := map[string]string{
+		"/gpx/@version":                       "must be",
+		"/gpx":                                "creator",
+		"/gpx/wpt[1]/@lat":                    "out of range",
+		"/gpx/wpt[1]/@lon":                    "out of range",
+		"/gpx/wpt[1]/ele":                     "not allowed after <name>",
+		"/gpx/wpt[1]/fix":                     "not one of",
+		"/gpx/wpt[1]/dgpsid":                  "out of range",
+		"/gpx/metadata":                       "not allowed after <wpt>",
+		"/gpx/trk[1]/trkseg[1]/trkpt[1]":      "lat",
+		"/gpx/trk[1]/trkseg[1]/trkpt[1]/time": "xsd:dateTime",
+	}
+	for path, message := range expected {
+		found := false
+		for _, violation := range violations {
+			if violation.Path == path && strings.Contains(violation.Message, message) {
+				found = true
+				if violation.Line == 0 {
+					t.Errorf(`violation %v has no line`, violation)
+				}
+			}
+		}
+		if !found {
+			t.Errorf(`ValidateBytes() has no violation %s: %s; got %v`, path, message, violations)
+		}
+	}
+	if len(violations) != len(expected) {
+		t.Errorf(`ValidateBytes() found %d violations; want %d: %v`, len(violations), len(expected), violations)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	gpx := &gpx_tools.GpxType{VersionAttr: "1.1", CreatorAttr: "test"}
+	gpx.Wpt = append(gpx.Wpt, &gpx_tools.WptType{LatAttr: 10, LonAttr: -200, Fix: "dgps"})
+	gpx.Metadata = &gpx_tools.MetadataType{Copyright: &gpx_tools.CopyrightType{AuthorAttr: "me", Year: "23"}}
+
+	violations := gpx_tools.Validate(gpx)
+	if len(violations) != 2 {
+		t.Fatalf(`Validate() = %v; want 2 violations`, violations)
+	}
+	if violations[0].Path != "/gpx/metadata/copyright/year" || violations[1].Path != "/gpx/wpt[1]/@lon" {
+		t.Errorf(`Validate() = %v; want year and longitude violations`, violations)
+	}
+}
+
+func TestValidateDateTimeValues(t *testing.T) {
+	for _, value := range []string{"2023-13-01T00:00:00Z", "2023-02-30T00:00:00Z", "2023-01-32T00:00:00", "2023-01-01T25:00:00Z"} {
+		document := `<gpx xmlns="http://www.topografix.com/GPX/1/1" version="1.1" creator="test">
+  <wpt lat="1" lon="2"><time>` + value + `</time></wpt>
+</gpx>`
+		violations := gpx_tools.ValidateBytes([]byte(document))
+		if len(violations) != 1 || violations[0].Path != "/gpx/wpt[1]/time" {
+			t.Errorf(`ValidateBytes() of time %s = %v; want one time violation`, value, violations)
+		}
+	}
+	valid := `<gpx xmlns="http://www.topografix.com/GPX/1/1" version="1.1" creator="test">
+  <wpt lat="1" lon="2"><time>2024-02-29T24:00:00Z</time></wpt>
+</gpx>`
+	if violations := gpx_tools.ValidateBytes([]byte(valid)); len(violations) != 0 {
+		t.Errorf(`ValidateBytes() = %v; want no violations`, violations)
+	}
+}
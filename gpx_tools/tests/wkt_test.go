@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"encoding/hex"
+	"gpx_tools"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func wktTrack() *gpx_tools.TrkType {
+	start := time.Date(2023, 11, 25, 12, 0, 0, 0, time.UTC)
+	return &gpx_tools.TrkType{Trkseg: []*gpx_tools.TrksegType{
+		{Trkpt: []*gpx_tools.WptType{
+			{LatAttr: 50, LonAttr: 14.5, Ele: gpx_tools.Optional(245.5), Time: gpx_tools.NewGpxTime(start)},
+			{LatAttr: 50.1, LonAttr: 14.6, Time: gpx_tools.NewGpxTime(start.Add(1500 * time.Millisecond))},
+		}},
+		{Trkpt: []*gpx_tools.WptType{{LatAttr: 51, LonAttr: 15}}},
+	}}
+}
+
+func TestToWkt(t *testing.T) {
+	wpt := &gpx_tools.WptType{LatAttr: 50, LonAttr: 14.5, Ele: gpx_tools.Optional(245.5)}
+	if wkt := wpt.ToWkt(gpx_tools.WktOptions{Z: true}); wkt != "POINT Z (14.5 50 245.5)" {
+		t.Errorf(`ToWkt() = %q; want POINT Z`, wkt)
+	}
+
+	trk := wktTrack()
+	expected := "SRID=4326;MULTILINESTRING ZM ((14.5 50 245.5 1700913600, 14.6 50.1 0 1700913601.5), (15 51 0 NaN))"
+	if wkt := trk.ToWkt(gpx_tools.WktOptions{Z: true, M: true, SRID: 4326}); wkt != expected {
+		t.Errorf(`ToWkt() = %q; want %q`, wkt, expected)
+	}
+	if wkt := trk.Trkseg[0].ToWkt(gpx_tools.WktOptions{}); wkt != "LINESTRING (14.5 50, 14.6 50.1)" {
+		t.Errorf(`ToWkt() = %q; want LINESTRING`, wkt)
+	}
+
+	trk.Trkseg = append(trk.Trkseg, &gpx_tools.TrksegType{})
+	expected = "MULTILINESTRING ((14.5 50, 14.6 50.1), (15 51), EMPTY)"
+	wkt := trk.ToWkt(gpx_tools.WktOptions{})
+	if wkt != expected {
+		t.Errorf(`ToWkt() = %q; want %q`, wkt, expected)
+	}
+	if parsed, err := gpx_tools.ParseWkt(wkt); err != nil || len(parsed.Trk) != 1 || len(parsed.Trk[0].Trkseg) != 2 {
+		t.Errorf(`ParseWkt(%q) = %v, %v; want track of 2 segments`, wkt, parsed, err)
+	}
+
+	gpx := &gpx_tools.GpxType{Wpt: []*gpx_tools.WptType{wpt}, Rte: []*gpx_tools.RteType{{}}}
+	if wkt := gpx.ToWkt(gpx_tools.WktOptions{M: true}); wkt != "GEOMETRYCOLLECTION M (POINT M (14.5 50 NaN), LINESTRING M EMPTY)" {
+		t.Errorf(`ToWkt() = %q; want GEOMETRYCOLLECTION`, wkt)
+	}
+}
+
+func TestToWkb(t *testing.T) {
+	wpt := &gpx_tools.WptType{LatAttr: 2, LonAttr: 1, Ele: gpx_tools.Optional(3.0)}
+	expected := "01e9030000000000000000f03f00000000000000400000000000000840"
+	if wkb := hex.EncodeToString(wpt.ToWkb(gpx_tools.WktOptions{Z: true})); wkb != expected {
+		t.Errorf(`ToWkb() = %s; want %s`, wkb, expected)
+	}
+	expected = "0101000020e6100000000000000000f03f0000000000000040"
+	if wkb := hex.EncodeToString(wpt.ToWkb(gpx_tools.WktOptions{SRID: 4326})); wkb != expected {
+		t.Errorf(`ToWkb() = %s; want EWKB %s`, wkb, expected)
+	}
+
+	for _, opts := range []gpx_tools.WktOptions{{Z: true, M: true}, {Z: true, M: true, SRID: 4326}} {
+		gpx, err := gpx_tools.ParseWkb(wktTrack().ToWkb(opts))
+		if err != nil {
+			t.Fatalf(`ParseWkb(ToWkb(%+v)) = %v; want nil`, opts, err)
+		}
+		if len(gpx.Trk) != 1 || len(gpx.Trk[0].Trkseg) != 2 {
+			t.Fatalf(`ParseWkb(ToWkb(%+v)).Trk = %+v; want track of 2 segments`, opts, gpx.Trk)
+		}
+		second := gpx.Trk[0].Trkseg[0].Trkpt[1]
+		if second.LatAttr != 50.1 || *second.Ele != 0 || second.Time.String() != "2023-11-25T12:00:01.5Z" || gpx.Trk[0].Trkseg[1].Trkpt[0].Time != nil {
+			t.Errorf(`ParseWkb(ToWkb(%+v)) second point = %+v`, opts, second)
+		}
+	}
+}
+
+func TestParseWkt(t *testing.T) {
+	gpx, err := gpx_tools.ParseWkt("SRID=4326;GEOMETRYCOLLECTION(POINT(14.5 50 245.5), MULTIPOINT((1 2),(3 4)), " +
+		"linestring m (1 2 1700913600, 3 4 NaN), MULTILINESTRING((1 2, 3 4), EMPTY, (5 6)), POINT EMPTY)")
+	if err != nil {
+		t.Fatalf(`ParseWkt() = %v; want nil`, err)
+	}
+	if len(gpx.Wpt) != 3 || gpx.Wpt[0].LonAttr != 14.5 || *gpx.Wpt[0].Ele != 245.5 || gpx.Wpt[2].LatAttr != 4 || gpx.Wpt[2].Ele != nil {
+		t.Errorf(`ParseWkt().Wpt = %+v; want 3 waypoints`, gpx.Wpt)
+	}
+	if len(gpx.Trk) != 2 || gpx.Trk[0].Trkseg[0].Trkpt[0].Time.String() != "2023-11-25T12:00:00Z" ||
+		gpx.Trk[0].Trkseg[0].Trkpt[1].Time != nil || len(gpx.Trk[1].Trkseg) != 2 {
+		t.Errorf(`ParseWkt().Trk = %+v; want 2 tracks`, gpx.Trk)
+	}
+
+	trk := wktTrack()
+	parsed, err := gpx_tools.ParseWkt(trk.ToWkt(gpx_tools.WktOptions{Z: true, M: true}))
+	if err != nil || math.Abs(*parsed.Trk[0].Trkseg[0].Trkpt[0].Ele-245.5) > 1e-9 {
+		t.Errorf(`ParseWkt(ToWkt()) = %+v, %v`, parsed, err)
+	}
+
+	for _, invalid := range []string{"POLYGON((0 0, 1 0, 0 0))", "POINT Z (1 2)", "LINESTRING(1 2, 3 4", "POINT(1 2) x"} {
+		if _, err := gpx_tools.ParseWkt(invalid); err == nil {
+			t.Errorf(`ParseWkt(%q) = nil; want error`, invalid)
+		}
+	}
+	if _, err := gpx_tools.ParseWkbHex("0101000020e6100000000000000000f03f"); err == nil || !strings.Contains(err.Error(), "end of WKB") {
+		t.Errorf(`ParseWkbHex(truncated) = %v; want error`, err)
+	}
+}
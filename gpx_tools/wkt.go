@@ -0,0 +1,731 @@
+package gpx_tools
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WktOptions configure WKT and WKB output. Zero value writes
+// two-dimensional geometries without SRID.
+type WktOptions struct {
+	// Z writes elevation as the third coordinate, missing elevation is 0.
+	Z bool
+	// M writes time as measure in seconds since 1970-01-01 UTC,
+	// missing time is NaN. PostGIS does not read NaN in WKT, so
+	// points without time have to be loaded as WKB.
+	M bool
+	// SRID is written as EWKT prefix and in EWKB, zero means plain
+	// WKT and ISO WKB. Coordinates are always WGS84 longitude and
+	// latitude, so the only meaningful value is 4326.
+	SRID int
+}
+
+// Geometry types of WKB.
+const (
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbGeometryCollection = 7
+)
+
+// Flags of EWKB geometry type.
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+var wktNames = map[uint32]string{
+	wkbPoint:              "POINT",
+	wkbLineString:         "LINESTRING",
+	wkbMultiPoint:         "MULTIPOINT",
+	wkbMultiLineString:    "MULTILINESTRING",
+	wkbGeometryCollection: "GEOMETRYCOLLECTION",
+}
+
+// Geometry shared by WKT and WKB. Points and lines hold points,
+// other types hold children.
+type wkbGeometry struct {
+	kind     uint32
+	points   []wkbCoords
+	children []*wkbGeometry
+	hasZ     bool
+	hasM     bool
+}
+
+type wkbCoords struct {
+	x, y, z, m float64
+}
+
+// ToWkt returns the point as POINT.
+func (wpt *WptType) ToWkt(opts WktOptions) string {
+	return wktString(wptGeometry(wpt, opts), opts)
+}
+
+// ToWkb returns the point as POINT.
+func (wpt *WptType) ToWkb(opts WktOptions) []byte {
+	return wkbBytes(wptGeometry(wpt, opts), opts)
+}
+
+// ToWkt returns points of the route as LINESTRING.
+func (rte *RteType) ToWkt(opts WktOptions) string {
+	return wktString(lineGeometry(rte.Rtept, opts), opts)
+}
+
+// ToWkb returns points of the route as LINESTRING.
+func (rte *RteType) ToWkb(opts WktOptions) []byte {
+	return wkbBytes(lineGeometry(rte.Rtept, opts), opts)
+}
+
+// ToWkt returns points of the segment as LINESTRING.
+func (trkseg *TrksegType) ToWkt(opts WktOptions) string {
+	return wktString(lineGeometry(trkseg.Trkpt, opts), opts)
+}
+
+// ToWkb returns points of the segment as LINESTRING.
+func (trkseg *TrksegType) ToWkb(opts WktOptions) []byte {
+	return wkbBytes(lineGeometry(trkseg.Trkpt, opts), opts)
+}
+
+// ToWkt returns the track as LINESTRING if it has
+// one segment, as MULTILINESTRING otherwise.
+func (trk *TrkType) ToWkt(opts WktOptions) string {
+	return wktString(trkGeometry(trk, opts), opts)
+}
+
+// ToWkb returns the track as LINESTRING if it has
+// one segment, as MULTILINESTRING otherwise.
+func (trk *TrkType) ToWkb(opts WktOptions) []byte {
+	return wkbBytes(trkGeometry(trk, opts), opts)
+}
+
+// ToWkt returns GEOMETRYCOLLECTION of waypoints,
+// routes and tracks of the document.
+func (gpx *GpxType) ToWkt(opts WktOptions) string {
+	return wktString(gpxGeometry(gpx, opts), opts)
+}
+
+// ToWkb returns GEOMETRYCOLLECTION of waypoints,
+// routes and tracks of the document.
+func (gpx *GpxType) ToWkb(opts WktOptions) []byte {
+	return wkbBytes(gpxGeometry(gpx, opts), opts)
+}
+
+func wptCoords(wpt *WptType) wkbCoords {
+	coords := wkbCoords{x: wpt.LonAttr, y: wpt.LatAttr, z: optionalValue(wpt.Ele), m: math.NaN()}
+	if t, err := wpt.Time.Get(); err == nil {
+		coords.m = float64(t.UnixMilli()) / 1000
+	}
+	return coords
+}
+
+func wptGeometry(wpt *WptType, opts WktOptions) *wkbGeometry {
+	return &wkbGeometry{kind: wkbPoint, points: []wkbCoords{wptCoords(wpt)}, hasZ: opts.Z, hasM: opts.M}
+}
+
+func lineGeometry(points []*WptType, opts WktOptions) *wkbGeometry {
+	line := &wkbGeometry{kind: wkbLineString, points: make([]wkbCoords, len(points)), hasZ: opts.Z, hasM: opts.M}
+	for i, wpt := range points {
+		line.points[i] = wptCoords(wpt)
+	}
+	return line
+}
+
+func trkGeometry(trk *TrkType, opts WktOptions) *wkbGeometry {
+	if len(trk.Trkseg) == 1 {
+		return lineGeometry(trk.Trkseg[0].Trkpt, opts)
+	}
+	multi := &wkbGeometry{kind: wkbMultiLineString, hasZ: opts.Z, hasM: opts.M}
+	for _, trkseg := range trk.Trkseg {
+		multi.children = append(multi.children, lineGeometry(trkseg.Trkpt, opts))
+	}
+	return multi
+}
+
+func gpxGeometry(gpx *GpxType, opts WktOptions) *wkbGeometry {
+	collection := &wkbGeometry{kind: wkbGeometryCollection, hasZ: opts.Z, hasM: opts.M}
+	for _, wpt := range gpx.Wpt {
+		collection.children = append(collection.children, wptGeometry(wpt, opts))
+	}
+	for _, rte := range gpx.Rte {
+		collection.children = append(collection.children, lineGeometry(rte.Rtept, opts))
+	}
+	for _, trk := range gpx.Trk {
+		collection.children = append(collection.children, trkGeometry(trk, opts))
+	}
+	return collection
+}
+
+func (geometry *wkbGeometry) isEmpty() bool {
+	return len(geometry.points) == 0 && len(geometry.children) == 0
+}
+
+func wktString(geometry *wkbGeometry, opts WktOptions) string {
+	var b strings.Builder
+	if opts.SRID != 0 {
+		fmt.Fprintf(&b, "SRID=%d;", opts.SRID)
+	}
+	geometry.writeWkt(&b)
+	return b.String()
+}
+
+func (geometry *wkbGeometry) writeWkt(b *strings.Builder) {
+	b.WriteString(wktNames[geometry.kind])
+	switch {
+	case geometry.hasZ && geometry.hasM:
+		b.WriteString(" ZM")
+	case geometry.hasZ:
+		b.WriteString(" Z")
+	case geometry.hasM:
+		b.WriteString(" M")
+	}
+	if geometry.isEmpty() {
+		b.WriteString(" EMPTY")
+		return
+	}
+	b.WriteString(" (")
+	for i, coords := range geometry.points {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		geometry.writeWktCoords(b, coords)
+	}
+	for i, child := range geometry.children {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if geometry.kind == wkbGeometryCollection {
+			child.writeWkt(b)
+			continue
+		}
+		// Children of multi geometries are written without type.
+		if child.isEmpty() {
+			b.WriteString("EMPTY")
+			continue
+		}
+		b.WriteString("(")
+		for j, coords := range child.points {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			child.writeWktCoords(b, coords)
+		}
+		b.WriteString(")")
+	}
+	b.WriteString(")")
+}
+
+func (geometry *wkbGeometry) writeWktCoords(b *strings.Builder, coords wkbCoords) {
+	b.WriteString(formatFloat(coords.x))
+	b.WriteString(" ")
+	b.WriteString(formatFloat(coords.y))
+	if geometry.hasZ {
+		b.WriteString(" ")
+		b.WriteString(formatFloat(coords.z))
+	}
+	if geometry.hasM {
+		b.WriteString(" ")
+		b.WriteString(formatFloat(coords.m))
+	}
+}
+
+// Write geometry in little endian byte order,
+// the top level geometry has SRID of EWKB.
+func wkbBytes(geometry *wkbGeometry, opts WktOptions) []byte {
+	return geometry.appendWkb(nil, opts, true)
+}
+
+func (geometry *wkbGeometry) appendWkb(data []byte, opts WktOptions, top bool) []byte {
+	data = append(data, 1)
+	kind := geometry.kind
+	if opts.SRID != 0 {
+		if geometry.hasZ {
+			kind |= ewkbZ
+		}
+		if geometry.hasM {
+			kind |= ewkbM
+		}
+		if top {
+			kind |= ewkbSRID
+		}
+	} else {
+		if geometry.hasZ {
+			kind += 1000
+		}
+		if geometry.hasM {
+			kind += 2000
+		}
+	}
+	data = binary.LittleEndian.AppendUint32(data, kind)
+	if opts.SRID != 0 && top {
+		data = binary.LittleEndian.AppendUint32(data, uint32(opts.SRID))
+	}
+
+	switch geometry.kind {
+	case wkbPoint:
+		coords := wkbCoords{x: math.NaN(), y: math.NaN(), z: math.NaN(), m: math.NaN()}
+		if len(geometry.points) > 0 {
+			coords = geometry.points[0]
+		}
+		data = geometry.appendWkbCoords(data, coords)
+	case wkbLineString:
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(geometry.points)))
+		for _, coords := range geometry.points {
+			data = geometry.appendWkbCoords(data, coords)
+		}
+	default:
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(geometry.children)))
+		for _, child := range geometry.children {
+			data = child.appendWkb(data, opts, false)
+		}
+	}
+	return data
+}
+
+func (geometry *wkbGeometry) appendWkbCoords(data []byte, coords wkbCoords) []byte {
+	data = binary.LittleEndian.AppendUint64(data, math.Float64bits(coords.x))
+	data = binary.LittleEndian.AppendUint64(data, math.Float64bits(coords.y))
+	if geometry.hasZ {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(coords.z))
+	}
+	if geometry.hasM {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(coords.m))
+	}
+	return data
+}
+
+// ParseWkt parses WKT or EWKT geometry into a Gpx struct.
+//
+// Points become waypoints and lines become tracks, segments of
+// MULTILINESTRING become segments of one track. Z coordinate is
+// elevation and M coordinate time in seconds since 1970-01-01 UTC.
+// Geometry collections are flattened, polygons are not supported.
+func ParseWkt(text string) (gpx Gpx, err error) {
+	parser := &wktParser{text: text}
+	if parser.peek() == "SRID" {
+		// SRID is skipped, coordinates are read as WGS84.
+		parser.next()
+		if parser.next() != "=" {
+			return nil, fmt.Errorf("Invalid SRID of EWKT")
+		}
+		if _, err := strconv.Atoi(parser.next()); err != nil || parser.next() != ";" {
+			return nil, fmt.Errorf("Invalid SRID of EWKT")
+		}
+	}
+	geometry, err := parser.geometry()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.next(); token != "" {
+		return nil, fmt.Errorf("Unexpected %q after geometry", token)
+	}
+	return geometryToGpx(geometry)
+}
+
+// ParseWkb parses ISO WKB or EWKB geometry
+// into a Gpx struct, see ParseWkt.
+func ParseWkb(data []byte) (gpx Gpx, err error) {
+	reader := &wkbReader{data: data}
+	geometry, err := reader.geometry()
+	if err != nil {
+		return nil, err
+	}
+	if reader.position != len(data) {
+		return nil, fmt.Errorf("Unexpected %d bytes after geometry", len(data)-reader.position)
+	}
+	return geometryToGpx(geometry)
+}
+
+// ParseWkbHex parses hex encoded WKB or EWKB geometry,
+// as returned by PostGIS, see ParseWkb.
+func ParseWkbHex(text string) (gpx Gpx, err error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(text), "\\x"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseWkb(data)
+}
+
+func geometryToGpx(geometry *wkbGeometry) (Gpx, error) {
+	gpx := NewGpx(DefaultCreator)
+	if err := geometry.addToGpx(gpx); err != nil {
+		return nil, err
+	}
+	return gpx, nil
+}
+
+func (geometry *wkbGeometry) addToGpx(gpx *GpxType) error {
+	switch geometry.kind {
+	case wkbPoint, wkbMultiPoint:
+		for _, coords := range geometry.points {
+			if math.IsNaN(coords.x) || math.IsNaN(coords.y) {
+				continue
+			}
+			gpx.Wpt = append(gpx.Wpt, geometry.wpt(coords))
+		}
+	case wkbLineString:
+		if len(geometry.points) > 0 {
+			gpx.Trk = append(gpx.Trk, &TrkType{Trkseg: []*TrksegType{geometry.trkseg()}})
+		}
+	case wkbMultiLineString:
+		trk := &TrkType{}
+		for _, child := range geometry.children {
+			if len(child.points) > 0 {
+				trk.Trkseg = append(trk.Trkseg, child.trkseg())
+			}
+		}
+		if len(trk.Trkseg) > 0 {
+			gpx.Trk = append(gpx.Trk, trk)
+		}
+	case wkbGeometryCollection:
+		for _, child := range geometry.children {
+			if err := child.addToGpx(gpx); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Unsupported geometry type %d", geometry.kind)
+	}
+	return nil
+}
+
+func (geometry *wkbGeometry) wpt(coords wkbCoords) *WptType {
+	wpt := &WptType{LatAttr: coords.y, LonAttr: coords.x}
+	if geometry.hasZ && !math.IsNaN(coords.z) {
+		wpt.Ele = Optional(coords.z)
+	}
+	if geometry.hasM && !math.IsNaN(coords.m) {
+		wpt.Time = NewGpxTime(time.UnixMilli(int64(math.Round(coords.m * 1000))).UTC())
+	}
+	return wpt
+}
+
+func (geometry *wkbGeometry) trkseg() *TrksegType {
+	trkseg := &TrksegType{Trkpt: make([]*WptType, len(geometry.points))}
+	for i, coords := range geometry.points {
+		trkseg.Trkpt[i] = geometry.wpt(coords)
+	}
+	return trkseg
+}
+
+type wktParser struct {
+	text     string
+	position int
+}
+
+// Return the next token, a word, a number or a punctuation
+// character, and move after it. Empty string is the end of text.
+func (p *wktParser) next() string {
+	for p.position < len(p.text) && strings.ContainsRune(" \t\r\n", rune(p.text[p.position])) {
+		p.position++
+	}
+	if p.position == len(p.text) {
+		return ""
+	}
+	start := p.position
+	if strings.ContainsRune("(),;=", rune(p.text[start])) {
+		p.position++
+		return p.text[start:p.position]
+	}
+	for p.position < len(p.text) && !strings.ContainsRune(" \t\r\n(),;=", rune(p.text[p.position])) {
+		p.position++
+	}
+	return strings.ToUpper(p.text[start:p.position])
+}
+
+func (p *wktParser) peek() string {
+	position := p.position
+	token := p.next()
+	p.position = position
+	return token
+}
+
+func (p *wktParser) expect(expected string) error {
+	if token := p.next(); token != expected {
+		return fmt.Errorf("Expected %q instead of %q at position %d of WKT", expected, token, p.position)
+	}
+	return nil
+}
+
+// Parse geometry starting with its tagged type, e.g. "POINT Z",
+// "POINTM" of EWKT or "POINT" with dimension given by coordinates.
+func (p *wktParser) geometry() (*wkbGeometry, error) {
+	name := p.next()
+	geometry := &wkbGeometry{}
+	for kind, kindName := range wktNames {
+		if name == kindName || name == kindName+"M" {
+			geometry.kind = kind
+			geometry.hasM = name != kindName
+		}
+	}
+	if geometry.kind == 0 {
+		return nil, fmt.Errorf("Unsupported geometry %q", name)
+	}
+	// Dimension of untagged geometry is given by the first coordinates.
+	tagged := geometry.hasM
+	switch p.peek() {
+	case "Z":
+		p.next()
+		geometry.hasZ, tagged = true, true
+	case "M":
+		p.next()
+		geometry.hasM, tagged = true, true
+	case "ZM":
+		p.next()
+		geometry.hasZ, geometry.hasM, tagged = true, true, true
+	}
+	if p.peek() == "EMPTY" {
+		p.next()
+		return geometry, nil
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	var err error
+	switch geometry.kind {
+	case wkbPoint, wkbLineString:
+		err = p.coordsList(geometry, tagged)
+	case wkbMultiPoint:
+		// Points may be enclosed in parentheses or not.
+		for {
+			enclosed := p.peek() == "("
+			if enclosed {
+				p.next()
+			}
+			if err := p.coords(geometry, tagged); err != nil {
+				return nil, err
+			}
+			tagged = true
+			if enclosed {
+				if err := p.expect(")"); err != nil {
+					return nil, err
+				}
+			}
+			if p.peek() != "," {
+				break
+			}
+			p.next()
+		}
+		err = p.expect(")")
+	case wkbMultiLineString:
+		for {
+			line := &wkbGeometry{kind: wkbLineString, hasZ: geometry.hasZ, hasM: geometry.hasM}
+			if p.peek() == "EMPTY" {
+				p.next()
+			} else {
+				if err := p.expect("("); err != nil {
+					return nil, err
+				}
+				if err := p.coordsList(line, tagged); err != nil {
+					return nil, err
+				}
+				geometry.hasZ, geometry.hasM, tagged = line.hasZ, line.hasM, true
+			}
+			geometry.children = append(geometry.children, line)
+			if p.peek() != "," {
+				break
+			}
+			p.next()
+		}
+		err = p.expect(")")
+	case wkbGeometryCollection:
+		for {
+			child, err := p.geometry()
+			if err != nil {
+				return nil, err
+			}
+			geometry.children = append(geometry.children, child)
+			if p.peek() != "," {
+				break
+			}
+			p.next()
+		}
+		err = p.expect(")")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return geometry, nil
+}
+
+// Parse coordinates separated by commas up to the closing parenthesis.
+func (p *wktParser) coordsList(geometry *wkbGeometry, tagged bool) error {
+	for {
+		if err := p.coords(geometry, tagged); err != nil {
+			return err
+		}
+		tagged = true
+		token := p.next()
+		if token == ")" {
+			return nil
+		}
+		if token != "," {
+			return fmt.Errorf("Expected \",\" or \")\" instead of %q at position %d of WKT", token, p.position)
+		}
+	}
+}
+
+// Parse coordinates of one point, untagged geometry
+// gets dimension from the number of values.
+func (p *wktParser) coords(geometry *wkbGeometry, tagged bool) error {
+	var values []float64
+	for {
+		token := p.peek()
+		if token == "," || token == ")" || token == "" {
+			break
+		}
+		value, err := strconv.ParseFloat(p.next(), 64)
+		if err != nil {
+			return fmt.Errorf("Invalid number %q of WKT", token)
+		}
+		values = append(values, value)
+	}
+	if !tagged {
+		geometry.hasZ = len(values) > 2
+		geometry.hasM = geometry.hasM || len(values) > 3
+	}
+	dimensions := 2
+	if geometry.hasZ {
+		dimensions++
+	}
+	if geometry.hasM {
+		dimensions++
+	}
+	if len(values) != dimensions {
+		return fmt.Errorf("Expected %d coordinates instead of %d at position %d of WKT", dimensions, len(values), p.position)
+	}
+	coords := wkbCoords{x: values[0], y: values[1], z: math.NaN(), m: math.NaN()}
+	switch {
+	case geometry.hasZ && geometry.hasM:
+		coords.z, coords.m = values[2], values[3]
+	case geometry.hasZ:
+		coords.z = values[2]
+	case geometry.hasM:
+		coords.m = values[2]
+	}
+	geometry.points = append(geometry.points, coords)
+	return nil
+}
+
+type wkbReader struct {
+	data     []byte
+	position int
+	order    binary.ByteOrder
+}
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if r.position+4 > len(r.data) {
+		return 0, fmt.Errorf("Unexpected end of WKB")
+	}
+	value := r.order.Uint32(r.data[r.position:])
+	r.position += 4
+	return value, nil
+}
+
+func (r *wkbReader) float64() (float64, error) {
+	if r.position+8 > len(r.data) {
+		return 0, fmt.Errorf("Unexpected end of WKB")
+	}
+	value := math.Float64frombits(r.order.Uint64(r.data[r.position:]))
+	r.position += 8
+	return value, nil
+}
+
+func (r *wkbReader) geometry() (*wkbGeometry, error) {
+	if r.position >= len(r.data) {
+		return nil, fmt.Errorf("Unexpected end of WKB")
+	}
+	switch r.data[r.position] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return nil, fmt.Errorf("Invalid byte order %d of WKB", r.data[r.position])
+	}
+	r.position++
+
+	kind, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+	geometry := &wkbGeometry{
+		hasZ: kind&ewkbZ != 0 || kind&0xffff/1000 == 1 || kind&0xffff/1000 == 3,
+		hasM: kind&ewkbM != 0 || kind&0xffff/1000 == 2 || kind&0xffff/1000 == 3,
+	}
+	if kind&ewkbSRID != 0 {
+		if _, err := r.uint32(); err != nil {
+			return nil, err
+		}
+	}
+	geometry.kind = kind & 0xffff % 1000
+
+	switch geometry.kind {
+	case wkbPoint:
+		coords, err := r.coords(geometry)
+		if err != nil {
+			return nil, err
+		}
+		geometry.points = []wkbCoords{coords}
+	case wkbLineString:
+		count, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < count; i++ {
+			coords, err := r.coords(geometry)
+			if err != nil {
+				return nil, err
+			}
+			geometry.points = append(geometry.points, coords)
+		}
+	case wkbMultiPoint, wkbMultiLineString, wkbGeometryCollection:
+		count, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < count; i++ {
+			child, err := r.geometry()
+			if err != nil {
+				return nil, err
+			}
+			if geometry.kind == wkbMultiPoint {
+				geometry.points = append(geometry.points, child.points...)
+				geometry.hasZ, geometry.hasM = child.hasZ, child.hasM
+				continue
+			}
+			geometry.children = append(geometry.children, child)
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported geometry type %d of WKB", geometry.kind)
+	}
+	return geometry, nil
+}
+
+func (r *wkbReader) coords(geometry *wkbGeometry) (coords wkbCoords, err error) {
+	coords = wkbCoords{z: math.NaN(), m: math.NaN()}
+	if coords.x, err = r.float64(); err != nil {
+		return coords, err
+	}
+	if coords.y, err = r.float64(); err != nil {
+		return coords, err
+	}
+	if geometry.hasZ {
+		if coords.z, err = r.float64(); err != nil {
+			return coords, err
+		}
+	}
+	if geometry.hasM {
+		if coords.m, err = r.float64(); err != nil {
+			return coords, err
+		}
+	}
+	return coords, nil
+}